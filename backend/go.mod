@@ -10,6 +10,8 @@ require (
 	github.com/jackc/pgx/v5 v5.6.0
 	github.com/jhump/protoreflect v1.17.0
 	github.com/rs/zerolog v1.31.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240227224415-6ceb2ff114de
+	google.golang.org/grpc v1.63.2
 	google.golang.org/protobuf v1.34.2
 )
 
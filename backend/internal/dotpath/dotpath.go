@@ -1,342 +1,280 @@
 package dotpath
 
 import (
-    "encoding/json"
-    "fmt"
-    "regexp"
-    "strconv"
-    "strings"
+	"encoding/json"
+	"strconv"
+	"strings"
 )
 
-var arrayIndexRegex = regexp.MustCompile(`^(\w+)\[(\d+)\]$`)
-
 // SetByPath sets a value in a nested structure using dot notation
-// Supports: user.id, items[0].name, user.profile.address.city
+// Supports: user.id, items[0].name, items[-1], items[], labels["app.kubernetes.io/name"]
+// Paths that do not apply to root are skipped; SetPath reports why.
 func SetByPath(root map[string]interface{}, path string, value interface{}) map[string]interface{} {
-	if path == "" {
-		return root
-	}
-	
-	parts := strings.Split(path, ".")
-	
-	current := root
-	for i, part := range parts {
-		if i == len(parts)-1 {
-			// Last part - set the value
-			if isArrayAccess(part) {
-				setArrayValue(current, part, value)
-			} else if isInvalidArrayAccess(part) {
-				// Invalid array access pattern, just return without doing anything
-				return root
-			} else {
-				// Merge-friendly behavior: avoid overwriting existing maps with empty {}
-				if existing, exists := current[part]; exists {
-					if existingMap, ok := existing.(map[string]interface{}); ok {
-						if newMap, ok2 := value.(map[string]interface{}); ok2 {
-							// If new map is empty, keep existing
-							if len(newMap) == 0 {
-								return root
-							}
-							// Merge keys into existing map
-							for k, v := range newMap {
-								existingMap[k] = v
-							}
-							current[part] = existingMap
-							return root
-						}
-					}
-				}
-				current[part] = value
-			}
-			return root
-		}
-		
-		// Navigate to next level
-		if isArrayAccess(part) {
-			next, err := getOrCreateArrayElement(current, part)
-			if err != nil {
-				// If we can't parse the array access, just return without doing anything
-				return root
-			}
-			current = next
-		} else if isInvalidArrayAccess(part) {
-			// Invalid array access pattern, just return without doing anything
-			return root
-		} else {
-			// Create nested map if it doesn't exist
-			if _, exists := current[part]; !exists {
-				current[part] = make(map[string]interface{})
-			}
-			if next, ok := current[part].(map[string]interface{}); ok {
-				current = next
-			} else {
-				// If the key exists but is not a map, just return without doing anything
-				return root
-			}
-		}
-	}
-	
+	_ = SetPath(root, path, value)
 	return root
 }
 
-// GetByPath retrieves a value from a nested structure using dot notation
-func GetByPath(root map[string]interface{}, path string) (interface{}, error) {
-	parts := strings.Split(path, ".")
-	
-	current := root
-	for _, part := range parts {
-		if isArrayAccess(part) {
-			val, err := getArrayValue(current, part)
-			if err != nil {
-				return nil, err
-			}
-			current = val.(map[string]interface{})
-		} else {
-			val, exists := current[part]
-			if !exists {
-				return nil, fmt.Errorf("path not found: %s", path)
-			}
-			current = val.(map[string]interface{})
-		}
-	}
-	
-	return current, nil
-}
-
-// isArrayAccess checks if a path part is an array access (e.g., items[0])
-func isArrayAccess(part string) bool {
-	return arrayIndexRegex.MatchString(part)
-}
-
-// isInvalidArrayAccess checks if a path part looks like an array access but is invalid
-// This catches patterns like items[abc], items[-1], items[1.5], etc.
-func isInvalidArrayAccess(part string) bool {
-	// Check if it looks like array access but doesn't match the valid pattern
-	if strings.Contains(part, "[") && strings.Contains(part, "]") {
-		return !arrayIndexRegex.MatchString(part)
+// SetPath sets a value in root at path, creating objects and arrays on the way. Arrays grow
+// to fit an index, [] appends and negative indices count back from the end. Setting an
+// object where one exists merges the two instead of replacing it.
+func SetPath(root map[string]interface{}, path string, value interface{}) error {
+	segs, err := ParsePath(path)
+	if err != nil {
+		return err
 	}
-	return false
+	_, err = setIn(root, segs, value, path)
+	return err
 }
 
-// parseArrayAccess extracts the key and index from array access notation
-func parseArrayAccess(part string) (string, int, error) {
-	matches := arrayIndexRegex.FindStringSubmatch(part)
-	if len(matches) != 3 {
-		return "", 0, fmt.Errorf("invalid array access format: %s", part)
-	}
-	
-	key := matches[1]
-	index, err := strconv.Atoi(matches[2])
-	if err != nil {
-		return "", 0, fmt.Errorf("invalid array index: %s", matches[2])
+// setIn sets value at segs below node and returns node, which is created or grown as needed
+func setIn(node interface{}, segs []Segment, value interface{}, path string) (interface{}, error) {
+	if len(segs) == 0 {
+		return mergeValue(node, value), nil
 	}
-	
-	return key, index, nil
-}
+	seg := segs[0]
 
-// getOrCreateMap gets or creates a map at the specified key
-func getOrCreateMap(current map[string]interface{}, key string) (map[string]interface{}, error) {
-	if val, exists := current[key]; exists {
-		if mapVal, ok := val.(map[string]interface{}); ok {
-			return mapVal, nil
+	if seg.Kind == KeySegment {
+		var obj map[string]interface{}
+		switch t := node.(type) {
+		case nil:
+			obj = make(map[string]interface{})
+		case map[string]interface{}:
+			obj = t
+		default:
+			return nil, segmentError(path, seg, "cannot set a key on %s", describe(node))
+		}
+		child, err := setIn(obj[seg.Key], segs[1:], value, path)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("key %s is not a map", key)
+		obj[seg.Key] = child
+		return obj, nil
 	}
-	
-	// Create new map
-	newMap := make(map[string]interface{})
-	current[key] = newMap
-	return newMap, nil
-}
 
-// getOrCreateArrayElement gets or creates an array element
-func getOrCreateArrayElement(current map[string]interface{}, part string) (map[string]interface{}, error) {
-	key, index, err := parseArrayAccess(part)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Handle negative indices
-	if index < 0 {
-		return nil, fmt.Errorf("negative array index: %d", index)
-	}
-	
-	// Get or create array
 	var arr []interface{}
-	if val, exists := current[key]; exists {
-		if arrayVal, ok := val.([]interface{}); ok {
-			arr = arrayVal
-		} else {
-			return nil, fmt.Errorf("key %s is not an array", key)
-		}
-	} else {
-		arr = make([]interface{}, 0)
-		current[key] = arr
+	switch t := node.(type) {
+	case nil:
+	case []interface{}:
+		arr = t
+	default:
+		return nil, segmentError(path, seg, "cannot index %s", describe(node))
 	}
-	
-	// Extend array if needed - create array with exact size needed
-	if index >= len(arr) {
-		newArr := make([]interface{}, index+1)
-		copy(newArr, arr)
-		arr = newArr
-		current[key] = arr
+	index := seg.Index
+	switch {
+	case seg.Kind == AppendSegment:
+		index = len(arr)
+		arr = append(arr, nil)
+	case index < 0:
+		index += len(arr)
+		if index < 0 {
+			return nil, segmentError(path, seg, "index %d is out of range for %d elements", seg.Index, len(arr))
+		}
+	case index >= len(arr):
+		// Extend array if needed - create array with exact size needed
+		grown := make([]interface{}, index+1)
+		copy(grown, arr)
+		arr = grown
 	}
-	
-	// Get or create element at index
-	var element map[string]interface{}
-	if arr[index] == nil {
-		element = make(map[string]interface{})
-		arr[index] = element
-	} else if mapVal, ok := arr[index].(map[string]interface{}); ok {
-		element = mapVal
-	} else {
-		return nil, fmt.Errorf("element at index %d is not a map", index)
+	child, err := setIn(arr[index], segs[1:], value, path)
+	if err != nil {
+		return nil, err
 	}
-	
-	return element, nil
+	arr[index] = child
+	return arr, nil
 }
 
-// setArrayValue sets a value in an array
-func setArrayValue(current map[string]interface{}, part string, value interface{}) {
-	key, index, err := parseArrayAccess(part)
-	if err != nil {
-		// If we can't parse the array access, just return without doing anything
-		return
-	}
-	
-	// Handle negative indices
-	if index < 0 {
-		return
+// mergeValue returns value to store over existing. Merge-friendly behavior: objects merge
+// their keys into an existing object, and an empty object keeps it as it is.
+func mergeValue(existing, value interface{}) interface{} {
+	existingMap, ok := existing.(map[string]interface{})
+	if !ok {
+		return value
 	}
-	
-	// Get or create array
-	var arr []interface{}
-	if val, exists := current[key]; exists {
-		if arrayVal, ok := val.([]interface{}); ok {
-			arr = arrayVal
-		} else {
-			// If the key exists but is not an array, just return without doing anything
-			return
-		}
-	} else {
-		arr = make([]interface{}, 0)
+	newMap, ok := value.(map[string]interface{})
+	if !ok {
+		return value
 	}
-	
-	// Extend array if needed - create array with exact size needed
-	if index >= len(arr) {
-		newArr := make([]interface{}, index+1)
-		copy(newArr, arr)
-		arr = newArr
-		current[key] = arr
+	for k, v := range newMap {
+		existingMap[k] = v
 	}
-	
-	// Set value at index
-	arr[index] = value
+	return existingMap
 }
 
-// getArrayValue gets a value from an array
-func getArrayValue(current map[string]interface{}, part string) (interface{}, error) {
-	key, index, err := parseArrayAccess(part)
+// GetByPath retrieves a value from a nested structure using dot notation, with the same
+// path syntax as SetByPath
+func GetByPath(root map[string]interface{}, path string) (interface{}, error) {
+	segs, err := ParsePath(path)
 	if err != nil {
 		return nil, err
 	}
-	
-	val, exists := current[key]
-	if !exists {
-		return nil, fmt.Errorf("array key %s not found", key)
-	}
-	
-	arr, ok := val.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("key %s is not an array", key)
+
+	var current interface{} = root
+	for _, seg := range segs {
+		switch seg.Kind {
+		case KeySegment:
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, segmentError(path, seg, "cannot read a key of %s", describe(current))
+			}
+			val, exists := obj[seg.Key]
+			if !exists {
+				return nil, segmentError(path, seg, "key not found")
+			}
+			current = val
+		case AppendSegment:
+			return nil, segmentError(path, seg, "[] appends and cannot be read; use an index")
+		case IndexSegment:
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, segmentError(path, seg, "cannot index %s", describe(current))
+			}
+			index := seg.Index
+			if index < 0 {
+				index += len(arr)
+			}
+			if index < 0 || index >= len(arr) {
+				return nil, segmentError(path, seg, "index %d is out of range for %d elements", seg.Index, len(arr))
+			}
+			current = arr[index]
+		}
 	}
-	
-	if index >= len(arr) {
-		return nil, fmt.Errorf("array index %d out of bounds", index)
+
+	return current, nil
+}
+
+// describe names the kind of a value found along a path, for errors
+func describe(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
 	}
-	
-	return arr[index], nil
+	return "a number"
 }
 
-// BuildFromFields builds a nested structure from a list of body fields
+// BuildFromFields builds a nested structure from a list of body fields. Fields are applied
+// in order, so a path set twice keeps its last value (this helps with oneof field conflicts)
+// and [] appends a new element each time. The first path that does not apply is returned
+// as a *PathError.
 func BuildFromFields(fields []interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
-	
-	// Track all paths to detect potential conflicts
-	paths := make(map[string]interface{})
-	
+
 	for _, field := range fields {
 		if bodyField, ok := field.(map[string]interface{}); ok {
 			path, pathOk := bodyField["path"].(string)
 			value, valueOk := bodyField["value"]
-			
+
 			if pathOk && valueOk {
-                // Coerce common literal types from strings (bool, number, null, JSON objects/arrays)
-                value = coerceValue(value)
-				// Check for potential conflicts with existing paths
-				if _, exists := paths[path]; exists {
-					// If the same path is set multiple times, use the last value
-					// This helps with oneof field conflicts
-					SetByPath(result, path, value)
-					paths[path] = value
-				} else {
-					SetByPath(result, path, value)
-					paths[path] = value
+				// Coerce common literal types from strings (bool, number, null, JSON objects/arrays)
+				if err := SetPath(result, path, coerceValue(value)); err != nil {
+					return nil, err
 				}
 			}
 		}
 	}
-	
+
 	return result, nil
 }
 
+// CoerceValue converts a dot-path field value the same way BuildFromFields does, so
+// callers can inspect the value that will actually be sent
+func CoerceValue(val interface{}) interface{} {
+	return coerceValue(val)
+}
+
 // coerceValue attempts to convert string inputs into appropriate JSON-native types
 // - "true"/"false" -> bool
 // - "null" -> nil
 // - JSON objects/arrays -> map[string]interface{} / []interface{}
-// - Simple strings (like "4444") remain as strings and are NOT converted to numbers
+// - Numeric strings -> float64, or json.Number for integers float64 cannot hold exactly
 // Otherwise returns the original value
 func coerceValue(val interface{}) interface{} {
-    s, ok := val.(string)
-    if !ok {
-        return val
-    }
-    str := strings.TrimSpace(s)
-    if str == "" {
-        return s
-    }
+	s, ok := val.(string)
+	if !ok {
+		return NormalizeNumbers(val)
+	}
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return s
+	}
+
+	// Fast path for objects/arrays
+	if strings.HasPrefix(str, "{") || strings.HasPrefix(str, "[") {
+		dec := json.NewDecoder(strings.NewReader(str))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err == nil && !dec.More() {
+			return NormalizeNumbers(v)
+		}
+		return s
+	}
 
-    // Fast path for objects/arrays
-    if strings.HasPrefix(str, "{") || strings.HasPrefix(str, "[") {
-        var v interface{}
-        if err := json.Unmarshal([]byte(str), &v); err == nil {
-            return v
-        }
-        return s
-    }
+	// Only coerce JSON literals (bool/null/number)
+	if str == "true" {
+		return true
+	}
+	if str == "false" {
+		return false
+	}
+	if str == "null" {
+		return nil
+	}
+
+	// Attempt to parse as a JSON number (e.g., 30, 1.25, 1e3). Integers beyond float64
+	// precision stay json.Number so 64-bit IDs survive encoding.
+	var num interface{}
+	if err := json.Unmarshal([]byte(str), &num); err == nil {
+		if _, isFloat := num.(float64); isFloat {
+			return normalizeNumber(json.Number(str))
+		}
+	}
 
-    // Only coerce JSON literals (bool/null/number)
-    if str == "true" {
-        return true
-    }
-    if str == "false" {
-        return false
-    }
-    if str == "null" {
-        return nil
-    }
+	// Return the original string for everything else
+	return s
+}
 
-    // Attempt to parse as a JSON number (e.g., 30, 1.25, 1e3)
-    // json.Unmarshal will convert JSON numbers to float64
-    var num interface{}
-    if err := json.Unmarshal([]byte(str), &num); err == nil {
-        switch num.(type) {
-        case float64:
-            return num
-        }
-    }
+// maxExactFloatInt is the largest integer magnitude float64 represents exactly (2^53)
+const maxExactFloatInt = 1 << 53
 
-    // Return the original string for everything else
-    return s
+// NormalizeNumbers walks a decoded JSON value and converts json.Number leaves to float64
+// where that is lossless, keeping json.Number for integers beyond 2^53
+func NormalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		return normalizeNumber(t)
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = NormalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = NormalizeNumbers(item)
+		}
+	}
+	return v
 }
 
+// normalizeNumber returns n as float64 unless it is an integer float64 cannot hold exactly
+func normalizeNumber(n json.Number) interface{} {
+	str := n.String()
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+		if i > maxExactFloatInt || i < -maxExactFloatInt {
+			return n
+		}
+		return float64(i)
+	}
+	if _, err := strconv.ParseUint(str, 10, 64); err == nil {
+		return n
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n
+}
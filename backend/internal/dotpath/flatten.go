@@ -0,0 +1,68 @@
+package dotpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datahopper/backend/internal/types"
+)
+
+// Flatten is the inverse of BuildFromFields: it returns the fewest dot-path fields that
+// rebuild root. Keys are visited in sorted order, arrays use indexed paths, keys that are
+// not plain names are quoted and empty objects and arrays are kept as values of their own.
+func Flatten(root map[string]interface{}) []types.BodyField {
+	fields := []types.BodyField{}
+	flattenObject(root, "", &fields)
+	return fields
+}
+
+// FlattenJSON decodes a JSON object and flattens it. Numbers are kept as BuildFromFields
+// coerces them, so integers beyond 2^53 stay exact.
+func FlattenJSON(data []byte) ([]types.BodyField, error) {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after the top-level value")
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON object, got %s", describe(v))
+	}
+	return Flatten(NormalizeNumbers(root).(map[string]interface{})), nil
+}
+
+func flattenObject(obj map[string]interface{}, prefix string, out *[]types.BodyField) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flattenValue(obj[k], JoinKey(prefix, k), out)
+	}
+}
+
+// flattenValue appends the fields of v, which sits at path
+func flattenValue(v interface{}, path string, out *[]types.BodyField) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) > 0 {
+			flattenObject(t, path, out)
+			return
+		}
+	case []interface{}:
+		if len(t) > 0 {
+			for i, item := range t {
+				flattenValue(item, JoinIndex(path, i), out)
+			}
+			return
+		}
+	}
+	*out = append(*out, types.BodyField{Path: path, Value: v})
+}
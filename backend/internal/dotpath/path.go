@@ -0,0 +1,217 @@
+package dotpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SegmentKind tells what a path segment addresses
+type SegmentKind int
+
+const (
+	KeySegment    SegmentKind = iota // An object or map key: name, escaped\.name or ["quoted"]
+	IndexSegment                     // [n]; negative indices count back from the end
+	AppendSegment                    // []; a new element at the end of the array
+)
+
+// Segment is one step of a parsed dot-path
+type Segment struct {
+	Kind   SegmentKind
+	Key    string // Set for KeySegment, unescaped
+	Index  int    // Set for IndexSegment
+	Offset int    // Byte offset of the segment in the path, for errors
+	Text   string // The segment as written
+}
+
+// String returns the segment in its canonical form, without a leading dot
+func (s Segment) String() string {
+	switch s.Kind {
+	case IndexSegment:
+		return "[" + strconv.Itoa(s.Index) + "]"
+	case AppendSegment:
+		return "[]"
+	}
+	if isBareKey(s.Key) {
+		return s.Key
+	}
+	return "[" + quoteKey(s.Key) + "]"
+}
+
+// PathError reports a path that does not parse, or that does not fit the value it is
+// applied to, and the segment at fault
+type PathError struct {
+	Path    string
+	Segment string // The offending segment as written
+	Offset  int    // Byte offset of the segment in Path
+	Reason  string
+}
+
+func (e *PathError) Error() string {
+	if e.Segment == "" {
+		return fmt.Sprintf("invalid path %q: %s", e.Path, e.Reason)
+	}
+	return fmt.Sprintf("invalid path %q at %q (offset %d): %s", e.Path, e.Segment, e.Offset, e.Reason)
+}
+
+func segmentError(path string, seg Segment, format string, args ...interface{}) *PathError {
+	return &PathError{Path: path, Segment: seg.Text, Offset: seg.Offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+// ParsePath splits a dot-path into segments. Keys are separated by dots; a backslash escapes
+// the next character, so `a\.b` is the single key "a.b". Brackets follow a key or another
+// bracket and hold an index (`items[2]`, `items[-1]`), nothing for append (`items[]`), or a
+// JSON string for a key with any characters (`labels["app.kubernetes.io/name"]`).
+func ParsePath(path string) ([]Segment, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, &PathError{Path: path, Reason: "path is empty"}
+	}
+	var segs []Segment
+	i := 0
+	for i < len(path) {
+		start := i
+		switch {
+		case path[i] == '[':
+			seg, next, err := parseBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			if len(segs) == 0 && seg.Kind != KeySegment {
+				return nil, segmentError(path, seg, "a path starts with a key, not an index")
+			}
+			segs = append(segs, seg)
+			i = next
+			continue
+		case path[i] == '.':
+			if len(segs) == 0 {
+				return nil, &PathError{Path: path, Segment: ".", Offset: i, Reason: "a path cannot start with a dot"}
+			}
+			i++
+			start = i
+		case len(segs) > 0:
+			// Keys after the first need a dot before them
+			return nil, &PathError{Path: path, Segment: path[i:], Offset: i, Reason: "expected a dot or a bracket after " + segs[len(segs)-1].Text}
+		}
+
+		key, next, err := parseBareKey(path, start)
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, Segment{Kind: KeySegment, Key: key, Offset: start, Text: path[start:next]})
+		i = next
+	}
+	return segs, nil
+}
+
+// parseBareKey reads a key up to the next unescaped dot or bracket
+func parseBareKey(path string, start int) (string, int, error) {
+	var b strings.Builder
+	i := start
+	for i < len(path) {
+		c := path[i]
+		if c == '.' || c == '[' {
+			break
+		}
+		switch c {
+		case '\\':
+			if i+1 == len(path) {
+				return "", 0, &PathError{Path: path, Segment: path[start:], Offset: start, Reason: "trailing backslash"}
+			}
+			b.WriteByte(path[i+1])
+			i += 2
+			continue
+		case ']', '"':
+			return "", 0, &PathError{Path: path, Segment: path[start:], Offset: start, Reason: fmt.Sprintf("unexpected %q in key; escape it or quote the key", c)}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	if i == start {
+		return "", 0, &PathError{Path: path, Segment: path[start:i], Offset: start, Reason: "empty key"}
+	}
+	return b.String(), i, nil
+}
+
+// parseBracket reads a bracketed segment starting at path[start] == '['
+func parseBracket(path string, start int) (Segment, int, error) {
+	end := start + 1
+	if end < len(path) && path[end] == '"' {
+		// Quoted key: scan to the closing quote, honouring escapes
+		end++
+		for end < len(path) && path[end] != '"' {
+			if path[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(path) || end+1 >= len(path) || path[end+1] != ']' {
+			return Segment{}, 0, &PathError{Path: path, Segment: path[start:], Offset: start, Reason: `unterminated quoted key; expected "]`}
+		}
+		text := path[start : end+2]
+		var key string
+		if err := json.Unmarshal([]byte(path[start+1:end+1]), &key); err != nil {
+			return Segment{}, 0, &PathError{Path: path, Segment: text, Offset: start, Reason: "invalid quoted key: " + err.Error()}
+		}
+		return Segment{Kind: KeySegment, Key: key, Offset: start, Text: text}, end + 2, nil
+	}
+
+	close := strings.IndexByte(path[start:], ']')
+	if close < 0 {
+		return Segment{}, 0, &PathError{Path: path, Segment: path[start:], Offset: start, Reason: "missing ]"}
+	}
+	text := path[start : start+close+1]
+	inner := text[1 : len(text)-1]
+	if inner == "" {
+		return Segment{Kind: AppendSegment, Offset: start, Text: text}, start + close + 1, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return Segment{}, 0, &PathError{Path: path, Segment: text, Offset: start, Reason: `expected an integer index, [] or a quoted key`}
+	}
+	return Segment{Kind: IndexSegment, Index: index, Offset: start, Text: text}, start + close + 1, nil
+}
+
+// FormatPath writes segments back as a canonical dot-path
+func FormatPath(segs []Segment) string {
+	var b strings.Builder
+	for i, seg := range segs {
+		text := seg.String()
+		if i > 0 && seg.Kind == KeySegment && isBareKey(seg.Key) {
+			b.WriteByte('.')
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// JoinKey appends a key to a dot-path, quoting it when it is not a plain name
+func JoinKey(prefix, key string) string {
+	if !isBareKey(key) {
+		return prefix + "[" + quoteKey(key) + "]"
+	}
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// JoinIndex appends an array index to a dot-path
+func JoinIndex(prefix string, index int) string {
+	return prefix + "[" + strconv.Itoa(index) + "]"
+}
+
+// isBareKey reports whether a key can be written without quotes or escapes
+func isBareKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, `.[]"\`)
+}
+
+// quoteKey writes a key as a JSON string, leaving HTML characters readable
+func quoteKey(key string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(key)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
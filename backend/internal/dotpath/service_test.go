@@ -1,7 +1,10 @@
 package dotpath
 
 import (
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -158,6 +161,51 @@ func TestSetByPath(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "quoted map key",
+			data:  map[string]interface{}{},
+			path:  `labels["app.kubernetes.io/name"]`,
+			value: "api",
+			expected: map[string]interface{}{
+				"labels": map[string]interface{}{"app.kubernetes.io/name": "api"},
+			},
+		},
+		{
+			name:  "escaped dot",
+			data:  map[string]interface{}{},
+			path:  `labels.team\.owner`,
+			value: "core",
+			expected: map[string]interface{}{
+				"labels": map[string]interface{}{"team.owner": "core"},
+			},
+		},
+		{
+			name:  "append",
+			data:  map[string]interface{}{"tags": []interface{}{"a"}},
+			path:  "tags[]",
+			value: "b",
+			expected: map[string]interface{}{
+				"tags": []interface{}{"a", "b"},
+			},
+		},
+		{
+			name:  "negative index",
+			data:  map[string]interface{}{"items": []interface{}{map[string]interface{}{"sku": "A"}, map[string]interface{}{"sku": "B"}}},
+			path:  "items[-1].qty",
+			value: 2,
+			expected: map[string]interface{}{
+				"items": []interface{}{map[string]interface{}{"sku": "A"}, map[string]interface{}{"sku": "B", "qty": 2}},
+			},
+		},
+		{
+			name:  "nested index",
+			data:  map[string]interface{}{},
+			path:  "grid[1][0]",
+			value: 3,
+			expected: map[string]interface{}{
+				"grid": []interface{}{nil, []interface{}{3}},
+			},
+		},
 		{
 			name:  "array expansion",
 			data:  map[string]interface{}{},
@@ -243,6 +291,24 @@ func TestBuildFromFields(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "64-bit integers keep full precision",
+			fields: []interface{}{
+				map[string]interface{}{"path": "id", "value": "1234567890123456789"},
+				map[string]interface{}{"path": "owner", "value": json.Number("18446744073709551615")},
+				map[string]interface{}{"path": "count", "value": json.Number("12")},
+				map[string]interface{}{"path": "meta", "value": `{"ref": 9007199254740993, "n": 1.5}`},
+			},
+			want: map[string]interface{}{
+				"id":    json.Number("1234567890123456789"),
+				"owner": json.Number("18446744073709551615"),
+				"count": float64(12),
+				"meta": map[string]interface{}{
+					"ref": json.Number("9007199254740993"),
+					"n":   1.5,
+				},
+			},
+		},
 		{
 			name:   "empty fields",
 			fields: []interface{}{},
@@ -311,3 +377,164 @@ func TestSetByPathEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePath(t *testing.T) {
+	segs, err := ParsePath(`spec.labels["app.kubernetes.io/name"].values[-1][]` + `.a\.b`)
+	if err != nil {
+		t.Fatalf("ParsePath failed: %v", err)
+	}
+	want := []Segment{
+		{Kind: KeySegment, Key: "spec"},
+		{Kind: KeySegment, Key: "labels"},
+		{Kind: KeySegment, Key: "app.kubernetes.io/name"},
+		{Kind: KeySegment, Key: "values"},
+		{Kind: IndexSegment, Index: -1},
+		{Kind: AppendSegment},
+		{Kind: KeySegment, Key: "a.b"},
+	}
+	if len(segs) != len(want) {
+		t.Fatalf("expected %d segments, got %+v", len(want), segs)
+	}
+	for i, seg := range segs {
+		if seg.Kind != want[i].Kind || seg.Key != want[i].Key || seg.Index != want[i].Index {
+			t.Errorf("segment %d: expected %+v, got %+v", i, want[i], seg)
+		}
+	}
+	if got := FormatPath(segs); got != `spec.labels["app.kubernetes.io/name"].values[-1][]["a.b"]` {
+		t.Errorf("unexpected canonical path %s", got)
+	}
+
+	for path, segment := range map[string]string{
+		"items[abc]":    "[abc]",
+		"items[0]x":     "x",
+		"[0].name":      "[0]",
+		"user..name":    "",
+		`labels["open]`: `["open]`,
+		"items[1":       "[1",
+		"a]b":           "a]b",
+		`trailing\`:     `trailing\`,
+		".leading":      ".",
+	} {
+		_, err := ParsePath(path)
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			t.Errorf("%s: expected a PathError, got %v", path, err)
+			continue
+		}
+		if pathErr.Segment != segment {
+			t.Errorf("%s: expected offending segment %q, got %q (%v)", path, segment, pathErr.Segment, err)
+		}
+	}
+}
+
+func TestGetByPath(t *testing.T) {
+	root := map[string]interface{}{
+		"labels": map[string]interface{}{"app.kubernetes.io/name": "api"},
+		"items":  []interface{}{map[string]interface{}{"sku": "A"}, map[string]interface{}{"sku": "B"}},
+	}
+	for path, want := range map[string]interface{}{
+		`labels["app.kubernetes.io/name"]`: "api",
+		`labels.app\.kubernetes\.io/name`:  "api",
+		"items[0].sku":                     "A",
+		"items[-1].sku":                    "B",
+	} {
+		got, err := GetByPath(root, path)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %v, got %v (%v)", path, want, got, err)
+		}
+	}
+
+	for path, segment := range map[string]string{
+		"items[2].sku":   "[2]",
+		"items[].sku":    "[]",
+		"labels.missing": "missing",
+		"items[0].sku.x": "x",
+		"labels[0]":      "[0]",
+	} {
+		_, err := GetByPath(root, path)
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) || pathErr.Segment != segment {
+			t.Errorf("%s: expected an error at %q, got %v", path, segment, err)
+		}
+	}
+}
+
+func TestBuildFromFieldsPathErrors(t *testing.T) {
+	got, err := BuildFromFields([]interface{}{
+		map[string]interface{}{"path": "items[]", "value": "a"},
+		map[string]interface{}{"path": "items[]", "value": "b"},
+		map[string]interface{}{"path": `meta["x.y"]`, "value": "1"},
+	})
+	if err != nil {
+		t.Fatalf("BuildFromFields failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"items": []interface{}{"a", "b"},
+		"meta":  map[string]interface{}{"x.y": float64(1)},
+	}
+	if !mapsEqual(got, want) {
+		t.Errorf("BuildFromFields() = %v, want %v", got, want)
+	}
+
+	_, err = BuildFromFields([]interface{}{
+		map[string]interface{}{"path": "name", "value": "n"},
+		map[string]interface{}{"path": "name.first", "value": "x"},
+	})
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || pathErr.Segment != "first" {
+		t.Errorf("expected an error at \"first\", got %v", err)
+	}
+}
+
+func TestFlattenJSON(t *testing.T) {
+	input := `{
+		"id": 9007199254740993,
+		"user": {"name": "John", "tags": ["a", "b"], "address": {"city": "Oslo"}},
+		"items": [{"sku": "A-1", "qty": 2}, {"sku": "B-2", "price": 1.5}],
+		"empty": {},
+		"none": [],
+		"grid": [[1, 2], [3]],
+		"headers": {"content.type": "json"},
+		"active": false,
+		"note": null
+	}`
+
+	fields, err := FlattenJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("FlattenJSON failed: %v", err)
+	}
+	paths := make([]string, len(fields))
+	asInput := make([]interface{}, len(fields))
+	for i, f := range fields {
+		paths[i] = f.Path
+		asInput[i] = map[string]interface{}{"path": f.Path, "value": f.Value}
+	}
+	wantPaths := []string{"active", "empty", "grid[0][0]", "grid[0][1]", "grid[1][0]", `headers["content.type"]`, "id",
+		"items[0].qty", "items[0].sku", "items[1].price", "items[1].sku", "none", "note", "user.address.city", "user.name",
+		"user.tags[0]", "user.tags[1]"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("expected paths %v, got %v", wantPaths, paths)
+	}
+
+	rebuilt, err := BuildFromFields(asInput)
+	if err != nil {
+		t.Fatalf("BuildFromFields failed: %v", err)
+	}
+	var original interface{}
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+	if err := dec.Decode(&original); err != nil {
+		t.Fatalf("failed to decode input: %v", err)
+	}
+	want, _ := json.Marshal(NormalizeNumbers(original))
+	got, _ := json.Marshal(rebuilt)
+	if string(got) != string(want) {
+		t.Errorf("round trip changed the document:\nwant %s\ngot  %s", want, got)
+	}
+
+	for _, bad := range []string{`[1, 2]`, `{"a": 1} {}`, `{`} {
+		if _, err := FlattenJSON([]byte(bad)); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
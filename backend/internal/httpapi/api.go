@@ -1,6 +1,7 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -41,6 +42,7 @@ func NewAPI(registry *registry.Service, workspace *workspace.Service, runner *ru
 	// Set loggers for services
 	registry.SetLogger(logger)
 	runner.SetLogger(logger)
+	runner.SetFileResolver(api.loadRequestFile)
 
 	return api
 }
@@ -72,6 +74,8 @@ func (api *API) SetupRoutes(router *gin.Engine) {
 		apiGroup.GET("/registry/messages", api.listMessages)
 		apiGroup.GET("/registry/messages/:fqn/schema", api.getMessageSchema)
 		apiGroup.GET("/registry/messages/:fqn/fields", api.getMessageFields)
+		apiGroup.GET("/registry/messages/:fqn/example", api.getMessageExample)
+		apiGroup.GET("/registry/services", api.listServices)
 		api.logger.Info().Msg("Registered schema endpoint")
 
 		// Collections
@@ -86,6 +90,10 @@ func (api *API) SetupRoutes(router *gin.Engine) {
 		apiGroup.GET("/collections/:id/requests/:requestId", api.getRequest)
 		apiGroup.PUT("/collections/:id/requests/:requestId", api.updateRequest)
 		apiGroup.DELETE("/collections/:id/requests/:requestId", api.deleteRequest)
+		apiGroup.GET("/collections/:id/requests/:requestId/files", api.listRequestFiles)
+		apiGroup.POST("/collections/:id/requests/:requestId/files", api.uploadRequestFile)
+		apiGroup.DELETE("/collections/:id/requests/:requestId/files/:fileId", api.deleteRequestFile)
+		apiGroup.POST("/collections/:id/requests/:requestId/run", api.runSavedRequest)
 
 		// Environments
 		apiGroup.GET("/environments", api.listEnvironments)
@@ -95,6 +103,17 @@ func (api *API) SetupRoutes(router *gin.Engine) {
 
 		// Request execution
 		apiGroup.POST("/run", api.runRequest)
+		apiGroup.POST("/decode-raw", api.decodeRaw)
+		apiGroup.POST("/validate", api.validateRequest)
+		apiGroup.POST("/body/convert", api.convertBody)
+		apiGroup.POST("/body/flatten", api.flattenBody)
+
+		// Streaming gRPC execution
+		apiGroup.POST("/stream", api.openStream)
+		apiGroup.GET("/stream/:id/events", api.streamEvents)
+		apiGroup.POST("/stream/:id/messages", api.sendStreamMessage)
+		apiGroup.POST("/stream/:id/close", api.closeStream)
+		apiGroup.DELETE("/stream/:id", api.cancelStream)
 
 		// Transactional save-request endpoint under /api as well (compat)
 		apiGroup.POST("/v1/save-request", api.saveRequest)
@@ -385,6 +404,22 @@ func (api *API) listMessages(c *gin.Context) {
 	c.JSON(http.StatusOK, messages)
 }
 
+// List registered services and their RPC methods
+func (api *API) listServices(c *gin.Context) {
+	if err := api.ensureRegistryLoaded(); err != nil {
+		api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded")
+	}
+
+	services, err := api.registry.ListServices()
+	if err != nil {
+		api.logger.Error().Err(err).Msg("Failed to list services")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
 // Get message field details
 func (api *API) getMessageFields(c *gin.Context) {
 	fqn := c.Param("fqn")
@@ -435,6 +470,39 @@ func (api *API) getMessageSchema(c *gin.Context) {
 	c.JSON(http.StatusOK, schema)
 }
 
+// getMessageExample returns example dot-path body fields for a message. The mode query
+// parameter is "minimal" (default) or "full".
+func (api *API) getMessageExample(c *gin.Context) {
+	fqn := c.Param("fqn")
+	if fqn == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message FQN is required"})
+		return
+	}
+
+	mode := registry.ExampleMode(c.DefaultQuery("mode", string(registry.ExampleMinimal)))
+	if mode != registry.ExampleMinimal && mode != registry.ExampleFull {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid mode %q: want minimal or full", mode)})
+		return
+	}
+
+	if err := api.ensureRegistryLoaded(); err != nil {
+		api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded")
+	}
+
+	body, err := api.registry.GetSchemaService().GenerateExample(fqn, mode)
+	if err != nil {
+		api.logger.Error().Err(err).Str("fqn", fqn).Msg("Failed to generate message example")
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("message not found: %s", fqn)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messageType": fqn,
+		"mode":        mode,
+		"body":        body,
+	})
+}
+
 // ensureRegistryLoaded attempts to load registry from DB if the in-memory registry is empty
 func (api *API) ensureRegistryLoaded() error {
 	// Simple heuristic: if ListMessageTypes returns 0, try to LoadFromDatabase
@@ -551,7 +619,7 @@ func (api *API) flattenMessageFields(fqn string, prefix string, visited map[stri
 func (api *API) listCollections(c *gin.Context) {
 	if apiRunnerPool != nil {
 		ctx := context.Background()
-		rows, err := apiRunnerPool.Query(ctx, `SELECT id, name, description, created_at FROM collections ORDER BY created_at ASC`)
+		rows, err := apiRunnerPool.Query(ctx, `SELECT id, name, description, variables, strict_variables, created_at FROM collections ORDER BY created_at ASC`)
 		if err != nil {
 			api.logger.Error().Err(err).Msg("Failed to query collections")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list collections"})
@@ -563,25 +631,31 @@ func (api *API) listCollections(c *gin.Context) {
 			var id uuid.UUID
 			var name string
 			var desc sql.NullString
+			var varsJSON []byte
+			var strict bool
 			var createdAt time.Time
-			if err := rows.Scan(&id, &name, &desc, &createdAt); err != nil {
+			if err := rows.Scan(&id, &name, &desc, &varsJSON, &strict, &createdAt); err != nil {
 				continue
 			}
 			// Load requests
 			reqRows, _ := apiRunnerPool.Query(ctx, `
-				SELECT id, name, verb, url, headers, body_model, proto_message_fqmn, response_message_fqmn, error_response_message_fqmn, last_response, last_response_at
+				SELECT id, name, verb, url, headers, body_model, proto_message_fqmn, response_message_fqmn, error_response_message_fqmn, response_type_matchers, oneof_selections, body_presence, body_encodings, bytes_display, body_mode, raw_body, protocol, service_method, last_response, last_response_at
 				FROM requests WHERE collection_id=$1 ORDER BY created_at ASC`, id)
 			requests := make([]*types.Request, 0)
 			for reqRows.Next() {
 				var rid uuid.UUID
 				var rname, verb, url string
-				var hdrsJSON, bodyJSON []byte
+				var bodyMode, rawBody, protocol, serviceMethod string
+				var hdrsJSON, bodyJSON, matchersJSON, oneofsJSON, presenceJSON, encodingsJSON, displayJSON []byte
 				var protoFQ, respFQ, errRespFQ sql.NullString
 				var lastRespJSON []byte
 				var lastRespAt sql.NullTime
-				if err := reqRows.Scan(&rid, &rname, &verb, &url, &hdrsJSON, &bodyJSON, &protoFQ, &respFQ, &errRespFQ, &lastRespJSON, &lastRespAt); err == nil {
+				if err := reqRows.Scan(&rid, &rname, &verb, &url, &hdrsJSON, &bodyJSON, &protoFQ, &respFQ, &errRespFQ, &matchersJSON, &oneofsJSON, &presenceJSON, &encodingsJSON, &displayJSON, &bodyMode, &rawBody, &protocol, &serviceMethod, &lastRespJSON, &lastRespAt); err == nil {
 					headers := parseHeadersJSON(hdrsJSON)
-					body := parseBodyJSON(bodyJSON)
+					body := parseBodyJSON(bodyJSON, presenceJSON, encodingsJSON)
+					responseTypes := parseResponseTypesJSON(matchersJSON)
+					oneofSelections := parseOneofSelectionsJSON(oneofsJSON)
+					bytesDisplay := parseBytesDisplayJSON(displayJSON)
 					var last map[string]any
 					if len(lastRespJSON) > 0 {
 						_ = json.Unmarshal(lastRespJSON, &last)
@@ -596,11 +670,18 @@ func (api *API) listCollections(c *gin.Context) {
 						Name:              rname,
 						Method:            verb,
 						URL:               url,
+						Protocol:          protocol,
+						ServiceMethod:     serviceMethod,
 						Headers:           headers,
+						BodyMode:          bodyMode,
 						Body:              body,
+						RawBody:           rawBody,
 						ProtoMessage:      protoFQ.String,
 						ResponseType:      respFQ.String,
 						ErrorResponseType: errRespFQ.String,
+						ResponseTypes:     responseTypes,
+						OneofSelections:   oneofSelections,
+						BytesDisplay:      bytesDisplay,
 						LastResponse:      last,
 						LastResponseAt:    lastAtPtr,
 					})
@@ -608,14 +689,15 @@ func (api *API) listCollections(c *gin.Context) {
 			}
 			reqRows.Close()
 			collections = append(collections, &types.Collection{
-				ID:          id.String(),
-				Name:        name,
-				Description: desc.String,
-				ProtoRoots:  []string{},
-				Variables:   map[string]string{},
-				Requests:    requests,
-				CreatedAt:   createdAt,
-				UpdatedAt:   createdAt,
+				ID:              id.String(),
+				Name:            name,
+				Description:     desc.String,
+				ProtoRoots:      []string{},
+				Variables:       parseVariablesJSON(varsJSON),
+				StrictVariables: strict,
+				Requests:        requests,
+				CreatedAt:       createdAt,
+				UpdatedAt:       createdAt,
 			})
 		}
 		c.JSON(http.StatusOK, collections)
@@ -645,21 +727,25 @@ func (api *API) createCollection(c *gin.Context) {
 		if strings.TrimSpace(req.Description) != "" {
 			desc = sql.NullString{String: req.Description, Valid: true}
 		}
-		_, err := apiRunnerPool.Exec(ctx, `INSERT INTO collections (id, name, description) VALUES ($1,$2,$3)`, id, req.Name, desc)
+		if req.Variables == nil {
+			req.Variables = map[string]string{}
+		}
+		_, err := apiRunnerPool.Exec(ctx, `INSERT INTO collections (id, name, description, variables, strict_variables) VALUES ($1,$2,$3,$4,$5)`, id, req.Name, desc, req.Variables, req.StrictVariables)
 		if err != nil {
 			api.logger.Error().Err(err).Msg("Failed to insert collection")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create collection"})
 			return
 		}
 		c.JSON(http.StatusCreated, &types.Collection{
-			ID:          id.String(),
-			Name:        req.Name,
-			Description: req.Description,
-			ProtoRoots:  req.ProtoRoots,
-			Variables:   req.Variables,
-			Requests:    []*types.Request{},
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			ID:              id.String(),
+			Name:            req.Name,
+			Description:     req.Description,
+			ProtoRoots:      req.ProtoRoots,
+			Variables:       req.Variables,
+			StrictVariables: req.StrictVariables,
+			Requests:        []*types.Request{},
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
 		})
 		return
 	}
@@ -680,27 +766,33 @@ func (api *API) getCollection(c *gin.Context) {
 		var uuidID uuid.UUID
 		var name string
 		var desc sql.NullString
+		var varsJSON []byte
+		var strict bool
 		var createdAt time.Time
-		row := apiRunnerPool.QueryRow(ctx, `SELECT id, name, description, created_at FROM collections WHERE id=$1`, id)
-		if err := row.Scan(&uuidID, &name, &desc, &createdAt); err != nil {
+		row := apiRunnerPool.QueryRow(ctx, `SELECT id, name, description, variables, strict_variables, created_at FROM collections WHERE id=$1`, id)
+		if err := row.Scan(&uuidID, &name, &desc, &varsJSON, &strict, &createdAt); err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
 			return
 		}
 		// Load requests
 		reqRows, _ := apiRunnerPool.Query(ctx, `
-			SELECT id, name, verb, url, headers, body_model, proto_message_fqmn, response_message_fqmn, error_response_message_fqmn, last_response, last_response_at
+			SELECT id, name, verb, url, headers, body_model, proto_message_fqmn, response_message_fqmn, error_response_message_fqmn, response_type_matchers, oneof_selections, body_presence, body_encodings, bytes_display, body_mode, raw_body, protocol, service_method, last_response, last_response_at
 			FROM requests WHERE collection_id=$1 ORDER BY created_at ASC`, uuidID)
 		requests := make([]*types.Request, 0)
 		for reqRows.Next() {
 			var rid uuid.UUID
 			var rname, verb, url string
-			var hdrsJSON, bodyJSON []byte
+			var bodyMode, rawBody, protocol, serviceMethod string
+			var hdrsJSON, bodyJSON, matchersJSON, oneofsJSON, presenceJSON, encodingsJSON, displayJSON []byte
 			var protoFQ, respFQ, errRespFQ sql.NullString
 			var lastRespJSON []byte
 			var lastRespAt sql.NullTime
-			if err := reqRows.Scan(&rid, &rname, &verb, &url, &hdrsJSON, &bodyJSON, &protoFQ, &respFQ, &errRespFQ, &lastRespJSON, &lastRespAt); err == nil {
+			if err := reqRows.Scan(&rid, &rname, &verb, &url, &hdrsJSON, &bodyJSON, &protoFQ, &respFQ, &errRespFQ, &matchersJSON, &oneofsJSON, &presenceJSON, &encodingsJSON, &displayJSON, &bodyMode, &rawBody, &protocol, &serviceMethod, &lastRespJSON, &lastRespAt); err == nil {
 				headers := parseHeadersJSON(hdrsJSON)
-				body := parseBodyJSON(bodyJSON)
+				body := parseBodyJSON(bodyJSON, presenceJSON, encodingsJSON)
+				responseTypes := parseResponseTypesJSON(matchersJSON)
+				oneofSelections := parseOneofSelectionsJSON(oneofsJSON)
+				bytesDisplay := parseBytesDisplayJSON(displayJSON)
 				var last map[string]any
 				if len(lastRespJSON) > 0 {
 					_ = json.Unmarshal(lastRespJSON, &last)
@@ -715,11 +807,18 @@ func (api *API) getCollection(c *gin.Context) {
 					Name:              rname,
 					Method:            verb,
 					URL:               url,
+					Protocol:          protocol,
+					ServiceMethod:     serviceMethod,
 					Headers:           headers,
+					BodyMode:          bodyMode,
 					Body:              body,
+					RawBody:           rawBody,
 					ProtoMessage:      protoFQ.String,
 					ResponseType:      respFQ.String,
 					ErrorResponseType: errRespFQ.String,
+					ResponseTypes:     responseTypes,
+					OneofSelections:   oneofSelections,
+					BytesDisplay:      bytesDisplay,
 					LastResponse:      last,
 					LastResponseAt:    lastAtPtr,
 				})
@@ -727,14 +826,15 @@ func (api *API) getCollection(c *gin.Context) {
 		}
 		reqRows.Close()
 		c.JSON(http.StatusOK, &types.Collection{
-			ID:          uuidID.String(),
-			Name:        name,
-			Description: desc.String,
-			ProtoRoots:  []string{},
-			Variables:   map[string]string{},
-			Requests:    requests,
-			CreatedAt:   createdAt,
-			UpdatedAt:   createdAt,
+			ID:              uuidID.String(),
+			Name:            name,
+			Description:     desc.String,
+			ProtoRoots:      []string{},
+			Variables:       parseVariablesJSON(varsJSON),
+			StrictVariables: strict,
+			Requests:        requests,
+			CreatedAt:       createdAt,
+			UpdatedAt:       createdAt,
 		})
 		return
 	}
@@ -761,7 +861,10 @@ func (api *API) updateCollection(c *gin.Context) {
 		if strings.TrimSpace(collection.Description) != "" {
 			desc = sql.NullString{String: collection.Description, Valid: true}
 		}
-		ct, err := apiRunnerPool.Exec(ctx, `UPDATE collections SET name=$2, description=$3 WHERE id=$1`, id, collection.Name, desc)
+		if collection.Variables == nil {
+			collection.Variables = map[string]string{}
+		}
+		ct, err := apiRunnerPool.Exec(ctx, `UPDATE collections SET name=$2, description=$3, variables=$4, strict_variables=$5 WHERE id=$1`, id, collection.Name, desc, collection.Variables, collection.StrictVariables)
 		if err != nil {
 			api.logger.Error().Err(err).Msg("Failed to update collection")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update collection"})
@@ -819,6 +922,10 @@ func (api *API) createRequest(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := runner.ValidateResponseTypes(req.ResponseTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if apiRunnerPool != nil {
 		ctx := context.Background()
@@ -894,6 +1001,10 @@ func (api *API) updateRequest(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := runner.ValidateResponseTypes(req.ResponseTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if apiRunnerPool != nil {
 		ctx := context.Background()
@@ -1094,6 +1205,13 @@ func (api *API) runRequest(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := req.ValidateMethod(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CollectionID != "" {
+		req.StrictVariables = req.StrictVariables || api.collectionStrictVariables(req.CollectionID)
+	}
 
 	// Ensure registry loaded before running
 	if err := api.ensureRegistryLoaded(); err != nil {
@@ -1104,36 +1222,39 @@ func (api *API) runRequest(c *gin.Context) {
 	result, err := api.runner.Run(&req)
 	if err != nil {
 		api.logger.Error().Err(err).Msg("Failed to execute request")
+		if bodyValidationResponse(c, err) || unresolvedVariablesResponse(c, err) || variableCycleResponse(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Persist last response if requestId provided and DB configured
-	if apiRunnerPool != nil {
-		var ids struct{
-			CollectionId *string `json:"collectionId"`
-			RequestId    *string `json:"requestId"`
-		}
-		// Ignore bind error; we’ll persist only if present
-		_ = c.ShouldBindJSON(&ids)
-		if ids.RequestId != nil {
-			respObj := map[string]any{
-				"status": result.Status,
-				"headers": result.Headers,
-				"decoded": result.Decoded,
-				"raw": result.Raw,
-				"decodeError": result.DecodeError,
-			}
-			if buf, err := json.Marshal(respObj); err == nil {
-				ctx := context.Background()
-				_, _ = apiRunnerPool.Exec(ctx, `UPDATE requests SET last_response=$2, last_response_at=NOW() WHERE id=$1`, *ids.RequestId, buf)
-			}
-		}
+	// Persist last response if the run is of a saved request
+	if req.RequestID != "" {
+		api.saveLastResponse(req.RequestID, result)
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
+// saveLastResponse stores a run result as the request's last response when a DB is configured
+func (api *API) saveLastResponse(requestID string, result *runner.RunRes) {
+	if apiRunnerPool == nil {
+		return
+	}
+	respObj := map[string]any{
+		"status":      result.Status,
+		"headers":     result.Headers,
+		"decoded":     result.Decoded,
+		"raw":         result.Raw,
+		"decodeError": result.DecodeError,
+	}
+	if buf, err := json.Marshal(respObj); err == nil {
+		ctx := context.Background()
+		_, _ = apiRunnerPool.Exec(ctx, `UPDATE requests SET last_response=$2, last_response_at=NOW() WHERE id=$1`, requestID, buf)
+	}
+}
+
 // helper to convert map headers to HeaderKV slice
 func toHeaderKV(m map[string]string) []types.HeaderKV {
 	if m == nil {
@@ -1158,17 +1279,71 @@ func parseHeadersJSON(b []byte) []types.HeaderKV {
 	return toHeaderKV(m)
 }
 
-func parseBodyJSON(b []byte) []types.BodyField {
+// parseResponseTypesJSON decodes stored status matchers, tolerating empty or invalid JSON
+func parseResponseTypesJSON(b []byte) []types.ResponseTypeMatcher {
+	matchers := []types.ResponseTypeMatcher{}
+	if len(b) == 0 {
+		return matchers
+	}
+	_ = json.Unmarshal(b, &matchers)
+	return matchers
+}
+
+// parseOneofSelectionsJSON decodes stored oneof selections, tolerating empty or invalid JSON
+func parseOneofSelectionsJSON(b []byte) types.OneofSelections {
+	selections := types.OneofSelections{}
+	if len(b) == 0 {
+		return selections
+	}
+	_ = json.Unmarshal(b, &selections)
+	return selections
+}
+
+// parseVariablesJSON decodes stored collection variables, tolerating empty or invalid JSON
+func parseVariablesJSON(b []byte) map[string]string {
+	vars := map[string]string{}
+	if len(b) == 0 {
+		return vars
+	}
+	_ = json.Unmarshal(b, &vars)
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	return vars
+}
+
+func parseBytesDisplayJSON(b []byte) types.BytesDisplay {
+	display := types.BytesDisplay{}
+	if len(b) == 0 {
+		return display
+	}
+	_ = json.Unmarshal(b, &display)
+	return display
+}
+
+// parseBodyJSON expands a stored body_model into body fields, carrying the presence mode
+// and bytes encoding stored for each path in body_presence and body_encodings
+func parseBodyJSON(b, presenceJSON, encodingsJSON []byte) []types.BodyField {
 	if len(b) == 0 || string(b) == "null" {
 		return []types.BodyField{}
 	}
+	// Decode numbers as json.Number so stored 64-bit IDs are not rounded through float64
 	var raw map[string]any
-	if err := json.Unmarshal(b, &raw); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
 		return []types.BodyField{}
 	}
+	presence, encodings := map[string]string{}, map[string]string{}
+	if len(presenceJSON) > 0 {
+		_ = json.Unmarshal(presenceJSON, &presence)
+	}
+	if len(encodingsJSON) > 0 {
+		_ = json.Unmarshal(encodingsJSON, &encodings)
+	}
 	fields := make([]types.BodyField, 0, len(raw))
 	for k, v := range raw {
-		fields = append(fields, types.BodyField{Path: k, Value: v})
+		fields = append(fields, types.BodyField{Path: k, Value: v, Presence: presence[k], Encoding: encodings[k]})
 	}
 	return fields
 }
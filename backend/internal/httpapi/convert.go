@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/datahopper/backend/internal/runner"
+	"github.com/gin-gonic/gin"
+)
+
+// convertBody handles POST /api/body/convert. It rewrites a request body between the
+// dot-path fields, protojson and text-format modes so the editor can switch views.
+func (api *API) convertBody(c *gin.Context) {
+	var req runner.ConvertBodyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := api.ensureRegistryLoaded(); err != nil {
+		api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded before conversion")
+	}
+
+	res, err := api.runner.ConvertBody(&req)
+	if err != nil {
+		if bodyValidationResponse(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// flattenBody handles POST /api/body/flatten. It turns a JSON payload, such as a decoded
+// response, into dot-path fields so it can be edited as a request body.
+func (api *API) flattenBody(c *gin.Context) {
+	var req runner.FlattenBodyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MessageType != "" {
+		if err := api.ensureRegistryLoaded(); err != nil {
+			api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded before flattening")
+		}
+	}
+
+	fields, err := api.runner.FlattenBody(&req)
+	if err != nil {
+		if bodyValidationResponse(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"body": fields})
+}
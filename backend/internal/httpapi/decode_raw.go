@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/datahopper/backend/internal/rawproto"
+	"github.com/gin-gonic/gin"
+)
+
+// DecodeRawPayload carries protobuf wire data to decode without a schema
+type DecodeRawPayload struct {
+	Data     string `json:"data" binding:"required"`
+	Encoding string `json:"encoding"` // base64 (default) or hex
+}
+
+// decodeRaw handles POST /api/decode-raw and decodes a payload like protoc --decode_raw
+func (api *API) decodeRaw(c *gin.Context) {
+	var payload DecodeRawPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var data []byte
+	var err error
+	switch strings.ToLower(payload.Encoding) {
+	case "", "base64":
+		data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(payload.Data))
+	case "hex":
+		data, err = hex.DecodeString(strings.Join(strings.Fields(payload.Data), ""))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "encoding must be base64 or hex"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data: " + err.Error()})
+		return
+	}
+
+	fields, err := rawproto.Decode(data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fields": fields,
+		"text":   rawproto.Format(fields),
+	})
+}
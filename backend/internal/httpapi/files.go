@@ -0,0 +1,176 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/datahopper/backend/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRequestFileSize caps files uploaded for bytes fields
+const maxRequestFileSize = 10 << 20
+
+// uploadRequestFile handles POST /api/collections/:id/requests/:requestId/files. The
+// multipart "file" part is stored with the request; bytes fields reference it by the
+// returned ID with the "file" encoding.
+func (api *API) uploadRequestFile(c *gin.Context) {
+	collectionID := c.Param("id")
+	requestID := c.Param("requestId")
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file: " + err.Error()})
+		return
+	}
+	if header.Size > maxRequestFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds %d bytes", maxRequestFileSize)})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open file: " + err.Error()})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file: " + err.Error()})
+		return
+	}
+	contentType := header.Header.Get("Content-Type")
+
+	if apiRunnerPool != nil {
+		ctx := context.Background()
+		file := &types.RequestFile{
+			ID:          uuid.NewString(),
+			Name:        header.Filename,
+			ContentType: contentType,
+			Size:        len(data),
+			CreatedAt:   time.Now(),
+		}
+		ct, err := apiRunnerPool.Exec(ctx, `INSERT INTO request_files (id, request_id, name, content_type, data, created_at)
+			SELECT $1, id, $3, $4, $5, $6 FROM requests WHERE id=$2 AND collection_id=$7`,
+			file.ID, requestID, file.Name, file.ContentType, data, file.CreatedAt, collectionID)
+		if err != nil {
+			api.logger.Error().Err(err).Msg("Failed to store request file")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+			return
+		}
+		if ct.RowsAffected() == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+			return
+		}
+		c.JSON(http.StatusCreated, file)
+		return
+	}
+
+	file, err := api.workspace.AddRequestFile(collectionID, requestID, header.Filename, contentType, data)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	c.JSON(http.StatusCreated, file)
+}
+
+// listRequestFiles handles GET /api/collections/:id/requests/:requestId/files
+func (api *API) listRequestFiles(c *gin.Context) {
+	collectionID := c.Param("id")
+	requestID := c.Param("requestId")
+
+	if apiRunnerPool != nil {
+		ctx := context.Background()
+		rows, err := apiRunnerPool.Query(ctx, `SELECT f.id, f.name, f.content_type, length(f.data), f.created_at
+			FROM request_files f JOIN requests r ON r.id = f.request_id
+			WHERE r.id=$1 AND r.collection_id=$2 ORDER BY f.created_at ASC`, requestID, collectionID)
+		if err != nil {
+			api.logger.Error().Err(err).Msg("Failed to query request files")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list files"})
+			return
+		}
+		defer rows.Close()
+		files := make([]*types.RequestFile, 0)
+		for rows.Next() {
+			var id uuid.UUID
+			file := &types.RequestFile{}
+			if err := rows.Scan(&id, &file.Name, &file.ContentType, &file.Size, &file.CreatedAt); err != nil {
+				continue
+			}
+			file.ID = id.String()
+			files = append(files, file)
+		}
+		c.JSON(http.StatusOK, files)
+		return
+	}
+
+	request, err := api.workspace.GetRequest(collectionID, requestID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	files := request.Files
+	if files == nil {
+		files = []*types.RequestFile{}
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// deleteRequestFile handles DELETE /api/collections/:id/requests/:requestId/files/:fileId
+func (api *API) deleteRequestFile(c *gin.Context) {
+	collectionID := c.Param("id")
+	requestID := c.Param("requestId")
+	fileID := c.Param("fileId")
+
+	if apiRunnerPool != nil {
+		if _, err := uuid.Parse(fileID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		ctx := context.Background()
+		ct, err := apiRunnerPool.Exec(ctx, `DELETE FROM request_files f USING requests r
+			WHERE f.id=$1 AND f.request_id = r.id AND r.id=$2 AND r.collection_id=$3`, fileID, requestID, collectionID)
+		if err != nil {
+			api.logger.Error().Err(err).Msg("Failed to delete request file")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete file"})
+			return
+		}
+		if ct.RowsAffected() == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+		return
+	}
+
+	if err := api.workspace.DeleteRequestFile(collectionID, requestID, fileID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+}
+
+// loadRequestFile is the runner's FileResolver for bytes fields with the "file" encoding.
+// Only files uploaded with the request being run are found.
+func (api *API) loadRequestFile(collectionID, requestID, fileID string) ([]byte, error) {
+	if apiRunnerPool != nil {
+		if _, err := uuid.Parse(fileID); err != nil {
+			return nil, fmt.Errorf("file not found: %s", fileID)
+		}
+		var data []byte
+		row := apiRunnerPool.QueryRow(context.Background(), `SELECT f.data FROM request_files f JOIN requests r ON r.id = f.request_id
+			WHERE f.id=$1 AND f.request_id=$2 AND r.collection_id=$3`, fileID, requestID, collectionID)
+		if err := row.Scan(&data); err != nil {
+			return nil, fmt.Errorf("file not found: %s", fileID)
+		}
+		return data, nil
+	}
+	file, err := api.workspace.FindRequestFile(collectionID, requestID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return file.Data, nil
+}
@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateRequest_RejectsInvalidResponseTypes(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	api := buildTestAPI(t)
+	r := gin.New()
+	api.SetupRoutes(r)
+	collection, err := api.workspace.CreateCollection(&types.CreateCollectionRequest{Name: "orders"})
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+
+	for payload, want := range map[string]int{
+		`{"name":"a","method":"GET","url":"http://x","responseTypes":[{"status":"4xx","messageType":"a.B"}]}`:  http.StatusCreated,
+		`{"name":"b","method":"GET","url":"http://x","responseTypes":[{"status":"nope","messageType":"a.B"}]}`: http.StatusBadRequest,
+		`{"name":"c","method":"GET","url":"http://x","responseTypes":[{"status":"404"}]}`:                      http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/collections/"+collection.ID+"/requests", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", payload, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRunRequest_MethodRequiredForHTTPOnly(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	api := buildTestAPI(t)
+	r := gin.New()
+	api.SetupRoutes(r)
+
+	for payload, wantRejected := range map[string]bool{
+		`{"url":"http://127.0.0.1:1/orders"}`:                                                     true,
+		`{"url":"http://127.0.0.1:1/orders","protocol":"http"}`:                                   true,
+		`{"url":"127.0.0.1:1","protocol":"grpc","serviceMethod":"order.v1.Orders/Get"}`:           false,
+		`{"url":"http://127.0.0.1:1","protocol":"connect","serviceMethod":"order.v1.Orders/Get"}`: false,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		rejected := w.Code == http.StatusBadRequest && strings.Contains(w.Body.String(), "method is required")
+		if rejected != wantRejected {
+			t.Errorf("%s: expected method rejection %v, got %d: %s", payload, wantRejected, w.Code, w.Body.String())
+		}
+	}
+}
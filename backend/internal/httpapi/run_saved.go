@@ -0,0 +1,238 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/runner"
+	"github.com/datahopper/backend/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RunSavedReq runs a stored request by reference. Only what a saved request does not carry
+// is given here; everything else comes from the request itself.
+type RunSavedReq struct {
+	Environment        string            `json:"environment,omitempty"`        // Active environment preference when empty
+	Variables          map[string]string `json:"variables,omitempty"`          // Per-run overrides, above every scope
+	TimeoutSeconds     int               `json:"timeoutSeconds,omitempty"`     // Replaces the saved timeout when set
+	Protocol           string            `json:"protocol,omitempty"`           // Replaces the saved protocol when set
+	ServiceMethod      string            `json:"serviceMethod,omitempty"`      // Replaces the saved RPC method when set
+	EnforceConstraints bool              `json:"enforceConstraints,omitempty"` // Reject bodies that break validate options
+	StrictVariables    bool              `json:"strictVariables,omitempty"`    // Fail on unresolved placeholders even if the collection is lenient
+}
+
+// ResolvedVariable is a merged variable and the scope its value was taken from
+type ResolvedVariable struct {
+	Value string `json:"value"`
+	Scope string `json:"scope"` // global, collection, environment or override
+}
+
+// RunSavedRes is the run result with the variables it was run with
+type RunSavedRes struct {
+	*runner.RunRes
+	Environment string                      `json:"environment,omitempty"`
+	Variables   map[string]ResolvedVariable `json:"variables"`
+}
+
+// runSavedRequest handles POST /api/collections/:id/requests/:requestId/run. The saved
+// request is run with variables merged from the globals environment, its collection, the
+// chosen environment and the per-run overrides, in that order.
+func (api *API) runSavedRequest(c *gin.Context) {
+	collectionID := c.Param("id")
+	requestID := c.Param("requestId")
+
+	var req RunSavedReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	saved, collection, err := api.loadSavedRequest(collectionID, requestID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+
+	envName := req.Environment
+	if envName == "" {
+		envName = api.activeEnvironment()
+	}
+	var envVars map[string]string
+	if envName != "" {
+		env, err := api.loadEnvironment(envName)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found: " + envName})
+			return
+		}
+		envVars = env.Variables
+	}
+	var globalVars map[string]string
+	if env, err := api.loadEnvironment(types.GlobalEnvironment); err == nil {
+		globalVars = env.Variables
+	}
+
+	vars, sources := interpolate.MergeScopes(
+		interpolate.Scope{Name: interpolate.ScopeGlobal, Variables: globalVars},
+		interpolate.Scope{Name: interpolate.ScopeCollection, Variables: collection.Variables},
+		interpolate.Scope{Name: interpolate.ScopeEnvironment, Variables: envVars},
+		interpolate.Scope{Name: interpolate.ScopeOverride, Variables: req.Variables},
+	)
+
+	runReq := savedRunReq(collectionID, saved, vars)
+	if req.TimeoutSeconds > 0 {
+		runReq.TimeoutSeconds = req.TimeoutSeconds
+	}
+	if req.Protocol != "" {
+		runReq.Protocol = req.Protocol
+	}
+	if req.ServiceMethod != "" {
+		runReq.ServiceMethod = req.ServiceMethod
+	}
+	runReq.EnforceConstraints = req.EnforceConstraints
+	runReq.StrictVariables = req.StrictVariables || collection.StrictVariables
+
+	if err := api.ensureRegistryLoaded(); err != nil {
+		api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded before run")
+	}
+
+	result, err := api.runner.Run(runReq)
+	if err != nil {
+		api.logger.Error().Err(err).Str("request_id", requestID).Msg("Failed to execute saved request")
+		if bodyValidationResponse(c, err) || unresolvedVariablesResponse(c, err) || variableCycleResponse(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	api.saveLastResponse(saved.ID, result)
+
+	resolved := make(map[string]ResolvedVariable, len(vars))
+	for name, value := range vars {
+		resolved[name] = ResolvedVariable{Value: value, Scope: sources[name]}
+	}
+	c.JSON(http.StatusOK, RunSavedRes{RunRes: result, Environment: envName, Variables: resolved})
+}
+
+// savedRunReq builds the run request for a saved request of a collection
+func savedRunReq(collectionID string, saved *types.Request, vars map[string]string) *runner.RunReq {
+	headers := make(map[string]string, len(saved.Headers))
+	for _, h := range saved.Headers {
+		if strings.TrimSpace(h.Key) != "" {
+			headers[h.Key] = h.Value
+		}
+	}
+	return &runner.RunReq{
+		Method:            saved.Method,
+		URL:               saved.URL,
+		Protocol:          saved.Protocol,
+		ServiceMethod:     saved.ServiceMethod,
+		ProtoMessage:      saved.ProtoMessage,
+		ResponseType:      saved.ResponseType,
+		ErrorResponseType: saved.ErrorResponseType,
+		Headers:           headers,
+		BodyMode:          saved.BodyMode,
+		Body:              saved.Body,
+		RawBody:           saved.RawBody,
+		TimeoutSeconds:    saved.TimeoutSeconds,
+		Variables:         vars,
+		ResponseTypes:     saved.ResponseTypes,
+		OneofSelections:   saved.OneofSelections,
+		BytesDisplay:      saved.BytesDisplay,
+		CollectionID:      collectionID,
+		RequestID:         saved.ID,
+	}
+}
+
+// loadSavedRequest returns a stored request with its collection
+func (api *API) loadSavedRequest(collectionID, requestID string) (*types.Request, *types.Collection, error) {
+	if apiRunnerPool == nil {
+		collection, err := api.workspace.GetCollection(collectionID)
+		if err != nil {
+			return nil, nil, err
+		}
+		request, err := api.workspace.GetRequest(collectionID, requestID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return request, collection, nil
+	}
+
+	ctx := context.Background()
+	var rid uuid.UUID
+	var rname, verb, url, protocol, serviceMethod, bodyMode, rawBody string
+	var hdrsJSON, bodyJSON, matchersJSON, oneofsJSON, presenceJSON, encodingsJSON, displayJSON, varsJSON []byte
+	var protoFQ, respFQ, errRespFQ sql.NullString
+	var timeoutMs sql.NullInt32
+	var strict bool
+	row := apiRunnerPool.QueryRow(ctx, `
+		SELECT r.id, r.name, r.verb, r.url, r.protocol, r.service_method, r.headers, r.body_model, r.proto_message_fqmn, r.response_message_fqmn, r.error_response_message_fqmn, r.response_type_matchers, r.oneof_selections, r.body_presence, r.body_encodings, r.bytes_display, r.body_mode, r.raw_body, r.timeout_ms, c.variables, c.strict_variables
+		FROM requests r JOIN collections c ON c.id = r.collection_id WHERE r.id=$1 AND r.collection_id=$2`, requestID, collectionID)
+	if err := row.Scan(&rid, &rname, &verb, &url, &protocol, &serviceMethod, &hdrsJSON, &bodyJSON, &protoFQ, &respFQ, &errRespFQ, &matchersJSON, &oneofsJSON, &presenceJSON, &encodingsJSON, &displayJSON, &bodyMode, &rawBody, &timeoutMs, &varsJSON, &strict); err != nil {
+		return nil, nil, err
+	}
+	request := &types.Request{
+		ID:                rid.String(),
+		Name:              rname,
+		Method:            verb,
+		URL:               url,
+		Protocol:          protocol,
+		ServiceMethod:     serviceMethod,
+		Headers:           parseHeadersJSON(hdrsJSON),
+		BodyMode:          bodyMode,
+		Body:              parseBodyJSON(bodyJSON, presenceJSON, encodingsJSON),
+		RawBody:           rawBody,
+		ProtoMessage:      protoFQ.String,
+		ResponseType:      respFQ.String,
+		ErrorResponseType: errRespFQ.String,
+		ResponseTypes:     parseResponseTypesJSON(matchersJSON),
+		OneofSelections:   parseOneofSelectionsJSON(oneofsJSON),
+		BytesDisplay:      parseBytesDisplayJSON(displayJSON),
+		TimeoutSeconds:    int(timeoutMs.Int32) / 1000,
+	}
+	return request, &types.Collection{ID: collectionID, Variables: parseVariablesJSON(varsJSON), StrictVariables: strict}, nil
+}
+
+// loadEnvironment returns a stored environment by name
+func (api *API) loadEnvironment(name string) (*types.Environment, error) {
+	if apiRunnerPool == nil {
+		return api.workspace.GetEnvironment(name)
+	}
+	var varsJSON []byte
+	row := apiRunnerPool.QueryRow(context.Background(), `SELECT variables FROM environments WHERE name=$1`, name)
+	if err := row.Scan(&varsJSON); err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	_ = json.Unmarshal(varsJSON, &vars)
+	return &types.Environment{Name: name, Variables: vars}, nil
+}
+
+// activeEnvironment returns the environment selected in preferences, if any
+func (api *API) activeEnvironment() string {
+	if apiRunnerPool == nil {
+		return ""
+	}
+	var active sql.NullString
+	_ = apiRunnerPool.QueryRow(context.Background(), `SELECT text_value FROM preferences WHERE key='active_environment'`).Scan(&active)
+	return strings.TrimSpace(active.String)
+}
+
+// collectionStrictVariables reports whether a collection fails runs on unresolved
+// placeholders; an unknown collection is lenient
+func (api *API) collectionStrictVariables(collectionID string) bool {
+	if apiRunnerPool == nil {
+		collection, err := api.workspace.GetCollection(collectionID)
+		return err == nil && collection.StrictVariables
+	}
+
+	var strict bool
+	row := apiRunnerPool.QueryRow(context.Background(), `SELECT strict_variables FROM collections WHERE id=$1`, collectionID)
+	return row.Scan(&strict) == nil && strict
+}
@@ -0,0 +1,279 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/runner"
+	"github.com/datahopper/backend/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRunSavedRequest_MergesVariableScopes(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	var gotPath, gotToken, gotTrace string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotToken, gotTrace = r.URL.Path, r.Header.Get("X-Token"), r.Header.Get("X-Trace")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	api := buildTestAPI(t)
+	collection, err := api.workspace.CreateCollection(&types.CreateCollectionRequest{
+		Name:      "orders",
+		Variables: map[string]string{"base": target.URL, "trace": "collection"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	saved, err := api.workspace.CreateRequest(collection.ID, &types.CreateRequestRequest{
+		Name:    "get order",
+		Method:  "GET",
+		URL:     "{{base}}/orders/{{id}}",
+		Headers: []types.HeaderKV{{Key: "X-Token", Value: "{{token}}"}, {Key: "X-Trace", Value: "{{trace}}"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	for _, env := range []*types.Environment{
+		{Name: types.GlobalEnvironment, Variables: map[string]string{"token": "global", "trace": "global", "id": "0"}},
+		{Name: "staging", Variables: map[string]string{"token": "staging"}},
+	} {
+		if err := api.workspace.CreateEnvironment(env); err != nil {
+			t.Fatalf("failed to create environment %s: %v", env.Name, err)
+		}
+	}
+
+	r := gin.New()
+	api.SetupRoutes(r)
+	url := "/api/collections/" + collection.ID + "/requests/" + saved.ID + "/run"
+
+	req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"environment":"staging","variables":{"id":"o-7"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if gotPath != "/orders/o-7" || gotToken != "staging" || gotTrace != "collection" {
+		t.Errorf("expected /orders/o-7 with token staging and trace collection, got %s, %q, %q", gotPath, gotToken, gotTrace)
+	}
+	var body RunSavedRes
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if body.Status != http.StatusOK || body.Environment != "staging" {
+		t.Errorf("expected status 200 in environment staging, got %d in %q", body.Status, body.Environment)
+	}
+	for name, want := range map[string]ResolvedVariable{
+		"base":  {Value: target.URL, Scope: "collection"},
+		"trace": {Value: "collection", Scope: "collection"},
+		"token": {Value: "staging", Scope: "environment"},
+		"id":    {Value: "o-7", Scope: "override"},
+	} {
+		if got := body.Variables[name]; got != want {
+			t.Errorf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+
+	for path, want := range map[string]int{
+		url: http.StatusNotFound, // unknown environment
+		"/api/collections/" + collection.ID + "/requests/missing/run": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"environment":"prod"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRunSavedRequest_StrictVariables(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	api := buildTestAPI(t)
+	r := gin.New()
+	api.SetupRoutes(r)
+
+	for _, strict := range []bool{false, true} {
+		collection, err := api.workspace.CreateCollection(&types.CreateCollectionRequest{
+			Name:            "orders",
+			Variables:       map[string]string{"base": target.URL},
+			StrictVariables: strict,
+		})
+		if err != nil {
+			t.Fatalf("failed to create collection: %v", err)
+		}
+		saved, err := api.workspace.CreateRequest(collection.ID, &types.CreateRequestRequest{
+			Name:    "get order",
+			Method:  "GET",
+			URL:     "{{base}}/orders/{{id}}",
+			Headers: []types.HeaderKV{{Key: "Authorization", Value: "Bearer {{token}}"}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/collections/"+collection.ID+"/requests/"+saved.ID+"/run", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var body struct {
+			Warnings            []runner.UnresolvedVariable `json:"warnings"`
+			UnresolvedVariables []runner.UnresolvedVariable `json:"unresolvedVariables"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+		want := []runner.UnresolvedVariable{
+			{Name: "id", Location: runner.LocationURL},
+			{Name: "token", Location: runner.LocationHeader, Key: "Authorization"},
+		}
+		got, wantCode := body.Warnings, http.StatusOK
+		if strict {
+			got, wantCode = body.UnresolvedVariables, http.StatusUnprocessableEntity
+		}
+		if w.Code != wantCode {
+			t.Fatalf("strict=%v: expected %d, got %d: %s", strict, wantCode, w.Code, w.Body.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("strict=%v: expected %+v, got %+v", strict, want, got)
+		}
+	}
+}
+
+func TestRunRequest_CollectionStrictVariables(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	api := buildTestAPI(t)
+	r := gin.New()
+	api.SetupRoutes(r)
+
+	for _, strict := range []bool{false, true} {
+		collection, err := api.workspace.CreateCollection(&types.CreateCollectionRequest{Name: "orders", StrictVariables: strict})
+		if err != nil {
+			t.Fatalf("failed to create collection: %v", err)
+		}
+
+		// An unsaved run of the collection's request picks up its strictness
+		payload := `{"method":"GET","url":"` + target.URL + `/orders/{{id}}","collectionId":"` + collection.ID + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		wantCode := http.StatusOK
+		if strict {
+			wantCode = http.StatusUnprocessableEntity
+		}
+		if w.Code != wantCode {
+			t.Fatalf("strict=%v: expected %d, got %d: %s", strict, wantCode, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRunSavedRequest_VariableCycle(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	api := buildTestAPI(t)
+	r := gin.New()
+	api.SetupRoutes(r)
+
+	collection, err := api.workspace.CreateCollection(&types.CreateCollectionRequest{
+		Name:      "orders",
+		Variables: map[string]string{"base": "{{host}}/v1", "host": "{{base}}"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	saved, err := api.workspace.CreateRequest(collection.ID, &types.CreateRequestRequest{
+		Name:   "get order",
+		Method: "GET",
+		URL:    "{{base}}/orders",
+	})
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/collections/"+collection.ID+"/requests/"+saved.ID+"/run", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Cycle []string `json:"cycle"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if want := []string{"base", "host", "base"}; !reflect.DeepEqual(body.Cycle, want) {
+		t.Errorf("expected cycle %v, got %v", want, body.Cycle)
+	}
+}
+
+func TestRunSavedRequest_SavedProtocol(t *testing.T) {
+	apiRunnerPool = nil // ensure no DB for this test
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	api := buildTestAPI(t)
+	r := gin.New()
+	api.SetupRoutes(r)
+
+	collection, err := api.workspace.CreateCollection(&types.CreateCollectionRequest{Name: "orders"})
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	saved, err := api.workspace.CreateRequest(collection.ID, &types.CreateRequestRequest{
+		Name:          "get order",
+		Method:        "POST",
+		URL:           target.URL,
+		Protocol:      runner.ProtocolGRPC,
+		ServiceMethod: "order.v1.Orders/Missing",
+	})
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if runReq := savedRunReq(collection.ID, saved, nil); runReq.Protocol != runner.ProtocolGRPC || runReq.ServiceMethod != "order.v1.Orders/Missing" {
+		t.Fatalf("expected the saved protocol and method, got %q %q", runReq.Protocol, runReq.ServiceMethod)
+	}
+
+	run := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/collections/"+collection.ID+"/requests/"+saved.ID+"/run", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	// The saved gRPC method is used when the run does not name one
+	if w := run(`{}`); w.Code == http.StatusOK || !strings.Contains(w.Body.String(), "order.v1.Orders") {
+		t.Errorf("expected the saved gRPC method to be resolved, got %d: %s", w.Code, w.Body.String())
+	}
+	// A protocol in the run body replaces the saved one
+	if w := run(`{"protocol": "http"}`); w.Code != http.StatusOK {
+		t.Errorf("expected the http override to run, got %d: %s", w.Code, w.Body.String())
+	}
+}
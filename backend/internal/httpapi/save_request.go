@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/datahopper/backend/internal/runner"
+	"github.com/datahopper/backend/internal/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -21,16 +23,25 @@ type SaveRequestPayload struct {
 		Description *string    `json:"description"`
 	} `json:"collection"`
 	Request struct {
-		ID                       *uuid.UUID     `json:"id"`
-		Name                     string         `json:"name"`
-		Verb                     string         `json:"verb"`
-		URL                      string         `json:"url"`
-		Headers                  map[string]any `json:"headers"`
-		BodyModel                map[string]any `json:"bodyModel"`
-		ProtoMessageFQMN         *string        `json:"protoMessageFqmn"`
-		ResponseMessageFQMN      *string        `json:"responseMessageFqmn"`
-		ErrorResponseMessageFQMN *string        `json:"errorResponseMessageFqmn"`
-		TimeoutMS                *int32         `json:"timeoutMs"`
+		ID                       *uuid.UUID                  `json:"id"`
+		Name                     string                      `json:"name"`
+		Verb                     string                      `json:"verb"`
+		URL                      string                      `json:"url"`
+		Protocol                 string                      `json:"protocol"`
+		ServiceMethod            string                      `json:"serviceMethod"`
+		Headers                  map[string]any              `json:"headers"`
+		BodyMode                 string                      `json:"bodyMode"`
+		BodyModel                map[string]any              `json:"bodyModel"`
+		RawBody                  string                      `json:"rawBody"`
+		BodyPresence             map[string]string           `json:"bodyPresence"`
+		BodyEncodings            map[string]string           `json:"bodyEncodings"`
+		ProtoMessageFQMN         *string                     `json:"protoMessageFqmn"`
+		ResponseMessageFQMN      *string                     `json:"responseMessageFqmn"`
+		ErrorResponseMessageFQMN *string                     `json:"errorResponseMessageFqmn"`
+		TimeoutMS                *int32                      `json:"timeoutMs"`
+		ResponseTypeMatchers     []types.ResponseTypeMatcher `json:"responseTypeMatchers"`
+		OneofSelections          types.OneofSelections       `json:"oneofSelections"`
+		BytesDisplay             types.BytesDisplay          `json:"bytesDisplay"`
 	} `json:"request"`
 }
 
@@ -70,9 +81,31 @@ func (api *API) saveRequest(c *gin.Context) {
 	if payload.Request.Headers == nil {
 		payload.Request.Headers = map[string]any{}
 	}
+	if payload.Request.BodyMode == "" {
+		payload.Request.BodyMode = types.BodyModeFields
+	}
 	if payload.Request.BodyModel == nil {
 		payload.Request.BodyModel = map[string]any{}
 	}
+	if payload.Request.BodyPresence == nil {
+		payload.Request.BodyPresence = map[string]string{}
+	}
+	if payload.Request.BodyEncodings == nil {
+		payload.Request.BodyEncodings = map[string]string{}
+	}
+	if payload.Request.BytesDisplay == nil {
+		payload.Request.BytesDisplay = types.BytesDisplay{}
+	}
+	if payload.Request.ResponseTypeMatchers == nil {
+		payload.Request.ResponseTypeMatchers = []types.ResponseTypeMatcher{}
+	}
+	if err := runner.ValidateResponseTypes(payload.Request.ResponseTypeMatchers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if payload.Request.OneofSelections == nil {
+		payload.Request.OneofSelections = types.OneofSelections{}
+	}
 
 	ctx := context.Background()
 	tx, err := pool.Begin(ctx)
@@ -167,8 +200,8 @@ func (api *API) saveRequest(c *gin.Context) {
 			return
 		}
 		// Update
-		_, err := tx.Exec(ctx, `UPDATE requests SET name=$2, verb=$3, url=$4, headers=$5, body_model=$6, proto_message_fqmn=$7, response_message_fqmn=$8, error_response_message_fqmn=$9, timeout_ms=$10, updated_at=NOW() WHERE id=$1`,
-			reqID, payload.Request.Name, verb, payload.Request.URL, payload.Request.Headers, payload.Request.BodyModel, payload.Request.ProtoMessageFQMN, payload.Request.ResponseMessageFQMN, payload.Request.ErrorResponseMessageFQMN, payload.Request.TimeoutMS,
+		_, err := tx.Exec(ctx, `UPDATE requests SET name=$2, verb=$3, url=$4, headers=$5, body_model=$6, proto_message_fqmn=$7, response_message_fqmn=$8, error_response_message_fqmn=$9, timeout_ms=$10, response_type_matchers=$11, oneof_selections=$12, body_presence=$13, body_encodings=$14, bytes_display=$15, body_mode=$16, raw_body=$17, protocol=$18, service_method=$19, updated_at=NOW() WHERE id=$1`,
+			reqID, payload.Request.Name, verb, payload.Request.URL, payload.Request.Headers, payload.Request.BodyModel, payload.Request.ProtoMessageFQMN, payload.Request.ResponseMessageFQMN, payload.Request.ErrorResponseMessageFQMN, payload.Request.TimeoutMS, payload.Request.ResponseTypeMatchers, payload.Request.OneofSelections, payload.Request.BodyPresence, payload.Request.BodyEncodings, payload.Request.BytesDisplay, payload.Request.BodyMode, payload.Request.RawBody, payload.Request.Protocol, payload.Request.ServiceMethod,
 		)
 		if err != nil {
 			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "requests_collection_id_name_key" {
@@ -185,8 +218,8 @@ func (api *API) saveRequest(c *gin.Context) {
 			if err == pgx.ErrNoRows {
 				// Create new
 				reqID = uuid.New()
-				_, err := tx.Exec(ctx, `INSERT INTO requests (id, collection_id, name, verb, url, headers, body_model, proto_message_fqmn, response_message_fqmn, error_response_message_fqmn, timeout_ms) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
-					reqID, colID, payload.Request.Name, verb, payload.Request.URL, payload.Request.Headers, payload.Request.BodyModel, payload.Request.ProtoMessageFQMN, payload.Request.ResponseMessageFQMN, payload.Request.ErrorResponseMessageFQMN, payload.Request.TimeoutMS,
+				_, err := tx.Exec(ctx, `INSERT INTO requests (id, collection_id, name, verb, url, headers, body_model, proto_message_fqmn, response_message_fqmn, error_response_message_fqmn, timeout_ms, response_type_matchers, oneof_selections, body_presence, body_encodings, bytes_display, body_mode, raw_body, protocol, service_method) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)`,
+					reqID, colID, payload.Request.Name, verb, payload.Request.URL, payload.Request.Headers, payload.Request.BodyModel, payload.Request.ProtoMessageFQMN, payload.Request.ResponseMessageFQMN, payload.Request.ErrorResponseMessageFQMN, payload.Request.TimeoutMS, payload.Request.ResponseTypeMatchers, payload.Request.OneofSelections, payload.Request.BodyPresence, payload.Request.BodyEncodings, payload.Request.BytesDisplay, payload.Request.BodyMode, payload.Request.RawBody, payload.Request.Protocol, payload.Request.ServiceMethod,
 				)
 				if err != nil {
 					if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "requests_collection_id_name_key" {
@@ -202,8 +235,8 @@ func (api *API) saveRequest(c *gin.Context) {
 			}
 		} else {
 			// Update existing by name
-			_, err := tx.Exec(ctx, `UPDATE requests SET verb=$2, url=$3, headers=$4, body_model=$5, proto_message_fqmn=$6, response_message_fqmn=$7, error_response_message_fqmn=$8, timeout_ms=$9, updated_at=NOW() WHERE id=$1`,
-				reqID, verb, payload.Request.URL, payload.Request.Headers, payload.Request.BodyModel, payload.Request.ProtoMessageFQMN, payload.Request.ResponseMessageFQMN, payload.Request.ErrorResponseMessageFQMN, payload.Request.TimeoutMS,
+			_, err := tx.Exec(ctx, `UPDATE requests SET verb=$2, url=$3, headers=$4, body_model=$5, proto_message_fqmn=$6, response_message_fqmn=$7, error_response_message_fqmn=$8, timeout_ms=$9, response_type_matchers=$10, oneof_selections=$11, body_presence=$12, body_encodings=$13, bytes_display=$14, body_mode=$15, raw_body=$16, protocol=$17, service_method=$18, updated_at=NOW() WHERE id=$1`,
+				reqID, verb, payload.Request.URL, payload.Request.Headers, payload.Request.BodyModel, payload.Request.ProtoMessageFQMN, payload.Request.ResponseMessageFQMN, payload.Request.ErrorResponseMessageFQMN, payload.Request.TimeoutMS, payload.Request.ResponseTypeMatchers, payload.Request.OneofSelections, payload.Request.BodyPresence, payload.Request.BodyEncodings, payload.Request.BytesDisplay, payload.Request.BodyMode, payload.Request.RawBody, payload.Request.Protocol, payload.Request.ServiceMethod,
 			)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update request"})
@@ -223,15 +256,24 @@ func (api *API) saveRequest(c *gin.Context) {
 			"id": colID,
 		},
 		Request: map[string]any{
-			"id":               reqID,
-			"collectionId":     colID,
-			"name":             payload.Request.Name,
-			"verb":             verb,
-			"url":              payload.Request.URL,
-			"headers":          payload.Request.Headers,
-			"bodyModel":        payload.Request.BodyModel,
-			"protoMessageFqmn": payload.Request.ProtoMessageFQMN,
-			"timeoutMs":        payload.Request.TimeoutMS,
+			"id":                   reqID,
+			"collectionId":         colID,
+			"name":                 payload.Request.Name,
+			"verb":                 verb,
+			"url":                  payload.Request.URL,
+			"protocol":             payload.Request.Protocol,
+			"serviceMethod":        payload.Request.ServiceMethod,
+			"headers":              payload.Request.Headers,
+			"bodyMode":             payload.Request.BodyMode,
+			"bodyModel":            payload.Request.BodyModel,
+			"rawBody":              payload.Request.RawBody,
+			"bodyPresence":         payload.Request.BodyPresence,
+			"bodyEncodings":        payload.Request.BodyEncodings,
+			"protoMessageFqmn":     payload.Request.ProtoMessageFQMN,
+			"timeoutMs":            payload.Request.TimeoutMS,
+			"responseTypeMatchers": payload.Request.ResponseTypeMatchers,
+			"oneofSelections":      payload.Request.OneofSelections,
+			"bytesDisplay":         payload.Request.BytesDisplay,
 		},
 	}
 	c.JSON(http.StatusOK, resp)
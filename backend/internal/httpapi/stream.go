@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/datahopper/backend/internal/runner"
+	"github.com/datahopper/backend/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamSendPayload carries one message to send on an open stream
+type StreamSendPayload struct {
+	Body []types.BodyField `json:"body"`
+}
+
+// openStream handles POST /api/stream and starts a streaming gRPC call
+func (api *API) openStream(c *gin.Context) {
+	var req runner.StreamReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CollectionID != "" {
+		req.StrictVariables = req.StrictVariables || api.collectionStrictVariables(req.CollectionID)
+	}
+
+	if err := api.ensureRegistryLoaded(); err != nil {
+		api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded before stream")
+	}
+
+	sess, err := api.runner.OpenStream(&req)
+	if err != nil {
+		api.logger.Error().Err(err).Msg("Failed to open stream")
+		if bodyValidationResponse(c, err) || unresolvedVariablesResponse(c, err) || variableCycleResponse(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sess)
+}
+
+// streamEvents handles GET /api/stream/:id/events and relays stream events as Server-Sent Events
+func (api *API) streamEvents(c *gin.Context) {
+	sess, ok := api.runner.GetStream(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return
+	}
+
+	events := sess.Events()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, open := <-events:
+			if !open {
+				// All events delivered; the session is finished
+				sess.Cancel()
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// sendStreamMessage handles POST /api/stream/:id/messages
+func (api *API) sendStreamMessage(c *gin.Context) {
+	sess, ok := api.runner.GetStream(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return
+	}
+
+	var payload StreamSendPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := sess.Send(payload.Body); err != nil {
+		api.logger.Error().Err(err).Str("streamId", sess.ID).Msg("Failed to send stream message")
+		if bodyValidationResponse(c, err) || unresolvedVariablesResponse(c, err) || variableCycleResponse(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message sent"})
+}
+
+// closeStream handles POST /api/stream/:id/close and half-closes the send side
+func (api *API) closeStream(c *gin.Context) {
+	sess, ok := api.runner.GetStream(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return
+	}
+
+	if err := sess.CloseSend(); err != nil {
+		api.logger.Error().Err(err).Str("streamId", sess.ID).Msg("Failed to half-close stream")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Stream half-closed"})
+}
+
+// cancelStream handles DELETE /api/stream/:id
+func (api *API) cancelStream(c *gin.Context) {
+	sess, ok := api.runner.GetStream(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return
+	}
+
+	sess.Cancel()
+	c.JSON(http.StatusOK, gin.H{"message": "Stream cancelled"})
+}
@@ -0,0 +1,81 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/runner"
+	"github.com/gin-gonic/gin"
+)
+
+// validateRequest handles POST /api/validate. It checks a run request's body against its
+// message descriptor without sending anything, so the editor can flag fields while typing.
+func (api *API) validateRequest(c *gin.Context) {
+	var req runner.RunReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := api.ensureRegistryLoaded(); err != nil {
+		api.logger.Error().Err(err).Msg("Failed to ensure registry is loaded before validation")
+	}
+
+	fieldErrors, warnings, err := api.runner.ValidateRun(&req)
+	if variableCycleResponse(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    len(fieldErrors) == 0,
+		"errors":   fieldErrors,
+		"warnings": warnings,
+	})
+}
+
+// bodyValidationResponse renders a rejected body with its per-path errors, reporting
+// whether err was a body validation failure
+func bodyValidationResponse(c *gin.Context, err error) bool {
+	var validationErr *runner.BodyValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":       err.Error(),
+		"fieldErrors": validationErr.Errors,
+	})
+	return true
+}
+
+// unresolvedVariablesResponse renders a strict run stopped by placeholders without a value,
+// reporting whether err was one
+func unresolvedVariablesResponse(c *gin.Context, err error) bool {
+	var unresolvedErr *runner.UnresolvedVariablesError
+	if !errors.As(err, &unresolvedErr) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":               err.Error(),
+		"unresolvedVariables": unresolvedErr.Variables,
+	})
+	return true
+}
+
+// variableCycleResponse renders a run stopped by variables that refer back to themselves,
+// reporting whether err was one
+func variableCycleResponse(c *gin.Context, err error) bool {
+	var cycleErr *interpolate.CycleError
+	if !errors.As(err, &cycleErr) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error": err.Error(),
+		"cycle": cycleErr.Chain,
+	})
+	return true
+}
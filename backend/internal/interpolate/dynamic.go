@@ -0,0 +1,235 @@
+package interpolate
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DynamicPrefix marks a placeholder as a built-in generator rather than a variable
+const DynamicPrefix = "$"
+
+// now is the clock behind $timestamp and $isoTimestamp
+var now = time.Now
+
+// generator produces the value of one dynamic variable from its arguments
+type generator func(args []string) (string, error)
+
+var generators = map[string]generator{
+	"uuid": func(args []string) (string, error) {
+		if err := argCount(args, 0, 0); err != nil {
+			return "", err
+		}
+		return uuid.NewString(), nil
+	},
+	"timestamp": func(args []string) (string, error) {
+		if err := argCount(args, 0, 0); err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(now().Unix(), 10), nil
+	},
+	"isoTimestamp": func(args []string) (string, error) {
+		if err := argCount(args, 0, 0); err != nil {
+			return "", err
+		}
+		return now().UTC().Format("2006-01-02T15:04:05.000Z"), nil
+	},
+	"randomInt": randomInt,
+	"randomHex": randomHex,
+	"base64": func(args []string) (string, error) {
+		if err := argCount(args, 1, 1); err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(args[0])), nil
+	},
+}
+
+// randomInt returns an integer in [min, max], 0 to 1000 when no bounds are given
+func randomInt(args []string) (string, error) {
+	if err := argCount(args, 0, 2); err != nil {
+		return "", err
+	}
+	if len(args) == 1 {
+		return "", fmt.Errorf("takes both a minimum and a maximum")
+	}
+	lo, hi := int64(0), int64(1000)
+	if len(args) == 2 {
+		var err error
+		if lo, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return "", fmt.Errorf("minimum %q is not an integer", args[0])
+		}
+		if hi, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return "", fmt.Errorf("maximum %q is not an integer", args[1])
+		}
+		if lo > hi {
+			return "", fmt.Errorf("minimum %d is above maximum %d", lo, hi)
+		}
+	}
+	span := new(big.Int).Add(new(big.Int).Sub(big.NewInt(hi), big.NewInt(lo)), big.NewInt(1))
+	n, err := rand.Int(rand.Reader, span)
+	if err != nil {
+		return "", err
+	}
+	return n.Add(n, big.NewInt(lo)).String(), nil
+}
+
+// randomHex returns the given number of random hex digits, 16 by default
+func randomHex(args []string) (string, error) {
+	if err := argCount(args, 0, 1); err != nil {
+		return "", err
+	}
+	length := 16
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > 4096 {
+			return "", fmt.Errorf("length %q must be an integer from 1 to 4096", args[0])
+		}
+		length = n
+	}
+	buf := make([]byte, (length+1)/2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf)[:length], nil
+}
+
+func argCount(args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("takes %d argument(s), got %d", min, len(args))
+		}
+		return fmt.Errorf("takes %d to %d arguments, got %d", min, max, len(args))
+	}
+	return nil
+}
+
+// IsDynamic reports whether a placeholder name refers to a built-in generator
+func IsDynamic(name string) bool {
+	return strings.HasPrefix(strings.TrimSpace(name), DynamicPrefix)
+}
+
+// Generate evaluates a dynamic placeholder such as "$randomInt 1 100". Arguments are
+// separated by spaces; quoted arguments use Go string syntax.
+func Generate(expr string) (string, error) {
+	fields, err := splitArgs(strings.TrimPrefix(strings.TrimSpace(expr), DynamicPrefix))
+	if err != nil {
+		return "", fmt.Errorf("{{%s}}: %v", expr, err)
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("{{%s}}: missing generator name", expr)
+	}
+	gen, ok := generators[fields[0]]
+	if !ok {
+		return "", fmt.Errorf("{{%s}}: unknown dynamic variable $%s", expr, fields[0])
+	}
+	value, err := gen(fields[1:])
+	if err != nil {
+		return "", fmt.Errorf("{{%s}}: $%s %v", expr, fields[0], err)
+	}
+	return value, nil
+}
+
+// splitArgs splits on spaces, keeping "quoted strings" together
+func splitArgs(s string) ([]string, error) {
+	var out []string
+	s = strings.TrimSpace(s)
+	for s != "" {
+		if s[0] == '"' {
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated quoted argument")
+			}
+			arg, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted argument %s", s[:end+1])
+			}
+			out = append(out, arg)
+			s = strings.TrimSpace(s[end+1:])
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		out = append(out, s[:end])
+		s = strings.TrimSpace(s[end:])
+	}
+	return out, nil
+}
+
+// WithDynamic returns a copy of vars with a generated value for every dynamic placeholder
+// used in values, which may be strings or structures Deep accepts, or in the variables they
+// reference. Each generator expression is evaluated once, so it resolves to the same value
+// everywhere it appears in a run, filtered or not; values already present in vars are kept.
+func WithDynamic(vars map[string]string, values ...interface{}) (map[string]string, error) {
+	result := MergeVariables(vars)
+	visited := make(map[string]bool)
+	for _, v := range values {
+		if err := collectDynamic(v, result, visited); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// collectDynamic generates the dynamic placeholders in v into vars, following references to
+// other variables once each
+func collectDynamic(v interface{}, vars map[string]string, visited map[string]bool) error {
+	switch val := v.(type) {
+	case string:
+		for _, match := range varRegex.FindAllStringSubmatch(val, -1) {
+			expr, err := parseExpression(match[1])
+			if err != nil {
+				continue
+			}
+			value, exists := vars[expr.source]
+			switch {
+			case !IsDynamic(expr.source):
+				if exists && !visited[expr.source] {
+					visited[expr.source] = true
+					if err := collectDynamic(value, vars, visited); err != nil {
+						return err
+					}
+				}
+			case !exists:
+				generated, err := Generate(expr.source)
+				if err != nil {
+					return err
+				}
+				vars[expr.source] = generated
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := collectDynamic(item, vars, visited); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range val {
+			if err := collectDynamic(item, vars, visited); err != nil {
+				return err
+			}
+		}
+	case map[string]string:
+		for _, item := range val {
+			if err := collectDynamic(item, vars, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
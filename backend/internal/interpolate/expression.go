@@ -0,0 +1,203 @@
+package interpolate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// expression is the parsed content of a {{...}} placeholder: a source, which is a variable
+// name or a dynamic generator such as "$randomInt 1 100", piped through filters
+type expression struct {
+	source  string
+	filters []filterCall
+}
+
+// filterCall is one filter of a pipeline with its arguments
+type filterCall struct {
+	name string
+	args []string
+}
+
+// filter transforms a value; defined is false while the source has no value, which only
+// default changes
+type filter struct {
+	minArgs, maxArgs int
+	apply            func(value string, defined bool, args []string) (string, bool)
+}
+
+var filters = map[string]filter{
+	"default": {1, 1, func(value string, defined bool, args []string) (string, bool) {
+		if !defined || value == "" {
+			return args[0], true
+		}
+		return value, true
+	}},
+	"upper":     {0, 0, definedOnly(strings.ToUpper)},
+	"urlencode": {0, 0, definedOnly(url.QueryEscape)},
+	"base64": {0, 0, definedOnly(func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	})},
+	"sha256": {0, 0, definedOnly(func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	})},
+	"jsonEscape": {0, 0, definedOnly(jsonEscape)},
+}
+
+// definedOnly wraps a string function as a filter that passes undefined values through
+func definedOnly(fn func(string) string) func(string, bool, []string) (string, bool) {
+	return func(value string, defined bool, _ []string) (string, bool) {
+		if !defined {
+			return value, false
+		}
+		return fn(value), true
+	}
+}
+
+// jsonEscape escapes a string for use inside a JSON string literal
+func jsonEscape(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1]
+}
+
+// parseExpression parses the content of a placeholder, such as `name | default "x" | upper`
+func parseExpression(text string) (*expression, error) {
+	parts, err := splitPipeline(text)
+	if err != nil {
+		return nil, err
+	}
+	expr := &expression{source: strings.TrimSpace(parts[0])}
+	if expr.source == "" {
+		return nil, fmt.Errorf("missing variable name")
+	}
+	for _, part := range parts[1:] {
+		args, err := splitArgs(part)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("empty filter")
+		}
+		f, ok := filters[args[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q", args[0])
+		}
+		if len(args)-1 < f.minArgs || len(args)-1 > f.maxArgs {
+			return nil, fmt.Errorf("filter %s takes %d argument(s), got %d", args[0], f.maxArgs, len(args)-1)
+		}
+		expr.filters = append(expr.filters, filterCall{name: args[0], args: args[1:]})
+	}
+	return expr, nil
+}
+
+// splitPipeline splits on the | characters outside quoted arguments
+func splitPipeline(text string) ([]string, error) {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '|':
+			if !quoted {
+				parts = append(parts, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quoted argument")
+	}
+	return append(parts, text[start:]), nil
+}
+
+// hasDefault reports whether the pipeline gives the expression a value when its source has none
+func (e *expression) hasDefault() bool {
+	for _, f := range e.filters {
+		if f.name == "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// apply runs the filters over the source value
+func (e *expression) apply(value string, defined bool) (string, bool) {
+	for _, f := range e.filters {
+		value, defined = filters[f.name].apply(value, defined, f.args)
+	}
+	return value, defined
+}
+
+// CycleError reports variables whose values refer back to themselves
+type CycleError struct {
+	Chain []string // Variables in the order they were resolved, ending with the repeated one
+}
+
+func (e *CycleError) Error() string {
+	return "variable cycle: " + strings.Join(e.Chain, " -> ")
+}
+
+// resolver expands placeholders with nested references, tracking the variables being
+// resolved to detect cycles. The first problem met is kept in err.
+type resolver struct {
+	vars  map[string]string
+	stack []string
+	err   error
+}
+
+func (r *resolver) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// expand replaces the placeholders in s. Placeholders that cannot be resolved are left as
+// they are.
+func (r *resolver) expand(s string) string {
+	return varRegex.ReplaceAllStringFunc(s, func(match string) string {
+		expr, err := parseExpression(match[2 : len(match)-2])
+		if err != nil {
+			r.fail(fmt.Errorf("%s: %v", match, err))
+			return match
+		}
+		value, defined := r.lookup(expr.source)
+		if value, defined = expr.apply(value, defined); defined {
+			return value
+		}
+		// Return original if variable not found
+		return match
+	})
+}
+
+// lookup returns the value of a source with its own placeholders expanded. Dynamic values are
+// generated text and are not expanded again.
+func (r *resolver) lookup(source string) (string, bool) {
+	value, exists := r.vars[source]
+	if !exists || IsDynamic(source) {
+		return value, exists
+	}
+	for i, name := range r.stack {
+		if name == source {
+			r.fail(&CycleError{Chain: append(append([]string{}, r.stack[i:]...), source)})
+			return "", false
+		}
+	}
+	r.stack = append(r.stack, source)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	return r.expand(value), true
+}
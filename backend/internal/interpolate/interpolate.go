@@ -1,30 +1,113 @@
 package interpolate
 
 import (
+	"encoding/json"
 	"regexp"
 	"strings"
 )
 
 var varRegex = regexp.MustCompile(`\{\{([^}]+)\}\}`)
 
-// String replaces {{var}} placeholders in a string with values from the variables map
+// looseVarRegex also matches placeholders missing their last brace, such as {{var}
+var looseVarRegex = regexp.MustCompile(`\{\{([^}]*)\}?`)
+
+// String replaces {{var}} placeholders in a string with values from the variables map.
+// Placeholders may pipe the value through filters, as in {{name | default "x" | upper}},
+// and variable values may hold placeholders of their own, which are resolved in turn.
+// Placeholders that cannot be resolved are left as they are.
 func String(s string, vars map[string]string) string {
-	return varRegex.ReplaceAllStringFunc(s, func(match string) string {
-		// Extract variable name from {{var}}
-		varName := strings.Trim(match, "{}")
-		if value, exists := vars[varName]; exists {
-			return value
+	r := &resolver{vars: vars}
+	return r.expand(s)
+}
+
+// Resolve is String reporting the first placeholder it could not evaluate: an invalid
+// expression, or a *CycleError for variables that refer back to themselves. Undefined
+// variables are not errors; ValidateVariables lists them.
+func Resolve(s string, vars map[string]string) (string, error) {
+	r := &resolver{vars: vars}
+	out := r.expand(s)
+	return out, r.err
+}
+
+// Value interpolates a single value. A string that is exactly one placeholder takes the
+// typed value of its result when that is JSON: a number, bool, null, or an object or array
+// to splice in. A JSON string such as "\"42\"" gives its content, so a variable can still
+// force a string. Anything else is interpolated as text.
+func Value(s string, vars map[string]string) interface{} {
+	out := String(s, vars)
+	if out == s || !isWholePlaceholder(s) {
+		return out
+	}
+	dec := json.NewDecoder(strings.NewReader(out))
+	dec.UseNumber()
+	var typed interface{}
+	if err := dec.Decode(&typed); err != nil || dec.More() {
+		return out
+	}
+	return typed
+}
+
+// JSON interpolates a JSON document. The value of a placeholder inside a string literal is
+// escaped for it, so quotes, backslashes and newlines in a value cannot break the document;
+// placeholders elsewhere splice their value in as it is, such as a number or an object.
+func JSON(s string, vars map[string]string) string {
+	r := &resolver{vars: vars}
+	return ReplaceJSON(s, func(placeholder string, inString bool) string {
+		value := r.expand(placeholder)
+		if inString && value != placeholder {
+			value = jsonEscape(value)
 		}
-		// Return original if variable not found
-		return match
+		return value
 	})
 }
 
-// Deep recursively interpolates strings in any data structure
+// ReplaceJSON replaces every placeholder of a JSON document with the result of fn, which is
+// told whether the placeholder sits inside a string literal
+func ReplaceJSON(s string, fn func(placeholder string, inString bool) string) string {
+	var b strings.Builder
+	inString, escaped := false, false
+	last := 0
+	for _, loc := range varRegex.FindAllStringIndex(s, -1) {
+		inString, escaped = scanJSONString(s[last:loc[0]], inString, escaped)
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(fn(s[loc[0]:loc[1]], inString))
+		last, escaped = loc[1], false
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// scanJSONString follows the string literals of a stretch of JSON, reporting whether it
+// ends inside one and whether it ends on an escaping backslash
+func scanJSONString(s string, inString, escaped bool) (bool, bool) {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case !inString:
+			inString = s[i] == '"'
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inString = false
+		}
+	}
+	return inString, escaped
+}
+
+// isWholePlaceholder reports whether s is a single {{...}} placeholder and nothing else
+func isWholePlaceholder(s string) bool {
+	loc := varRegex.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
+
+// Deep recursively interpolates strings in any data structure. Strings that are a whole
+// placeholder keep the type of their value, as Value describes; map[string]string values
+// stay strings.
 func Deep(v interface{}, vars map[string]string) interface{} {
 	switch val := v.(type) {
 	case string:
-		return String(val, vars)
+		return Value(val, vars)
 	case []interface{}:
 		result := make([]interface{}, len(val))
 		for i, item := range val {
@@ -62,17 +145,45 @@ func MergeVariables(vars ...map[string]string) map[string]string {
 	return result
 }
 
-// ExtractVariables finds all {{var}} placeholders in a string
+// Variable scopes, from the lowest precedence to the highest
+const (
+	ScopeGlobal      = "global"
+	ScopeCollection  = "collection"
+	ScopeEnvironment = "environment"
+	ScopeOverride    = "override"
+)
+
+// Scope is a named set of variables
+type Scope struct {
+	Name      string
+	Variables map[string]string
+}
+
+// MergeScopes merges scopes like MergeVariables and also returns the name of the scope each
+// variable was taken from
+func MergeScopes(scopes ...Scope) (vars map[string]string, sources map[string]string) {
+	vars, sources = make(map[string]string), make(map[string]string)
+	for _, scope := range scopes {
+		for k, v := range scope.Variables {
+			vars[k] = v
+			sources[k] = scope.Name
+		}
+	}
+	return vars, sources
+}
+
+// ExtractVariables finds the variables referenced by {{var}} placeholders in a string, in
+// order. Filters and dynamic generators are not variables and are left out.
 func ExtractVariables(s string) []string {
 	// First, find all potential variable patterns, including malformed ones
 	// This regex matches {{var} and {{var}} patterns
-	allMatches := regexp.MustCompile(`\{\{([^}]*)\}?`).FindAllStringSubmatch(s, -1)
+	allMatches := looseVarRegex.FindAllStringSubmatch(s, -1)
 	result := make([]string, 0, len(allMatches))
 	
 	for _, match := range allMatches {
 		if len(match) > 1 {
-			varName := strings.TrimSpace(match[1])
-			if varName != "" {
+			varName := placeholderSource(match[1])
+			if varName != "" && !IsDynamic(varName) {
 				result = append(result, varName)
 			}
 		}
@@ -81,16 +192,43 @@ func ExtractVariables(s string) []string {
 	return result
 }
 
-// ValidateVariables checks if all required variables are provided
+// placeholderSource returns the variable or generator a placeholder reads
+func placeholderSource(text string) string {
+	if expr, err := parseExpression(text); err == nil {
+		return expr.source
+	}
+	// Keep the part before the first filter of expressions that do not parse
+	source, _, _ := strings.Cut(text, "|")
+	return strings.TrimSpace(source)
+}
+
+// ValidateVariables checks if all required variables are provided, following the variables
+// referenced from the values of other variables. Placeholders with a default filter never
+// need their variable, and dynamic variables are generated rather than provided. Each
+// missing variable is listed once.
 func ValidateVariables(s string, vars map[string]string) []string {
-	required := ExtractVariables(s)
 	missing := make([]string, 0)
-	
-	for _, varName := range required {
-		if _, exists := vars[varName]; !exists {
-			missing = append(missing, varName)
+	seen := make(map[string]bool)
+	var visit func(text string)
+	visit = func(text string) {
+		for _, match := range looseVarRegex.FindAllStringSubmatch(text, -1) {
+			source := placeholderSource(match[1])
+			if source == "" || IsDynamic(source) || seen[source] {
+				continue
+			}
+			if value, exists := vars[source]; exists {
+				seen[source] = true
+				visit(value)
+				continue
+			}
+			if expr, err := parseExpression(match[1]); err == nil && expr.hasDefault() {
+				continue
+			}
+			seen[source] = true
+			missing = append(missing, source)
 		}
 	}
+	visit(s)
 	
 	return missing
 }
@@ -1,8 +1,14 @@
 package interpolate
 
 import (
+	"encoding/json"
+	"errors"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestString(t *testing.T) {
@@ -52,6 +58,48 @@ func TestString(t *testing.T) {
 			variables: map[string]string{"base_url": "https://api.example.com"},
 			expected:  "URL: https://api.example.com/api/v1",
 		},
+		{
+			name:      "default for a missing variable",
+			template:  `{{region | default "eu-west-1"}}/{{env | default "prod"}}`,
+			variables: map[string]string{"env": "dev"},
+			expected:  "eu-west-1/dev",
+		},
+		{
+			name:      "filter pipeline",
+			template:  `{{name | upper}} {{query | urlencode}} {{name | base64}} {{quote | jsonEscape}} {{missing | default "a b" | urlencode}}`,
+			variables: map[string]string{"name": "ada", "query": "a b&c", "quote": `say "hi"` + "\n"},
+			expected:  `ADA a+b%26c YWRh say \"hi\"\n a+b`,
+		},
+		{
+			name:      "sha256 filter",
+			template:  "{{secret | sha256}}",
+			variables: map[string]string{"secret": "abc"},
+			expected:  "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			name:      "nested references",
+			template:  "{{baseUrl}}/users",
+			variables: map[string]string{"baseUrl": "{{scheme}}://{{host}}", "scheme": "https", "host": "{{sub}}.example.com", "sub": "api"},
+			expected:  "https://api.example.com/users",
+		},
+		{
+			name:      "filters apply to nested values",
+			template:  "{{greeting | upper}}",
+			variables: map[string]string{"greeting": "hello {{name}}", "name": "ada"},
+			expected:  "HELLO ADA",
+		},
+		{
+			name:      "unresolved filtered variable",
+			template:  "{{name | upper}}",
+			variables: map[string]string{},
+			expected:  "{{name | upper}}",
+		},
+		{
+			name:      "unknown filter",
+			template:  "{{name | reverse}}",
+			variables: map[string]string{"name": "ada"},
+			expected:  "{{name | reverse}}",
+		},
 		{
 			name:      "nested braces in text",
 			template:  "Function: f(x) = {{formula}}",
@@ -262,6 +310,24 @@ func TestMergeVariables(t *testing.T) {
 	}
 }
 
+func TestMergeScopes(t *testing.T) {
+	vars, sources := MergeScopes(
+		Scope{Name: ScopeGlobal, Variables: map[string]string{"host": "global.example.com", "token": "g"}},
+		Scope{Name: ScopeCollection, Variables: map[string]string{"host": "orders.example.com", "path": "/orders"}},
+		Scope{Name: ScopeEnvironment, Variables: nil},
+		Scope{Name: ScopeOverride, Variables: map[string]string{"token": "t-1"}},
+	)
+
+	wantVars := map[string]string{"host": "orders.example.com", "path": "/orders", "token": "t-1"}
+	wantSources := map[string]string{"host": ScopeCollection, "path": ScopeCollection, "token": ScopeOverride}
+	if !reflect.DeepEqual(vars, wantVars) {
+		t.Errorf("expected variables %v, got %v", wantVars, vars)
+	}
+	if !reflect.DeepEqual(sources, wantSources) {
+		t.Errorf("expected sources %v, got %v", wantSources, sources)
+	}
+}
+
 func TestExtractVariables(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -298,6 +364,11 @@ func TestExtractVariables(t *testing.T) {
 			template: "Hello {{name} and {{name}} and {{",
 			expected: []string{"name", "name"},
 		},
+		{
+			name:     "filters and dynamic variables",
+			template: `{{ name | default "x" | upper }} {{$uuid}} {{token|sha256}}`,
+			expected: []string{"name", "token"},
+		},
 		{
 			name:     "variables with underscores",
 			template: "{{base_url}}/{{api_version}}",
@@ -346,6 +417,24 @@ func TestValidateVariables(t *testing.T) {
 			variables: map[string]string{"name": "World"},
 			expected:  []string{},
 		},
+		{
+			name:      "dynamic variables are never missing",
+			template:  "{{$uuid}} {{ $randomInt 1 9 }} {{id}}",
+			variables: map[string]string{},
+			expected:  []string{"id"},
+		},
+		{
+			name:      "nested references",
+			template:  "{{baseUrl}}/{{path}}",
+			variables: map[string]string{"baseUrl": "{{scheme}}://{{host}}", "scheme": "https"},
+			expected:  []string{"host", "path"},
+		},
+		{
+			name:      "defaults and cycles",
+			template:  `{{region | default "eu"}} {{a}} {{b}} {{region}}`,
+			variables: map[string]string{"a": "{{b}}", "b": "{{a}}"},
+			expected:  []string{"region"},
+		},
 		{
 			name:      "extra variables provided",
 			template:  "Hello {{name}}!",
@@ -363,3 +452,179 @@ func TestValidateVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestResolve(t *testing.T) {
+	vars := map[string]string{"a": "x{{b}}", "b": "{{c}}", "c": "{{a}}", "name": "ada"}
+
+	_, err := Resolve("{{name}} {{a}}", vars)
+	var cycle *CycleError
+	if !errors.As(err, &cycle) || strings.Join(cycle.Chain, ",") != "a,b,c,a" {
+		t.Errorf("expected the cycle a -> b -> c -> a, got %v", err)
+	}
+	if _, err := Resolve(`{{name | default "unterminated}}`, vars); err == nil {
+		t.Error("expected an error for an unterminated argument")
+	}
+	if _, err := Resolve("{{name | nope}}", vars); err == nil || !strings.Contains(err.Error(), "unknown filter") {
+		t.Errorf("expected an unknown filter error, got %v", err)
+	}
+	if got, err := Resolve("{{name}} {{missing}}", vars); err != nil || got != "ada {{missing}}" {
+		t.Errorf("expected undefined variables to be left without error, got %q (%v)", got, err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 120000000, time.FixedZone("CEST", 7200)) }
+	defer func() { now = time.Now }()
+
+	patterns := map[string]string{
+		"$uuid":                  `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		"$timestamp":             `^1714972089$`,
+		"$isoTimestamp":          `^2024-05-06T05:08:09\.120Z$`,
+		"$randomHex 7":           `^[0-9a-f]{7}$`,
+		"$randomHex":             `^[0-9a-f]{16}$`,
+		`$base64 "hi \"there\""`: `^aGkgInRoZXJlIg==$`,
+		" $randomInt 5 5 ":       `^5$`,
+	}
+
+	for expr, pattern := range patterns {
+		got, err := Generate(expr)
+		if err != nil {
+			t.Errorf("%s: Generate failed: %v", expr, err)
+			continue
+		}
+		if !regexp.MustCompile(pattern).MatchString(got) {
+			t.Errorf("%s: %q does not match %s", expr, got, pattern)
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		got, err := Generate("$randomInt -3 3")
+		n, _ := strconv.Atoi(got)
+		if err != nil || n < -3 || n > 3 {
+			t.Fatalf("$randomInt -3 3: got %q (%v)", got, err)
+		}
+	}
+
+	for _, expr := range []string{"$nope", "$uuid 1", "$randomInt 1", "$randomInt 9 1", "$randomInt a b", "$randomHex 0", "$base64", `$base64 "open`, "$"} {
+		if _, err := Generate(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestWithDynamic(t *testing.T) {
+	vars := map[string]string{"host": "api", "$timestamp": "pinned"}
+	body := map[string]interface{}{
+		"ids":  []interface{}{"{{$uuid}}", "{{ $uuid }}"},
+		"time": "{{$timestamp}}",
+	}
+	resolved, err := WithDynamic(vars, "https://{{host}}/{{$uuid}}", map[string]string{"X-Trace": "{{$randomHex 32}}"}, body)
+	if err != nil {
+		t.Fatalf("WithDynamic failed: %v", err)
+	}
+
+	url := String("https://{{host}}/{{$uuid}}", resolved)
+	ids := Deep(body, resolved).(map[string]interface{})["ids"].([]interface{})
+	id := strings.TrimPrefix(url, "https://api/")
+	if id == "" || ids[0] != id || ids[1] != id {
+		t.Errorf("expected one uuid everywhere, got %s and %v", url, ids)
+	}
+	if got := String("{{$timestamp}}", resolved); got != "pinned" {
+		t.Errorf("expected a provided value to win, got %s", got)
+	}
+	if len(resolved["$randomHex 32"]) != 32 {
+		t.Errorf("expected a header value to be generated, got %q", resolved["$randomHex 32"])
+	}
+	if _, exists := vars["$uuid"]; exists {
+		t.Error("expected the input variables to be left untouched")
+	}
+
+	again, _ := WithDynamic(vars, "{{$uuid}}")
+	if again["$uuid"] == id {
+		t.Error("expected a fresh value on every call")
+	}
+	vars = map[string]string{"traceId": "{{$randomHex 8}}"}
+	resolved, err = WithDynamic(vars, "{{traceId}} {{$uuid | upper}} {{$uuid}}")
+	if err != nil {
+		t.Fatalf("WithDynamic failed: %v", err)
+	}
+	parts := strings.Fields(String("{{traceId}} {{$uuid | upper}} {{$uuid}}", resolved))
+	if len(parts[0]) != 8 || parts[1] != strings.ToUpper(parts[2]) {
+		t.Errorf("expected referenced and filtered dynamic values to resolve consistently, got %v", parts)
+	}
+	if _, err := WithDynamic(nil, "{{$unknown}}"); err == nil {
+		t.Error("expected an error for an unknown dynamic variable")
+	}
+}
+
+func TestValue(t *testing.T) {
+	vars := map[string]string{
+		"count":   "42",
+		"big":     "9007199254740993",
+		"active":  "true",
+		"none":    "null",
+		"address": `{"city": "Oslo", "zip": 150}`,
+		"tags":    `["a", "b"]`,
+		"zip":     `"0150"`,
+		"name":    "Ada",
+		"nested":  "{{count}}",
+	}
+	tests := []struct {
+		template string
+		expected interface{}
+	}{
+		{"{{count}}", json.Number("42")},
+		{"{{big}}", json.Number("9007199254740993")},
+		{"{{active}}", true},
+		{"{{none}}", nil},
+		{"{{address}}", map[string]interface{}{"city": "Oslo", "zip": json.Number("150")}},
+		{"{{tags}}", []interface{}{"a", "b"}},
+		{"{{zip}}", "0150"},
+		{"{{name}}", "Ada"},
+		{"{{nested}}", json.Number("42")},
+		{`{{missing | default "7"}}`, json.Number("7")},
+		{"{{count}} items", "42 items"},
+		{" {{count}}", " 42"},
+		{"{{missing}}", "{{missing}}"},
+	}
+	for _, tt := range tests {
+		if got := Value(tt.template, vars); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Value(%q) = %#v, want %#v", tt.template, got, tt.expected)
+		}
+	}
+
+	deep := Deep(map[string]interface{}{"shipping": "{{address}}", "items": []interface{}{"{{count}}"}}, vars)
+	want := map[string]interface{}{
+		"shipping": map[string]interface{}{"city": "Oslo", "zip": json.Number("150")},
+		"items":    []interface{}{json.Number("42")},
+	}
+	if !reflect.DeepEqual(deep, want) {
+		t.Errorf("Deep() = %v, want %v", deep, want)
+	}
+	if headers := Deep(map[string]string{"X-Count": "{{count}}"}, vars); headers.(map[string]string)["X-Count"] != "42" {
+		t.Errorf("expected header values to stay strings, got %v", headers)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	vars := map[string]string{
+		"name":    `Ada "the first" \ Lovelace`,
+		"note":    "line\nbreak",
+		"count":   "42",
+		"address": `{"city": "Oslo"}`,
+	}
+	doc := `{"name": "{{name}}", "greeting": "hi {{name | upper}}", "note": "{{note}}", "count": {{count}}, "address": {{address}}, "quoted": "\"{{count}}\"", "missing": "{{missing}}", "literal": "}{{count}"}`
+
+	got := JSON(doc, vars)
+	want := `{"name": "Ada \"the first\" \\ Lovelace", "greeting": "hi ADA \"THE FIRST\" \\ LOVELACE", "note": "line\nbreak", "count": 42, "address": {"city": "Oslo"}, "quoted": "\"42\"", "missing": "{{missing}}", "literal": "}{{count}"}`
+	if got != want {
+		t.Errorf("JSON() =\n%s\nwant\n%s", got, want)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("interpolated document is not JSON: %v", err)
+	}
+	if decoded["name"] != vars["name"] || decoded["note"] != vars["note"] {
+		t.Errorf("expected values to decode unchanged, got %q and %q", decoded["name"], decoded["note"])
+	}
+}
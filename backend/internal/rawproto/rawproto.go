@@ -0,0 +1,245 @@
+// Package rawproto decodes protobuf wire data without a schema, in the spirit of
+// protoc --decode_raw. Length-delimited fields are guessed as strings, nested
+// messages, packed varints or plain bytes.
+package rawproto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxDepth bounds how deeply nested messages are guessed
+const maxDepth = 64
+
+// Wire types as rendered in Field.WireType
+const (
+	WireVarint  = "varint"
+	WireFixed64 = "fixed64"
+	WireBytes   = "bytes"
+	WireGroup   = "group"
+	WireFixed32 = "fixed32"
+)
+
+// Interpretations of a field's value as rendered in Field.Kind
+const (
+	KindVarint  = "varint"
+	KindFixed64 = "fixed64"
+	KindFixed32 = "fixed32"
+	KindString  = "string"
+	KindMessage = "message"
+	KindGroup   = "group"
+	KindPacked  = "packed"
+	KindBytes   = "bytes"
+)
+
+// Field is one decoded field of a message
+type Field struct {
+	Number       int32             `json:"number"`
+	WireType     string            `json:"wireType"`
+	Kind         string            `json:"kind"`
+	Value        string            `json:"value,omitempty"`        // Scalar value; bytes are base64-encoded
+	Alternatives map[string]string `json:"alternatives,omitempty"` // Other readings of the value, e.g. sint64 or double
+	Packed       []string          `json:"packed,omitempty"`       // Elements of a packed repeated varint field
+	Fields       []Field           `json:"fields,omitempty"`       // Children of a nested message or group
+}
+
+// Decode walks protobuf wire data into a tree of fields. It fails when data is not
+// well-formed wire format.
+func Decode(data []byte) ([]Field, error) {
+	fields, err := decodeMessage(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// decodeMessage decodes every field in data
+func decodeMessage(data []byte, depth int) ([]Field, error) {
+	fields := []Field{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid tag: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		field, n, err := decodeField(num, typ, data, depth)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", num, err)
+		}
+		data = data[n:]
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// decodeField decodes the value following a tag and returns the bytes consumed
+func decodeField(num protowire.Number, typ protowire.Type, data []byte, depth int) (Field, int, error) {
+	field := Field{Number: int32(num)}
+
+	switch typ {
+	case protowire.VarintType:
+		v, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return field, 0, protowire.ParseError(n)
+		}
+		field.WireType, field.Kind = WireVarint, KindVarint
+		field.Value = strconv.FormatUint(v, 10)
+		field.Alternatives = varintAlternatives(v)
+		return field, n, nil
+
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(data)
+		if n < 0 {
+			return field, 0, protowire.ParseError(n)
+		}
+		field.WireType, field.Kind = WireFixed64, KindFixed64
+		field.Value = fmt.Sprintf("0x%016x", v)
+		field.Alternatives = map[string]string{
+			"uint64": strconv.FormatUint(v, 10),
+			"int64":  strconv.FormatInt(int64(v), 10),
+			"double": strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64),
+		}
+		return field, n, nil
+
+	case protowire.Fixed32Type:
+		v, n := protowire.ConsumeFixed32(data)
+		if n < 0 {
+			return field, 0, protowire.ParseError(n)
+		}
+		field.WireType, field.Kind = WireFixed32, KindFixed32
+		field.Value = fmt.Sprintf("0x%08x", v)
+		field.Alternatives = map[string]string{
+			"uint32": strconv.FormatUint(uint64(v), 10),
+			"int32":  strconv.FormatInt(int64(int32(v)), 10),
+			"float":  strconv.FormatFloat(float64(math.Float32frombits(v)), 'g', -1, 32),
+		}
+		return field, n, nil
+
+	case protowire.BytesType:
+		v, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return field, 0, protowire.ParseError(n)
+		}
+		field.WireType = WireBytes
+		guessBytes(&field, v, depth)
+		return field, n, nil
+
+	case protowire.StartGroupType:
+		v, n := protowire.ConsumeGroup(num, data)
+		if n < 0 {
+			return field, 0, protowire.ParseError(n)
+		}
+		if depth >= maxDepth {
+			return field, 0, fmt.Errorf("groups nested deeper than %d", maxDepth)
+		}
+		children, err := decodeMessage(v, depth+1)
+		if err != nil {
+			return field, 0, err
+		}
+		field.WireType, field.Kind = WireGroup, KindGroup
+		field.Fields = children
+		return field, n, nil
+	}
+
+	return field, 0, fmt.Errorf("unsupported wire type %d", typ)
+}
+
+// guessBytes picks the most plausible reading of a length-delimited value: printable
+// text, then a nested message, then packed varints, falling back to raw bytes
+func guessBytes(field *Field, v []byte, depth int) {
+	if len(v) > 0 && isPrintable(v) {
+		field.Kind, field.Value = KindString, string(v)
+		return
+	}
+	if len(v) > 0 && depth < maxDepth {
+		if children, err := decodeMessage(v, depth+1); err == nil {
+			field.Kind, field.Fields = KindMessage, children
+			return
+		}
+		if packed, ok := decodePackedVarints(v); ok {
+			field.Kind, field.Packed = KindPacked, packed
+			return
+		}
+	}
+	field.Kind, field.Value = KindBytes, base64.StdEncoding.EncodeToString(v)
+}
+
+// decodePackedVarints reads v as a sequence of varints
+func decodePackedVarints(v []byte) ([]string, bool) {
+	var values []string
+	for len(v) > 0 {
+		x, n := protowire.ConsumeVarint(v)
+		if n < 0 {
+			return nil, false
+		}
+		values = append(values, strconv.FormatUint(x, 10))
+		v = v[n:]
+	}
+	return values, true
+}
+
+// varintAlternatives lists the signed readings of a varint when they differ from the unsigned value
+func varintAlternatives(v uint64) map[string]string {
+	alts := map[string]string{}
+	if int64(v) < 0 {
+		alts["int64"] = strconv.FormatInt(int64(v), 10)
+	}
+	if zz := protowire.DecodeZigZag(v); zz < 0 || uint64(zz) != v {
+		alts["sint64"] = strconv.FormatInt(zz, 10)
+	}
+	if v <= 1 {
+		alts["bool"] = strconv.FormatBool(v == 1)
+	}
+	if len(alts) == 0 {
+		return nil
+	}
+	return alts
+}
+
+// isPrintable reports whether v is valid UTF-8 made of printable characters and common whitespace
+func isPrintable(v []byte) bool {
+	if !utf8.Valid(v) {
+		return false
+	}
+	for _, r := range string(v) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// Format renders fields in the text layout of protoc --decode_raw
+func Format(fields []Field) string {
+	var b strings.Builder
+	writeFields(&b, fields, 0)
+	return b.String()
+}
+
+func writeFields(b *strings.Builder, fields []Field, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, f := range fields {
+		switch f.Kind {
+		case KindMessage, KindGroup:
+			fmt.Fprintf(b, "%s%d {\n", pad, f.Number)
+			writeFields(b, f.Fields, indent+1)
+			fmt.Fprintf(b, "%s}\n", pad)
+		case KindString:
+			fmt.Fprintf(b, "%s%d: %s\n", pad, f.Number, strconv.Quote(f.Value))
+		case KindPacked:
+			fmt.Fprintf(b, "%s%d: [%s]\n", pad, f.Number, strings.Join(f.Packed, ", "))
+		case KindBytes:
+			fmt.Fprintf(b, "%s%d: base64:%s\n", pad, f.Number, f.Value)
+		default:
+			fmt.Fprintf(b, "%s%d: %s\n", pad, f.Number, f.Value)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package rawproto
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecode_Guesses(t *testing.T) {
+	var nested []byte
+	nested = protowire.AppendTag(nested, 1, protowire.VarintType)
+	nested = protowire.AppendVarint(nested, 7)
+	nested = protowire.AppendTag(nested, 2, protowire.Fixed32Type)
+	nested = protowire.AppendFixed32(nested, 0x3fc00000) // 1.5f
+
+	var packed []byte
+	for _, v := range []uint64{1, 300, 2} {
+		packed = protowire.AppendVarint(packed, v)
+	}
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+	msg = protowire.AppendString(msg, "hello")
+	msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+	msg = protowire.AppendBytes(msg, nested)
+	msg = protowire.AppendTag(msg, 3, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, protowire.EncodeZigZag(-3))
+	msg = protowire.AppendTag(msg, 4, protowire.BytesType)
+	msg = protowire.AppendBytes(msg, packed)
+	msg = protowire.AppendTag(msg, 5, protowire.Fixed64Type)
+	msg = protowire.AppendFixed64(msg, 42)
+
+	fields, err := Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(fields) != 5 {
+		t.Fatalf("expected 5 fields, got %d: %+v", len(fields), fields)
+	}
+
+	if fields[0].Kind != KindString || fields[0].Value != "hello" {
+		t.Errorf("expected string field, got %+v", fields[0])
+	}
+	if fields[1].Kind != KindMessage || len(fields[1].Fields) != 2 || fields[1].Fields[0].Value != "7" {
+		t.Errorf("expected nested message, got %+v", fields[1])
+	}
+	if got := fields[1].Fields[1].Alternatives["float"]; got != "1.5" {
+		t.Errorf("expected fixed32 float reading 1.5, got %q", got)
+	}
+	if fields[2].Kind != KindVarint || fields[2].Alternatives["sint64"] != "-3" {
+		t.Errorf("expected zigzag reading, got %+v", fields[2])
+	}
+	if fields[3].Kind != KindPacked || strings.Join(fields[3].Packed, ",") != "1,300,2" {
+		t.Errorf("expected packed varints, got %+v", fields[3])
+	}
+	if fields[4].Kind != KindFixed64 || fields[4].Alternatives["uint64"] != "42" {
+		t.Errorf("expected fixed64 field, got %+v", fields[4])
+	}
+
+	text := Format(fields)
+	for _, want := range []string{`1: "hello"`, "2 {\n  1: 7\n", "4: [1, 300, 2]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected formatted output to contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestDecode_Group(t *testing.T) {
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.StartGroupType)
+	msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, 1)
+	msg = protowire.AppendTag(msg, 1, protowire.EndGroupType)
+
+	fields, err := Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(fields) != 1 || fields[0].Kind != KindGroup || len(fields[0].Fields) != 1 {
+		t.Fatalf("expected group with one child, got %+v", fields)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	// Length-delimited field claiming more bytes than are present
+	if _, err := Decode([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Fatal("expected error for truncated field")
+	}
+	// Field number zero is not valid
+	if _, err := Decode([]byte{0x00, 0x01}); err == nil {
+		t.Fatal("expected error for field number 0")
+	}
+}
@@ -0,0 +1,378 @@
+package registry
+
+import (
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Extension numbers of the validation rules on google.protobuf.FieldOptions. The rule
+// messages are read straight from the option bytes, so neither validate.proto needs to be
+// linked into the binary.
+const (
+	protovalidateFieldExt = 1159 // (buf.validate.field)
+	pgvRulesExt           = 1071 // (validate.rules), protoc-gen-validate
+)
+
+// Field numbers shared by buf.validate.FieldRules and validate.FieldRules
+const (
+	rulesFloat    = 1
+	rulesDouble   = 2
+	rulesInt32    = 3
+	rulesInt64    = 4
+	rulesUint32   = 5
+	rulesUint64   = 6
+	rulesSint32   = 7
+	rulesSint64   = 8
+	rulesFixed32  = 9
+	rulesFixed64  = 10
+	rulesSfixed32 = 11
+	rulesSfixed64 = 12
+	rulesBool     = 13
+	rulesString   = 14
+	rulesBytes    = 15
+	rulesEnum     = 16
+	rulesMessage  = 17 // validate.MessageRules (PGV only)
+	rulesRepeated = 18
+	rulesMap      = 19
+	rulesRequired = 25 // buf.validate.FieldRules.required
+)
+
+// stringFormats maps StringRules well-known format fields to ValidationConstraints.Format
+var stringFormats = map[protowire.Number]string{
+	12: "email",
+	13: "hostname",
+	14: "ip",
+	15: "ipv4",
+	16: "ipv6",
+	17: "uri",
+	18: "uri_ref",
+	22: "uuid",
+}
+
+// wireField is one decoded field of a rules message
+type wireField struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+// FieldConstraints reads buf.validate (protovalidate) or legacy validate.rules
+// (protoc-gen-validate) options of a field. Returns nil when the field has no rules.
+func FieldConstraints(fd protoreflect.FieldDescriptor) *ValidationConstraints {
+	opts := fd.Options()
+	if opts == nil {
+		return nil
+	}
+	raw, err := proto.Marshal(opts)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+
+	var c *ValidationConstraints
+	for _, f := range parseWire(raw) {
+		if f.typ != protowire.BytesType || (f.num != protovalidateFieldExt && f.num != pgvRulesExt) {
+			continue
+		}
+		if c == nil {
+			c = &ValidationConstraints{}
+		}
+		parseFieldRules(f.bytes, c)
+	}
+	if c == nil || isEmptyConstraints(c) {
+		return nil
+	}
+	return c
+}
+
+// parseFieldRules fills c from a FieldRules message
+func parseFieldRules(b []byte, c *ValidationConstraints) {
+	for _, f := range parseWire(b) {
+		switch f.num {
+		case rulesRequired:
+			if f.typ == protowire.VarintType && f.varint != 0 {
+				c.Required = boolPtr(true)
+			}
+		case rulesMessage:
+			for _, m := range parseWire(f.bytes) {
+				if m.num == 2 && m.varint != 0 { // MessageRules.required
+					c.Required = boolPtr(true)
+				}
+			}
+		case rulesString:
+			parseStringRules(f.bytes, c)
+		case rulesBytes:
+			parseBytesRules(f.bytes, c)
+		case rulesEnum:
+			parseEnumRules(f.bytes, c)
+		case rulesBool:
+			for _, r := range parseWire(f.bytes) {
+				if r.num == 1 { // const
+					c.In = []string{strconv.FormatBool(r.varint != 0)}
+				}
+			}
+		case rulesRepeated:
+			parseRepeatedRules(f.bytes, c)
+		case rulesMap:
+			for _, r := range parseWire(f.bytes) {
+				switch r.num {
+				case 1: // min_pairs
+					c.MinItems = int32Ptr(r.varint)
+				case 2: // max_pairs
+					c.MaxItems = int32Ptr(r.varint)
+				}
+			}
+		case rulesFloat, rulesDouble, rulesInt32, rulesInt64, rulesUint32, rulesUint64,
+			rulesSint32, rulesSint64, rulesFixed32, rulesFixed64, rulesSfixed32, rulesSfixed64:
+			parseNumericRules(f.num, f.bytes, c)
+		}
+	}
+}
+
+// parseStringRules reads StringRules
+func parseStringRules(b []byte, c *ValidationConstraints) {
+	for _, r := range parseWire(b) {
+		switch r.num {
+		case 1: // const
+			c.In = []string{string(r.bytes)}
+		case 19: // len
+			c.MinLen, c.MaxLen = int32Ptr(r.varint), int32Ptr(r.varint)
+		case 2: // min_len
+			c.MinLen = int32Ptr(r.varint)
+		case 3: // max_len
+			c.MaxLen = int32Ptr(r.varint)
+		case 6: // pattern
+			c.Pattern = stringPtr(string(r.bytes))
+		case 7: // prefix
+			c.Prefix = stringPtr(string(r.bytes))
+		case 8: // suffix
+			c.Suffix = stringPtr(string(r.bytes))
+		case 9: // contains
+			c.Contains = stringPtr(string(r.bytes))
+		case 10: // in
+			c.In = append(c.In, string(r.bytes))
+		case 11: // not_in
+			c.NotIn = append(c.NotIn, string(r.bytes))
+		default:
+			if format, ok := stringFormats[r.num]; ok && r.varint != 0 {
+				c.Format = stringPtr(format)
+			}
+		}
+	}
+}
+
+// parseBytesRules reads the length and pattern rules of BytesRules
+func parseBytesRules(b []byte, c *ValidationConstraints) {
+	for _, r := range parseWire(b) {
+		switch r.num {
+		case 13: // len
+			c.MinLen, c.MaxLen = int32Ptr(r.varint), int32Ptr(r.varint)
+		case 2: // min_len
+			c.MinLen = int32Ptr(r.varint)
+		case 3: // max_len
+			c.MaxLen = int32Ptr(r.varint)
+		case 4: // pattern
+			c.Pattern = stringPtr(string(r.bytes))
+		}
+	}
+}
+
+// parseEnumRules reads EnumRules; values are rendered as enum numbers
+func parseEnumRules(b []byte, c *ValidationConstraints) {
+	for _, r := range parseWire(b) {
+		switch r.num {
+		case 1: // const
+			c.In = []string{strconv.FormatInt(int64(int32(r.varint)), 10)}
+		case 2: // defined_only
+			c.DefinedOnly = r.varint != 0
+		case 3: // in
+			c.In = append(c.In, packedValues(r, rulesInt32)...)
+		case 4: // not_in
+			c.NotIn = append(c.NotIn, packedValues(r, rulesInt32)...)
+		}
+	}
+}
+
+// parseRepeatedRules reads RepeatedRules, including the rules applied to each item
+func parseRepeatedRules(b []byte, c *ValidationConstraints) {
+	for _, r := range parseWire(b) {
+		switch r.num {
+		case 1: // min_items
+			c.MinItems = int32Ptr(r.varint)
+		case 2: // max_items
+			c.MaxItems = int32Ptr(r.varint)
+		case 3: // unique
+			c.Unique = r.varint != 0
+		case 4: // items
+			items := &ValidationConstraints{}
+			parseFieldRules(r.bytes, items)
+			if !isEmptyConstraints(items) {
+				c.Items = items
+			}
+		}
+	}
+}
+
+// parseNumericRules reads the const, lt, lte, gt, gte, in and not_in rules shared by all
+// numeric rule messages; kind is the FieldRules field number, which selects the encoding.
+// Bounds of 64-bit integer rules are also kept as written in ExactMin and ExactMax.
+func parseNumericRules(kind protowire.Number, b []byte, c *ValidationConstraints) {
+	exact := func(s string) string {
+		if is64BitIntRules(kind) {
+			return s
+		}
+		return ""
+	}
+	for _, r := range parseWire(b) {
+		values := packedValues(r, kind)
+		if len(values) == 0 {
+			continue
+		}
+		switch r.num {
+		case 1: // const
+			c.In = values[:1]
+		case 2: // lt
+			c.Max, c.ExactMax, c.ExclusiveMax = parseFloatPtr(values[0]), exact(values[0]), true
+		case 3: // lte
+			c.Max, c.ExactMax, c.ExclusiveMax = parseFloatPtr(values[0]), exact(values[0]), false
+		case 4: // gt
+			c.Min, c.ExactMin, c.ExclusiveMin = parseFloatPtr(values[0]), exact(values[0]), true
+		case 5: // gte
+			c.Min, c.ExactMin, c.ExclusiveMin = parseFloatPtr(values[0]), exact(values[0]), false
+		case 6: // in
+			c.In = append(c.In, values...)
+		case 7: // not_in
+			c.NotIn = append(c.NotIn, values...)
+		}
+	}
+}
+
+// packedValues renders the numeric value(s) of a rule field, which may be packed
+func packedValues(r wireField, kind protowire.Number) []string {
+	if r.typ != protowire.BytesType {
+		return []string{formatNumeric(kind, r.varint)}
+	}
+	var out []string
+	b := r.bytes
+	for len(b) > 0 {
+		var v uint64
+		var n int
+		switch fixedWidth(kind) {
+		case 4:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case 8:
+			v, n = protowire.ConsumeFixed64(b)
+		default:
+			v, n = protowire.ConsumeVarint(b)
+		}
+		if n < 0 {
+			return out
+		}
+		out = append(out, formatNumeric(kind, v))
+		b = b[n:]
+	}
+	return out
+}
+
+// is64BitIntRules reports whether kind is the rule message of a 64-bit integer type
+func is64BitIntRules(kind protowire.Number) bool {
+	switch kind {
+	case rulesInt64, rulesUint64, rulesSint64, rulesFixed64, rulesSfixed64:
+		return true
+	}
+	return false
+}
+
+// fixedWidth returns the wire width of fixed-size numeric kinds, or 0 for varints
+func fixedWidth(kind protowire.Number) int {
+	switch kind {
+	case rulesFloat, rulesFixed32, rulesSfixed32:
+		return 4
+	case rulesDouble, rulesFixed64, rulesSfixed64:
+		return 8
+	}
+	return 0
+}
+
+// formatNumeric decodes a raw wire value according to the rule kind
+func formatNumeric(kind protowire.Number, v uint64) string {
+	switch kind {
+	case rulesFloat:
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(v))), 'g', -1, 32)
+	case rulesDouble:
+		return strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64)
+	case rulesInt32, rulesSfixed32:
+		return strconv.FormatInt(int64(int32(v)), 10)
+	case rulesInt64, rulesSfixed64:
+		return strconv.FormatInt(int64(v), 10)
+	case rulesSint32, rulesSint64:
+		return strconv.FormatInt(protowire.DecodeZigZag(v), 10)
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// parseWire decodes the top-level fields of a message, stopping at malformed data
+func parseWire(b []byte) []wireField {
+	var out []wireField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return out
+		}
+		b = b[n:]
+		f := wireField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.varint = uint64(v)
+		case protowire.Fixed64Type:
+			f.varint, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return out
+		}
+		b = b[n:]
+		out = append(out, f)
+	}
+	return out
+}
+
+func isEmptyConstraints(c *ValidationConstraints) bool {
+	return c.MinLen == nil && c.MaxLen == nil && c.Min == nil && c.Max == nil &&
+		c.Pattern == nil && c.Prefix == nil && c.Suffix == nil && c.Contains == nil &&
+		c.Format == nil && c.Required == nil && len(c.In) == 0 && len(c.NotIn) == 0 &&
+		!c.DefinedOnly && c.MinItems == nil && c.MaxItems == nil && !c.Unique && c.Items == nil
+}
+
+func parseFloatPtr(s string) *float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func int32Ptr(v uint64) *int32 {
+	if v > math.MaxInt32 {
+		v = math.MaxInt32
+	}
+	n := int32(v)
+	return &n
+}
+
+func stringPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protovalidateProto is a trimmed copy of buf/validate/validate.proto that keeps the
+// upstream field numbers
+const protovalidateProto = `syntax = "proto2";
+
+package buf.validate;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+  optional FieldRules field = 1159;
+}
+
+message FieldRules {
+  optional bool required = 25;
+  oneof type {
+    DoubleRules double = 2;
+    Int32Rules int32 = 3;
+    UInt64Rules uint64 = 6;
+    SInt64Rules sint64 = 8;
+    StringRules string = 14;
+    EnumRules enum = 16;
+    RepeatedRules repeated = 18;
+    MapRules map = 19;
+  }
+}
+
+message DoubleRules {
+  optional double lt = 2;
+  optional double gte = 5;
+}
+
+message Int32Rules {
+  optional int32 gt = 4;
+  optional int32 lte = 3;
+  repeated int32 not_in = 7;
+}
+
+message UInt64Rules {
+  optional uint64 gt = 4;
+}
+
+message SInt64Rules {
+  optional sint64 gte = 5;
+}
+
+message StringRules {
+  optional uint64 min_len = 2;
+  optional uint64 max_len = 3;
+  optional string pattern = 6;
+  repeated string in = 10;
+  optional bool email = 12;
+  optional bool uuid = 22;
+}
+
+message EnumRules {
+  optional bool defined_only = 2;
+  repeated int32 in = 3;
+}
+
+message RepeatedRules {
+  optional uint64 min_items = 1;
+  optional uint64 max_items = 2;
+  optional bool unique = 3;
+  optional FieldRules items = 4;
+}
+
+message MapRules {
+  optional uint64 max_pairs = 2;
+}
+`
+
+// pgvProto is a trimmed copy of protoc-gen-validate's validate/validate.proto
+const pgvProto = `syntax = "proto2";
+
+package validate;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+  optional FieldRules rules = 1071;
+}
+
+message FieldRules {
+  optional MessageRules message = 17;
+  oneof type {
+    UInt32Rules uint32 = 5;
+    StringRules string = 14;
+  }
+}
+
+message MessageRules {
+  optional bool required = 2;
+}
+
+message UInt32Rules {
+  optional uint32 gte = 5;
+  optional uint32 lt = 2;
+}
+
+message StringRules {
+  optional uint64 len = 19;
+  optional string prefix = 7;
+}
+`
+
+const signupProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "buf_validate.proto";
+import "pgv_validate.proto";
+
+enum Plan {
+  PLAN_UNSPECIFIED = 0;
+  PLAN_FREE = 1;
+  PLAN_PRO = 2;
+}
+
+message Profile {
+  string bio = 1;
+}
+
+message Signup {
+  string email = 1 [(buf.validate.field).string.email = true];
+  string handle = 2 [(buf.validate.field).string = {min_len: 3, max_len: 15, pattern: "^[a-z]+$"}];
+  int32 age = 3 [(buf.validate.field).int32 = {gt: 12, lte: 130, not_in: [42]}];
+  double score = 4 [(buf.validate.field).double = {gte: 0, lt: 1}];
+  Plan plan = 5 [(buf.validate.field).enum = {defined_only: true, in: [1, 2]}];
+  repeated string tags = 6 [(buf.validate.field).repeated = {min_items: 1, max_items: 3, unique: true, items: {string: {min_len: 2}}}];
+  map<string, string> labels = 7 [(buf.validate.field).map.max_pairs = 2];
+  Profile profile = 8 [(buf.validate.field).required = true];
+  sint64 offset = 9 [(buf.validate.field).sint64.gte = -5];
+  string region = 10 [(buf.validate.field).string = {in: ["eu", "us"]}];
+  string id = 11 [(buf.validate.field).string.uuid = true];
+  uint32 seats = 12 [(validate.rules).uint32 = {gte: 1, lt: 100}];
+  string code = 13 [(validate.rules).string = {len: 4, prefix: "AC"}];
+  Profile legacy = 14 [(validate.rules).message.required = true];
+  string note = 15;
+  uint64 quota = 16 [(buf.validate.field).uint64.gt = 18446744073709551614];
+}
+`
+
+func setupSignupRegistry(t *testing.T) *Service {
+	t.Helper()
+	reg := NewService()
+	err := reg.RegisterFromVirtualFS(map[string][]byte{
+		// Flat filenames; both upstream files are named validate.proto
+		"buf_validate.proto": []byte(protovalidateProto),
+		"pgv_validate.proto": []byte(pgvProto),
+		"signup.proto":       []byte(signupProto),
+	})
+	if err != nil {
+		t.Fatalf("failed to register protos: %v", err)
+	}
+	return reg
+}
+
+func TestFieldConstraints(t *testing.T) {
+	reg := setupSignupRegistry(t)
+	md, err := reg.GetMessageDescriptor("acme.v1.Signup")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor failed: %v", err)
+	}
+	constraints := func(name string) *ValidationConstraints {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			t.Fatalf("field %s not found", name)
+		}
+		return FieldConstraints(fd)
+	}
+	f := func(v float64) *float64 { return &v }
+	i := func(v int32) *int32 { return &v }
+	s := func(v string) *string { return &v }
+	yes := func() *bool { b := true; return &b }
+
+	tests := []struct {
+		field string
+		want  *ValidationConstraints
+	}{
+		{"email", &ValidationConstraints{Format: s("email")}},
+		{"handle", &ValidationConstraints{MinLen: i(3), MaxLen: i(15), Pattern: s("^[a-z]+$")}},
+		{"age", &ValidationConstraints{Min: f(12), ExclusiveMin: true, Max: f(130), NotIn: []string{"42"}}},
+		{"score", &ValidationConstraints{Min: f(0), Max: f(1), ExclusiveMax: true}},
+		{"plan", &ValidationConstraints{DefinedOnly: true, In: []string{"1", "2"}}},
+		{"tags", &ValidationConstraints{MinItems: i(1), MaxItems: i(3), Unique: true, Items: &ValidationConstraints{MinLen: i(2)}}},
+		{"labels", &ValidationConstraints{MaxItems: i(2)}},
+		{"profile", &ValidationConstraints{Required: yes()}},
+		{"offset", &ValidationConstraints{Min: f(-5), ExactMin: "-5"}},
+		{"region", &ValidationConstraints{In: []string{"eu", "us"}}},
+		{"id", &ValidationConstraints{Format: s("uuid")}},
+		{"seats", &ValidationConstraints{Min: f(1), Max: f(100), ExclusiveMax: true}},
+		{"code", &ValidationConstraints{MinLen: i(4), MaxLen: i(4), Prefix: s("AC")}},
+		{"legacy", &ValidationConstraints{Required: yes()}},
+		{"note", nil},
+		{"quota", &ValidationConstraints{Min: f(18446744073709551614), ExclusiveMin: true, ExactMin: "18446744073709551614"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if got := constraints(tt.field); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMessageSchema_IncludesConstraints(t *testing.T) {
+	reg := setupSignupRegistry(t)
+	schema, err := NewSchemaService(reg).GetMessageSchema("acme.v1.Signup")
+	if err != nil {
+		t.Fatalf("GetMessageSchema failed: %v", err)
+	}
+	for _, field := range schema.Fields {
+		if field.Name == "handle" {
+			if field.Constraints == nil || field.Constraints.MinLen == nil || *field.Constraints.MinLen != 3 {
+				t.Fatalf("expected handle constraints in schema, got %+v", field.Constraints)
+			}
+			return
+		}
+	}
+	t.Fatal("handle field not found in schema")
+}
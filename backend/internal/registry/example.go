@@ -0,0 +1,481 @@
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/datahopper/backend/internal/dotpath"
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ExampleMode selects how much of a message GenerateExample fills in
+type ExampleMode string
+
+const (
+	ExampleMinimal ExampleMode = "minimal" // Required and constrained fields, first field of each oneof
+	ExampleFull    ExampleMode = "full"    // Every field; repeated and map fields get one entry
+)
+
+// maxExampleDepth bounds message nesting in generated examples
+const maxExampleDepth = 6
+
+// stringFormatExamples are sample values for the well-known string formats
+var stringFormatExamples = map[string]string{
+	"email":    "jane.doe@example.com",
+	"hostname": "api.example.com",
+	"ip":       "192.0.2.10",
+	"ipv4":     "192.0.2.10",
+	"ipv6":     "2001:db8::10",
+	"uri":      "https://example.com/resource",
+	"uri_ref":  "/resource",
+	"uuid":     "3f0c1a52-6a2b-4c8e-9d41-2b7f8e5a1c90",
+}
+
+// exampleGenerator carries the mode and the messages on the current path, so recursive
+// types stop at the first repetition
+type exampleGenerator struct {
+	mode   ExampleMode
+	active map[protoreflect.FullName]bool
+	out    []types.BodyField
+}
+
+// GenerateExample builds dot-path body fields for a message with realistic values that
+// respect enums, well-known types and validation constraints
+func (s *SchemaService) GenerateExample(fqmn string, mode ExampleMode) ([]types.BodyField, error) {
+	switch mode {
+	case "":
+		mode = ExampleMinimal
+	case ExampleMinimal, ExampleFull:
+	default:
+		return nil, fmt.Errorf("unknown example mode %q (want %s or %s)", mode, ExampleMinimal, ExampleFull)
+	}
+
+	msgDesc, err := s.registry.GetMessageDescriptor(fqmn)
+	if err != nil {
+		return nil, fmt.Errorf("message not found: %s", fqmn)
+	}
+
+	g := &exampleGenerator{mode: mode, active: make(map[protoreflect.FullName]bool), out: []types.BodyField{}}
+	g.message(msgDesc, "", 0)
+	return g.out, nil
+}
+
+// message appends fields of md under prefix, reporting whether any were added
+func (g *exampleGenerator) message(md protoreflect.MessageDescriptor, prefix string, depth int) bool {
+	if depth > maxExampleDepth || g.active[md.FullName()] {
+		return false
+	}
+	g.active[md.FullName()] = true
+	defer delete(g.active, md.FullName())
+
+	before := len(g.out)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !g.include(fd) {
+			continue
+		}
+		g.field(fd, dotpath.JoinKey(prefix, fd.JSONName()), depth)
+	}
+	return len(g.out) > before
+}
+
+// include decides whether a field is part of the example. Only the first field of each
+// real oneof is used, since setting two would conflict. Minimal examples also keep
+// implicit-presence scalars with rules, as their zero value is validated too.
+func (g *exampleGenerator) include(fd protoreflect.FieldDescriptor) bool {
+	if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		return oneof.Fields().Get(0) == fd
+	}
+	if g.mode == ExampleFull || fd.Cardinality() == protoreflect.Required {
+		return true
+	}
+	c := FieldConstraints(fd)
+	switch {
+	case c == nil:
+		return false
+	case c.Required != nil && *c.Required:
+		return true
+	case fd.IsList() || fd.IsMap():
+		return c.MinItems != nil && *c.MinItems > 0
+	}
+	return !fd.HasPresence()
+}
+
+// field appends the example value(s) of one field
+func (g *exampleGenerator) field(fd protoreflect.FieldDescriptor, path string, depth int) {
+	c := FieldConstraints(fd)
+
+	switch {
+	case fd.IsMap():
+		key := exampleMapKey(fd.MapKey())
+		g.value(fd.MapValue(), dotpath.JoinKey(path, key), nil, 0, depth)
+	case fd.IsList():
+		count := 1
+		if c != nil && c.MinItems != nil && int(*c.MinItems) > count {
+			count = int(*c.MinItems)
+		}
+		var items *ValidationConstraints
+		if c != nil {
+			items = c.Items
+		}
+		for i := 0; i < count; i++ {
+			g.value(fd, dotpath.JoinIndex(path, i), items, i, depth)
+		}
+	default:
+		g.value(fd, path, c, 0, depth)
+	}
+}
+
+// value appends the example for a single (non-repeated) value of fd at path. variant
+// distinguishes items of the same list so unique rules hold.
+func (g *exampleGenerator) value(fd protoreflect.FieldDescriptor, path string, c *ValidationConstraints, variant, depth int) {
+	if md := fd.Message(); md != nil {
+		if v, ok := wellKnownExample(md); ok {
+			g.out = append(g.out, types.BodyField{Path: path, Value: v})
+			return
+		}
+		if md.FullName() == anyTypeName {
+			g.out = append(g.out, types.BodyField{Path: path + ".@type", Value: "type.googleapis.com/google.protobuf.Empty"})
+			return
+		}
+		if !g.message(md, path, depth+1) {
+			// Nothing to fill in (minimal mode, empty or recursive message); send it empty
+			g.out = append(g.out, types.BodyField{Path: path, Value: map[string]interface{}{}})
+		}
+		return
+	}
+	g.out = append(g.out, types.BodyField{Path: path, Value: exampleScalar(fd, c, variant)})
+}
+
+// exampleScalar returns a value for a scalar or enum field honouring its constraints
+func exampleScalar(fd protoreflect.FieldDescriptor, c *ValidationConstraints, variant int) interface{} {
+	if c == nil {
+		c = &ValidationConstraints{}
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if len(c.In) > 0 {
+			return c.In[0] == "true"
+		}
+		return true
+	case protoreflect.StringKind:
+		return exampleString(fd, c, variant)
+	case protoreflect.BytesKind:
+		b := []byte("example")
+		if variant > 0 {
+			b = append(b, byte('0'+variant%10))
+		}
+		if c.MinLen != nil {
+			for len(b) < int(*c.MinLen) {
+				b = append(b, '.')
+			}
+		}
+		if c.MaxLen != nil && len(b) > int(*c.MaxLen) {
+			b = b[:*c.MaxLen]
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	case protoreflect.EnumKind:
+		return exampleEnum(fd.Enum(), c)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return exampleFloat(c, variant)
+	// 64-bit integers are strings in protojson
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(exampleInt(fd.Kind(), c, variant), 10)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(exampleUint(c, variant), 10)
+	default:
+		return exampleInt(fd.Kind(), c, variant)
+	}
+}
+
+// exampleString picks a string from the In list, the format rule or the field name, then
+// applies prefix, suffix, contains and length rules
+func exampleString(fd protoreflect.FieldDescriptor, c *ValidationConstraints, variant int) string {
+	if len(c.In) > 0 {
+		return c.In[variant%len(c.In)]
+	}
+
+	s := ""
+	if c.Format != nil {
+		s = stringFormatExamples[*c.Format]
+	}
+	if s == "" {
+		s = stringForName(string(fd.Name()))
+	}
+	if variant > 0 {
+		s = fmt.Sprintf("%s-%d", s, variant+1)
+	}
+	if c.Contains != nil && !strings.Contains(s, *c.Contains) {
+		s += *c.Contains
+	}
+	if c.Prefix != nil && !strings.HasPrefix(s, *c.Prefix) {
+		s = *c.Prefix + s
+	}
+	if c.Suffix != nil && !strings.HasSuffix(s, *c.Suffix) {
+		s += *c.Suffix
+	}
+
+	if c.MaxLen != nil && len([]rune(s)) > int(*c.MaxLen) {
+		s = truncateExample(s, c)
+	}
+	if n := len([]rune(s)); c.MinLen != nil && n < int(*c.MinLen) {
+		// Pad before any required suffix
+		padding := strings.Repeat("x", int(*c.MinLen)-n)
+		if c.Suffix != nil {
+			return strings.TrimSuffix(s, *c.Suffix) + padding + *c.Suffix
+		}
+		s += padding
+	}
+	return s
+}
+
+// truncateExample shortens s to MaxLen while keeping a required prefix and suffix
+func truncateExample(s string, c *ValidationConstraints) string {
+	max := int(*c.MaxLen)
+	prefix, suffix := "", ""
+	if c.Prefix != nil {
+		prefix = *c.Prefix
+	}
+	if c.Suffix != nil {
+		suffix = *c.Suffix
+	}
+	body := []rune(strings.TrimSuffix(strings.TrimPrefix(s, prefix), suffix))
+	keep := max - len([]rune(prefix)) - len([]rune(suffix))
+	if keep < 0 {
+		keep = 0
+	}
+	if len(body) > keep {
+		body = body[:keep]
+	}
+	return prefix + string(body) + suffix
+}
+
+// stringForName guesses a realistic value from common field names
+func stringForName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "email"):
+		return "jane.doe@example.com"
+	case strings.Contains(lower, "url") || strings.Contains(lower, "uri") || strings.Contains(lower, "link"):
+		return "https://example.com"
+	case strings.Contains(lower, "phone"):
+		return "+15555550100"
+	case lower == "id" || strings.HasSuffix(lower, "_id"):
+		return stringFormatExamples["uuid"]
+	case strings.Contains(lower, "first_name") || strings.Contains(lower, "firstname"):
+		return "Jane"
+	case strings.Contains(lower, "last_name") || strings.Contains(lower, "lastname"):
+		return "Doe"
+	case strings.Contains(lower, "name"):
+		return "Jane Doe"
+	case strings.Contains(lower, "country"):
+		return "US"
+	case strings.Contains(lower, "currency"):
+		return "USD"
+	case strings.Contains(lower, "city"):
+		return "Springfield"
+	case strings.Contains(lower, "date"):
+		return "2024-01-02"
+	}
+	return "example"
+}
+
+// exampleEnum returns the name of the first allowed value, skipping the zero value when
+// another one exists since it usually means unspecified
+func exampleEnum(ed protoreflect.EnumDescriptor, c *ValidationConstraints) interface{} {
+	values := ed.Values()
+	if values.Len() == 0 {
+		return 0
+	}
+	allowed := func(v protoreflect.EnumValueDescriptor) bool {
+		n := strconv.Itoa(int(v.Number()))
+		return (len(c.In) == 0 || containsExample(c.In, n)) && !containsExample(c.NotIn, n)
+	}
+	var fallback protoreflect.EnumValueDescriptor
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		if !allowed(v) {
+			continue
+		}
+		if v.Number() != 0 {
+			return string(v.Name())
+		}
+		fallback = v
+	}
+	if fallback != nil {
+		return string(fallback.Name())
+	}
+	if len(c.In) > 0 {
+		// Only undeclared numbers are allowed
+		if n, err := strconv.Atoi(c.In[0]); err == nil {
+			return n
+		}
+	}
+	return string(values.Get(0).Name())
+}
+
+// exampleInt returns an integer within Min/Max that is allowed by In/NotIn
+func exampleInt(kind protoreflect.Kind, c *ValidationConstraints, variant int) int64 {
+	if len(c.In) > 0 {
+		if n, err := strconv.ParseInt(c.In[variant%len(c.In)], 10, 64); err == nil {
+			return n
+		}
+	}
+
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	if isUnsignedKind(kind) {
+		lo = 0
+	}
+	if c.Min != nil {
+		lo = int64(math.Ceil(*c.Min))
+		if c.ExclusiveMin && float64(lo) == *c.Min {
+			lo++
+		}
+	}
+	if c.Max != nil {
+		hi = int64(math.Floor(*c.Max))
+		if c.ExclusiveMax && float64(hi) == *c.Max {
+			hi--
+		}
+	}
+
+	v := int64(1)
+	if v < lo {
+		v = lo
+	}
+	v += int64(variant)
+	if v > hi {
+		v = hi
+	}
+	for i := 0; i < 100 && containsExample(c.NotIn, strconv.FormatInt(v, 10)) && v < hi; i++ {
+		v++
+	}
+	return v
+}
+
+// exampleUint is exampleInt for uint64 and fixed64 fields, whose values go past int64.
+// Their bounds are read from ExactMin and ExactMax.
+func exampleUint(c *ValidationConstraints, variant int) uint64 {
+	if len(c.In) > 0 {
+		if n, err := strconv.ParseUint(c.In[variant%len(c.In)], 10, 64); err == nil {
+			return n
+		}
+	}
+
+	lo, hi := uint64(0), uint64(math.MaxUint64)
+	if n, err := strconv.ParseUint(c.ExactMin, 10, 64); err == nil {
+		lo = n
+		if c.ExclusiveMin && lo < hi {
+			lo++
+		}
+	}
+	if n, err := strconv.ParseUint(c.ExactMax, 10, 64); err == nil {
+		hi = n
+		if c.ExclusiveMax && hi > 0 {
+			hi--
+		}
+	}
+
+	v := uint64(1)
+	if v < lo {
+		v = lo
+	}
+	if uint64(variant) > hi-v {
+		v = hi
+	} else {
+		v += uint64(variant)
+	}
+	for i := 0; i < 100 && containsExample(c.NotIn, strconv.FormatUint(v, 10)) && v < hi; i++ {
+		v++
+	}
+	return v
+}
+
+// exampleFloat returns a float within Min/Max that is allowed by In/NotIn
+func exampleFloat(c *ValidationConstraints, variant int) float64 {
+	if len(c.In) > 0 {
+		if f, err := strconv.ParseFloat(c.In[variant%len(c.In)], 64); err == nil {
+			return f
+		}
+	}
+
+	v := 1.5 + float64(variant)
+	switch {
+	case c.Min != nil && c.Max != nil:
+		v = *c.Min + (*c.Max-*c.Min)/2
+	case c.Min != nil && (v < *c.Min || c.ExclusiveMin && v == *c.Min):
+		v = *c.Min + 1
+	case c.Max != nil && (v > *c.Max || c.ExclusiveMax && v == *c.Max):
+		v = *c.Max - 1
+	}
+	for i := 0; i < 100 && containsExample(c.NotIn, strconv.FormatFloat(v, 'g', -1, 64)); i++ {
+		v += 0.25
+	}
+	return v
+}
+
+// exampleMapKey returns a map key of the key field's kind, in dot-path form
+func exampleMapKey(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return "key"
+	case protoreflect.BoolKind:
+		return "true"
+	}
+	return "1"
+}
+
+// wellKnownExample returns the dot-path input form of a well-known type
+func wellKnownExample(md protoreflect.MessageDescriptor) (interface{}, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return "2024-01-02T15:04:05Z", true
+	case "google.protobuf.Duration":
+		return "1.5s", true
+	case "google.protobuf.FieldMask":
+		return "name", true
+	case "google.protobuf.Struct":
+		return map[string]interface{}{"key": "value"}, true
+	case "google.protobuf.Value":
+		return "value", true
+	case "google.protobuf.ListValue":
+		return []interface{}{"value"}, true
+	case "google.protobuf.Empty":
+		return map[string]interface{}{}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return 1, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return "1", true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return 1.5, true
+	case "google.protobuf.BoolValue":
+		return true, true
+	case "google.protobuf.StringValue":
+		return "example", true
+	case "google.protobuf.BytesValue":
+		return base64.StdEncoding.EncodeToString([]byte("example")), true
+	}
+	return nil, false
+}
+
+func isUnsignedKind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return true
+	}
+	return false
+}
+
+func containsExample(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+)
+
+const catalogProto = `syntax = "proto3";
+
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 1;
+}
+
+message Category {
+  string name = 1;
+  Category parent = 2;
+}
+
+message Product {
+  string product_id = 1;
+  Status status = 2;
+  int64 stock = 3;
+  repeated string tags = 4;
+  map<string, Category> categories = 5;
+  google.protobuf.Timestamp created_at = 6;
+  google.protobuf.Int32Value rating = 7;
+  oneof price {
+    double amount = 8;
+    string quote = 9;
+  }
+  optional bool featured = 10;
+}
+`
+
+func setupCatalogRegistry(t *testing.T) *Service {
+	t.Helper()
+	reg := NewService()
+	if err := reg.RegisterFromVirtualFS(map[string][]byte{"catalog.proto": []byte(catalogProto)}); err != nil {
+		t.Fatalf("failed to register protos: %v", err)
+	}
+	return reg
+}
+
+func bodyValues(fields []types.BodyField) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		values[f.Path] = f.Value
+	}
+	return values
+}
+
+func TestGenerateExample_Full(t *testing.T) {
+	schema := NewSchemaService(setupCatalogRegistry(t))
+
+	body, err := schema.GenerateExample("shop.v1.Product", ExampleFull)
+	if err != nil {
+		t.Fatalf("GenerateExample failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"productId":             "3f0c1a52-6a2b-4c8e-9d41-2b7f8e5a1c90",
+		"status":                "STATUS_ACTIVE",
+		"stock":                 "1",
+		"tags[0]":               "example",
+		"categories.key.name":   "Jane Doe",
+		"categories.key.parent": map[string]interface{}{}, // recursion stops at the repeated type
+		"createdAt":             "2024-01-02T15:04:05Z",
+		"rating":                1,
+		"amount":                1.5,
+		"featured":              true,
+	}
+	if got := bodyValues(body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGenerateExample_Minimal(t *testing.T) {
+	schema := NewSchemaService(setupCatalogRegistry(t))
+
+	body, err := schema.GenerateExample("shop.v1.Product", ExampleMinimal)
+	if err != nil {
+		t.Fatalf("GenerateExample failed: %v", err)
+	}
+	// Only the first oneof member; proto3 fields are otherwise optional
+	if len(body) != 1 || body[0].Path != "amount" {
+		t.Fatalf("expected only the first oneof field, got %+v", body)
+	}
+
+	if _, err := schema.GenerateExample("shop.v1.Product", "everything"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+	if _, err := schema.GenerateExample("shop.v1.Missing", ExampleFull); err == nil {
+		t.Fatal("expected an error for an unknown message")
+	}
+}
+
+func TestGenerateExample_RespectsConstraints(t *testing.T) {
+	schema := NewSchemaService(setupSignupRegistry(t))
+
+	body, err := schema.GenerateExample("acme.v1.Signup", ExampleMinimal)
+	if err != nil {
+		t.Fatalf("GenerateExample failed: %v", err)
+	}
+	// Required messages, repeated fields with min_items and implicit scalars with rules are
+	// part of the minimal body; unconstrained fields are not
+	values := bodyValues(body)
+	for _, path := range []string{"tags[0]", "profile", "legacy", "email", "age", "region"} {
+		if _, ok := values[path]; !ok {
+			t.Errorf("expected %s in minimal body %+v", path, body)
+		}
+	}
+	if _, ok := values["note"]; ok {
+		t.Errorf("expected no note in minimal body %+v", body)
+	}
+
+	body, err = schema.GenerateExample("acme.v1.Signup", ExampleFull)
+	if err != nil {
+		t.Fatalf("GenerateExample failed: %v", err)
+	}
+	values = bodyValues(body)
+	checks := map[string]interface{}{
+		"email":  "jane.doe@example.com",
+		"handle": "example",
+		"age":    int64(13),
+		"score":  0.5,
+		"plan":   "PLAN_FREE",
+		"offset": "1",
+		"region": "eu",
+		"seats":  int64(1),
+		"code":   "ACex",
+		"quota":  "18446744073709551615",
+	}
+	for path, want := range checks {
+		if got := values[path]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package registry
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// TypeCandidate is a message type that can decode a payload, with how well it fits
+type TypeCandidate struct {
+	FQN           string  `json:"fqn"`
+	Score         float64 `json:"score"`         // Matched fields plus top-level coverage; higher is better
+	MatchedFields int     `json:"matchedFields"` // Populated known fields, counted through nested messages
+	Coverage      float64 `json:"coverage"`      // Fraction of the type's top-level fields present in the payload
+}
+
+// InferMessageTypes ranks registered message types by how plausibly they decode body.
+// Types are rejected when decoding fails (wire-type mismatches on messages, invalid UTF-8
+// in proto3 strings) or leaves unknown fields anywhere in the result. At most limit
+// candidates are returned; limit <= 0 returns all of them.
+func (s *Service) InferMessageTypes(body []byte, limit int) []TypeCandidate {
+	if len(body) == 0 {
+		return nil
+	}
+
+	candidates := make([]TypeCandidate, 0)
+	for _, md := range s.allMessageDescriptors() {
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(body, msg); err != nil {
+			continue
+		}
+		matched, clean := countKnownFields(msg)
+		if !clean || matched == 0 {
+			continue
+		}
+
+		coverage := 0.0
+		if total := md.Fields().Len(); total > 0 {
+			present := 0
+			msg.Range(func(protoreflect.FieldDescriptor, protoreflect.Value) bool {
+				present++
+				return true
+			})
+			coverage = float64(present) / float64(total)
+		}
+
+		candidates = append(candidates, TypeCandidate{
+			FQN:           string(md.FullName()),
+			Score:         float64(matched) + coverage,
+			MatchedFields: matched,
+			Coverage:      coverage,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].FQN < candidates[j].FQN
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// inferSkipPackage holds descriptor and well-known types, which would otherwise match almost any payload
+const inferSkipPackage = "google.protobuf"
+
+// allMessageDescriptors returns every user-registered message, including nested ones but not map entries
+func (s *Service) allMessageDescriptors() []protoreflect.MessageDescriptor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []protoreflect.MessageDescriptor
+	var collect func(msgs protoreflect.MessageDescriptors)
+	collect = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			md := msgs.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+			out = append(out, md)
+			collect(md.Messages())
+		}
+	}
+	s.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		if fd.Package() != inferSkipPackage {
+			collect(fd.Messages())
+		}
+		return true
+	})
+	return out
+}
+
+// countKnownFields counts populated fields through nested messages and reports whether
+// the message and all its children are free of unknown fields
+func countKnownFields(msg protoreflect.Message) (int, bool) {
+	if len(msg.GetUnknown()) > 0 {
+		return 0, false
+	}
+
+	count := 0
+	clean := true
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		count++
+		var n int
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				var c int
+				c, clean = countKnownFields(mv.Message())
+				n += c
+				return clean
+			})
+		case fd.IsList():
+			if fd.Message() == nil {
+				return true
+			}
+			list := v.List()
+			for i := 0; i < list.Len() && clean; i++ {
+				var c int
+				c, clean = countKnownFields(list.Get(i).Message())
+				n += c
+			}
+		case fd.Message() != nil:
+			n, clean = countKnownFields(v.Message())
+		}
+		count += n
+		return clean
+	})
+	return count, clean
+}
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const inferProto = `syntax = "proto3";
+
+package infer.v1;
+
+message User {
+  string name = 1;
+  int64 id = 2;
+  Address address = 3;
+}
+
+message Address {
+  string city = 1;
+}
+
+message Counter {
+  int64 value = 2;
+}
+
+message Blob {
+  bytes data = 1;
+}
+
+message Flag {
+  bool enabled = 4;
+}
+`
+
+func TestInferMessageTypes(t *testing.T) {
+	reg := NewService()
+	if err := reg.RegisterFromVirtualFS(map[string][]byte{"infer.proto": []byte(inferProto)}); err != nil {
+		t.Fatalf("failed to register protos: %v", err)
+	}
+
+	var address []byte
+	address = protowire.AppendTag(address, 1, protowire.BytesType)
+	address = protowire.AppendString(address, "Paris")
+
+	var body []byte
+	body = protowire.AppendTag(body, 1, protowire.BytesType)
+	body = protowire.AppendString(body, "Ada")
+	body = protowire.AppendTag(body, 2, protowire.VarintType)
+	body = protowire.AppendVarint(body, 42)
+	body = protowire.AppendTag(body, 3, protowire.BytesType)
+	body = protowire.AppendBytes(body, address)
+
+	candidates := reg.InferMessageTypes(body, 0)
+	if len(candidates) != 1 || candidates[0].FQN != "infer.v1.User" {
+		t.Fatalf("expected only User to match, got %+v", candidates)
+	}
+	if candidates[0].MatchedFields != 4 || candidates[0].Coverage != 1 {
+		t.Fatalf("unexpected scoring: %+v", candidates[0])
+	}
+
+	// Field 1 as invalid UTF-8 bytes rules out string-typed candidates
+	var binary []byte
+	binary = protowire.AppendTag(binary, 1, protowire.BytesType)
+	binary = protowire.AppendBytes(binary, []byte{0xff, 0xfe})
+	candidates = reg.InferMessageTypes(binary, 0)
+	if len(candidates) != 1 || candidates[0].FQN != "infer.v1.Blob" {
+		t.Fatalf("expected only Blob to match invalid UTF-8, got %+v", candidates)
+	}
+
+	if got := reg.InferMessageTypes(nil, 0); got != nil {
+		t.Fatalf("expected no candidates for empty body, got %+v", got)
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// anyTypeName is the full name of google.protobuf.Any
+const anyTypeName = "google.protobuf.Any"
+
 // SchemaService provides comprehensive protobuf schema metadata
 type SchemaService struct {
 	registry *Service
@@ -47,7 +50,8 @@ type FieldSchema struct {
 	Map            *MapSchema             `json:"map,omitempty"`  // For map fields
 	WKT            *WellKnownType        `json:"wkt,omitempty"`   // Well-known type info
 	Constraints    *ValidationConstraints `json:"constraints,omitempty"` // Validation hints
-	BytesHint      *string                `json:"bytesHint,omitempty"`   // For bytes fields
+	BytesHint      *string                `json:"bytesHint,omitempty"`   // For bytes fields: encoding assumed when a BodyField names none
+	IsAny          bool                   `json:"isAny,omitempty"`       // google.protobuf.Any: set "@type" plus the packed type's fields
 }
 
 // OneofGroup represents a oneof group definition
@@ -75,24 +79,40 @@ type MapSchema struct {
 	KeyKind   string  `json:"keyKind"`   // Key type kind
 	ValueKind string  `json:"valueKind"` // Value type kind
 	ValueFQMN *string `json:"valueFqmn,omitempty"` // For message value types
+	ValueIsAny bool   `json:"valueIsAny,omitempty"` // Map values are google.protobuf.Any
 }
 
 // WellKnownType represents well-known type information
 type WellKnownType struct {
-	Type   string `json:"type"`   // WKT type (Timestamp, Duration, etc.)
-	Format string `json:"format"` // Format hint (RFC3339, etc.)
+	Type    string   `json:"type"`              // WKT type (Timestamp, Duration, etc.)
+	Format  string   `json:"format"`            // Format hint (RFC3339, etc.)
+	Accepts []string `json:"accepts,omitempty"` // Input forms accepted for dot-path values
 }
 
-// ValidationConstraints represents validation hints
+// ValidationConstraints represents validation hints parsed from buf.validate or
+// validate.rules field options (see FieldConstraints)
 type ValidationConstraints struct {
-	MinLen   *int32    `json:"minLen,omitempty"`
-	MaxLen   *int32    `json:"maxLen,omitempty"`
-	Min      *float64  `json:"min,omitempty"`
-	Max      *float64  `json:"max,omitempty"`
-	Pattern  *string   `json:"pattern,omitempty"`
-	Required *bool     `json:"required,omitempty"`
-	In       []string  `json:"in,omitempty"`
-	NotIn    []string  `json:"notIn,omitempty"`
+	MinLen       *int32                 `json:"minLen,omitempty"`       // Characters for strings, bytes for bytes
+	MaxLen       *int32                 `json:"maxLen,omitempty"`
+	Min          *float64               `json:"min,omitempty"`
+	Max          *float64               `json:"max,omitempty"`
+	ExclusiveMin bool                   `json:"exclusiveMin,omitempty"` // Min is gt rather than gte
+	ExclusiveMax bool                   `json:"exclusiveMax,omitempty"` // Max is lt rather than lte
+	ExactMin     string                 `json:"-"`                      // Min as written in a 64-bit integer rule, compared as an integer since float64 cannot hold every such bound; empty for other rules
+	ExactMax     string                 `json:"-"`                      // Max as written in a 64-bit integer rule, compared as an integer since float64 cannot hold every such bound; empty for other rules
+	Pattern      *string                `json:"pattern,omitempty"`
+	Prefix       *string                `json:"prefix,omitempty"`
+	Suffix       *string                `json:"suffix,omitempty"`
+	Contains     *string                `json:"contains,omitempty"`
+	Format       *string                `json:"format,omitempty"` // email, hostname, ip, ipv4, ipv6, uri, uri_ref or uuid
+	Required     *bool                  `json:"required,omitempty"`
+	In           []string               `json:"in,omitempty"` // Allowed values; a const rule is a single entry
+	NotIn        []string               `json:"notIn,omitempty"`
+	DefinedOnly  bool                   `json:"definedOnly,omitempty"` // Enum values must be declared
+	MinItems     *int32                 `json:"minItems,omitempty"`    // Repeated items or map pairs
+	MaxItems     *int32                 `json:"maxItems,omitempty"`
+	Unique       bool                   `json:"unique,omitempty"`
+	Items        *ValidationConstraints `json:"items,omitempty"` // Rules for each repeated item
 }
 
 // GetMessageSchema returns comprehensive schema metadata for a message
@@ -166,6 +186,7 @@ func (s *SchemaService) buildFieldSchema(field protoreflect.FieldDescriptor) Fie
 		if wkt := s.detectWellKnownType(fqmn); wkt != nil {
 			fieldSchema.WKT = wkt
 		}
+		fieldSchema.IsAny = fqmn == anyTypeName
 	}
 
 	// Handle enum fields
@@ -178,9 +199,11 @@ func (s *SchemaService) buildFieldSchema(field protoreflect.FieldDescriptor) Fie
 		fieldSchema.Map = s.buildMapSchema(field)
 	}
 
+	fieldSchema.Constraints = FieldConstraints(field)
+
 	// Handle bytes fields
 	if field.Kind() == protoreflect.BytesKind {
-		hint := "base64"
+		hint := "base64" // types.BytesBase64
 		fieldSchema.BytesHint = &hint
 	}
 
@@ -238,6 +261,7 @@ func (s *SchemaService) buildMapSchema(field protoreflect.FieldDescriptor) *MapS
 	if field.MapValue().Message() != nil {
 		fqmn := string(field.MapValue().Message().FullName())
 		mapSchema.ValueFQMN = &fqmn
+		mapSchema.ValueIsAny = fqmn == anyTypeName
 	}
 
 	return mapSchema
@@ -282,25 +306,73 @@ func (s *SchemaService) getDefaultValue(field protoreflect.FieldDescriptor) inte
 	}
 }
 
-// detectWellKnownType detects if a type is a well-known type
+// detectWellKnownType detects if a type is a well-known type and describes the input
+// forms the runner accepts for it
 func (s *SchemaService) detectWellKnownType(fqmn string) *WellKnownType {
 	switch fqmn {
 	case "google.protobuf.Timestamp":
-		return &WellKnownType{Type: "Timestamp", Format: "RFC3339"}
+		return &WellKnownType{Type: "Timestamp", Format: "RFC3339", Accepts: []string{
+			`RFC3339 string ("2024-01-02T15:04:05Z")`,
+			"Unix seconds as number or string",
+			"{seconds, nanos}",
+		}}
 	case "google.protobuf.Duration":
-		return &WellKnownType{Type: "Duration", Format: "[-]Ns"}
+		return &WellKnownType{Type: "Duration", Format: "[-]Ns", Accepts: []string{
+			`seconds with "s" suffix ("1.5s")`,
+			"seconds as number or string",
+			`Go duration ("1m30s", "500ms")`,
+			"{seconds, nanos}",
+		}}
+	case "google.protobuf.FieldMask":
+		return &WellKnownType{Type: "FieldMask", Format: "comma-separated paths", Accepts: []string{
+			`comma-separated string ("name,address.zip_code")`,
+			"list of paths",
+			"{paths: [...]}",
+		}}
 	case "google.protobuf.Struct":
-		return &WellKnownType{Type: "Struct", Format: "JSON"}
+		return &WellKnownType{Type: "Struct", Format: "JSON", Accepts: []string{
+			"JSON object",
+			"string holding a JSON object",
+		}}
 	case "google.protobuf.Value":
-		return &WellKnownType{Type: "Value", Format: "JSON"}
+		return &WellKnownType{Type: "Value", Format: "JSON", Accepts: []string{
+			"any JSON value",
+		}}
+	case "google.protobuf.ListValue":
+		return &WellKnownType{Type: "ListValue", Format: "JSON array", Accepts: []string{
+			"JSON array",
+			"string holding a JSON array",
+		}}
+	case "google.protobuf.Empty":
+		return &WellKnownType{Type: "Empty", Format: "{}", Accepts: []string{
+			"any value; always sent as {}",
+		}}
 	case "google.protobuf.Any":
-		return &WellKnownType{Type: "Any", Format: "typeUrl + value"}
+		return &WellKnownType{Type: "Any", Format: "@type + fields", Accepts: []string{
+			`"@type" (full type URL or message name) plus the packed message's fields`,
+		}}
 	case "google.protobuf.Int32Value", "google.protobuf.Int64Value",
-		 "google.protobuf.UInt32Value", "google.protobuf.UInt64Value",
-		 "google.protobuf.FloatValue", "google.protobuf.DoubleValue",
-		 "google.protobuf.BoolValue", "google.protobuf.StringValue",
-		 "google.protobuf.BytesValue":
-		return &WellKnownType{Type: "Wrapper", Format: "scalar with presence"}
+		"google.protobuf.UInt32Value", "google.protobuf.UInt64Value",
+		"google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return &WellKnownType{Type: "Wrapper", Format: "scalar with presence", Accepts: []string{
+			"number or numeric string",
+			"{value}",
+		}}
+	case "google.protobuf.BoolValue":
+		return &WellKnownType{Type: "Wrapper", Format: "scalar with presence", Accepts: []string{
+			`boolean or "true"/"false"`,
+			"{value}",
+		}}
+	case "google.protobuf.StringValue":
+		return &WellKnownType{Type: "Wrapper", Format: "scalar with presence", Accepts: []string{
+			"string; numbers and booleans are converted",
+			"{value}",
+		}}
+	case "google.protobuf.BytesValue":
+		return &WellKnownType{Type: "Wrapper", Format: "scalar with presence", Accepts: []string{
+			"base64 string",
+			"{value}",
+		}}
 	}
 	return nil
 }
@@ -17,6 +17,7 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 // Service manages Protobuf file registration and message type discovery
@@ -311,6 +312,97 @@ func (s *Service) GetMessageDescriptor(fqn string) (protoreflect.MessageDescript
 	return nil, fmt.Errorf("message not found: %s", fqn)
 }
 
+// ServiceInfo describes a registered RPC service and its methods
+type ServiceInfo struct {
+	FQN     string       `json:"fqn"`
+	Methods []MethodInfo `json:"methods"`
+}
+
+// MethodInfo describes a single RPC method of a registered service
+type MethodInfo struct {
+	Name            string `json:"name"`
+	FullMethod      string `json:"fullMethod"` // gRPC path form, e.g. /pkg.Service/Method
+	InputType       string `json:"inputType"`
+	OutputType      string `json:"outputType"`
+	ClientStreaming bool   `json:"clientStreaming"`
+	ServerStreaming bool   `json:"serverStreaming"`
+}
+
+// ListServices returns all registered services with their methods
+func (s *Service) ListServices() ([]ServiceInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	services := make([]ServiceInfo, 0)
+	s.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		svcs := fd.Services()
+		for i := 0; i < svcs.Len(); i++ {
+			sd := svcs.Get(i)
+			info := ServiceInfo{
+				FQN:     string(sd.FullName()),
+				Methods: make([]MethodInfo, 0, sd.Methods().Len()),
+			}
+			for j := 0; j < sd.Methods().Len(); j++ {
+				info.Methods = append(info.Methods, buildMethodInfo(sd.Methods().Get(j)))
+			}
+			services = append(services, info)
+		}
+		return true
+	})
+
+	return services, nil
+}
+
+// GetMethodDescriptor returns a method descriptor by its fully qualified name.
+// Accepts "pkg.Service/Method", "/pkg.Service/Method" and "pkg.Service.Method".
+func (s *Service) GetMethodDescriptor(fqn string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName := splitMethodName(fqn)
+	if serviceName == "" || methodName == "" {
+		return nil, fmt.Errorf("invalid method name: %s", fqn)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service not found: %s", serviceName)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("not a service: %s", serviceName)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, fmt.Errorf("method not found: %s/%s", serviceName, methodName)
+	}
+	return md, nil
+}
+
+// splitMethodName splits a method reference into service FQN and method name
+func splitMethodName(fqn string) (string, string) {
+	name := strings.TrimPrefix(strings.TrimSpace(fqn), "/")
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return "", ""
+}
+
+// buildMethodInfo converts a method descriptor into its API representation
+func buildMethodInfo(md protoreflect.MethodDescriptor) MethodInfo {
+	return MethodInfo{
+		Name:            string(md.Name()),
+		FullMethod:      "/" + string(md.Parent().FullName()) + "/" + string(md.Name()),
+		InputType:       string(md.Input().FullName()),
+		OutputType:      string(md.Output().FullName()),
+		ClientStreaming: md.IsStreamingClient(),
+		ServerStreaming: md.IsStreamingServer(),
+	}
+}
+
 // compositeResolver checks the current registry first, then the global files
 type compositeResolver struct {
 	primary  *protoregistry.Files
@@ -341,6 +433,56 @@ func (r compositeResolver) FindDescriptorByName(name protoreflect.FullName) (pro
 	return nil, protoregistry.NotFound
 }
 
+// TypeResolver resolves message and extension types by name or type URL, as protojson needs
+// for google.protobuf.Any fields
+type TypeResolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+// Resolver returns a TypeResolver over the registered files that falls back to the types
+// linked into the binary, so Any values can pack types that only exist in uploaded protos
+func (s *Service) Resolver() TypeResolver {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return compositeResolver{primary: s.files, fallback: protoregistry.GlobalFiles}
+}
+
+func (r compositeResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if r.primary != nil {
+		if d, err := r.primary.FindDescriptorByName(name); err == nil {
+			if md, ok := d.(protoreflect.MessageDescriptor); ok {
+				return dynamicpb.NewMessageType(md), nil
+			}
+		}
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r compositeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if idx := strings.LastIndex(url, "/"); idx >= 0 {
+		name = url[idx+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r compositeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if r.primary != nil {
+		if d, err := r.primary.FindDescriptorByName(field); err == nil {
+			if xd, ok := d.(protoreflect.ExtensionDescriptor); ok {
+				return dynamicpb.NewExtensionType(xd), nil
+			}
+		}
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+// FindExtensionByNumber only consults linked types; registered files are not indexed by extension number
+func (r compositeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
 // GetMessageFields returns field information for a message by FQN
 func (s *Service) GetMessageFields(fqn string) ([]map[string]interface{}, error) {
 	s.logger.Info().
@@ -299,6 +299,26 @@ func (s *Service) storeProtoparseDescriptors(fileDescriptors []*desc.FileDescrip
 	}
 	addWellKnown("google/protobuf/descriptor.proto")
 	addWellKnown("google/protobuf/timestamp.proto")
+	// Plus any other Google imports (any.proto, duration.proto, ...) the user files pull in
+	seenWellKnown := map[string]bool{
+		"google/protobuf/descriptor.proto": true,
+		"google/protobuf/timestamp.proto":  true,
+	}
+	var addDeps func(fd *desc.FileDescriptor)
+	addDeps = func(fd *desc.FileDescriptor) {
+		for _, dep := range fd.GetDependencies() {
+			name := dep.GetName()
+			if !strings.HasPrefix(name, "google/") || seenWellKnown[name] {
+				continue
+			}
+			seenWellKnown[name] = true
+			addDeps(dep)
+			addWellKnown(name)
+		}
+	}
+	for _, fd := range userFiles {
+		addDeps(fd)
+	}
 	if err := s.registerDescriptorSet(set); err != nil {
 		return fmt.Errorf("failed to register protoparse descriptors into registry: %w", err)
 	}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/datahopper/backend/internal/registry"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// anyTypeName is the full name of google.protobuf.Any
+const anyTypeName = "google.protobuf.Any"
+
+// anyTypeKey is the JSON key naming the packed type of an Any value
+const anyTypeKey = "@type"
+
+// defaultTypeURLPrefix is prepended to bare message names given as "@type"
+const defaultTypeURLPrefix = "type.googleapis.com/"
+
+// typeResolver returns the resolver used for protojson and Any handling
+func (s *Service) typeResolver() registry.TypeResolver {
+	if s.registry == nil {
+		return protoregistry.GlobalTypes
+	}
+	return s.registry.Resolver()
+}
+
+// messageDescriptorFor returns the descriptor to walk a nested JSON object with. For
+// google.protobuf.Any objects that carry "@type" this is the packed type, and a bare
+// message name in "@type" is expanded to a full type URL in place.
+func (s *Service) messageDescriptorFor(md protoreflect.MessageDescriptor, m map[string]interface{}) protoreflect.MessageDescriptor {
+	if md.FullName() != anyTypeName {
+		return md
+	}
+	typeURL, ok := m[anyTypeKey].(string)
+	if !ok || strings.TrimSpace(typeURL) == "" {
+		return md
+	}
+	if !strings.Contains(typeURL, "/") {
+		typeURL = defaultTypeURLPrefix + strings.TrimSpace(typeURL)
+		m[anyTypeKey] = typeURL
+	}
+
+	mt, err := s.typeResolver().FindMessageByURL(typeURL)
+	if err != nil {
+		return md
+	}
+	return mt.Descriptor()
+}
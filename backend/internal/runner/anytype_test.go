@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+)
+
+const envelopeProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/any.proto";
+
+message Envelope {
+  string id = 1;
+  google.protobuf.Any payload = 2;
+  repeated google.protobuf.Any extras = 3;
+}
+
+message Order {
+  string sku = 1;
+  int64 quantity = 2;
+  string note = 3;
+}
+`
+
+func TestAny_RoundTrip(t *testing.T) {
+	svc := NewService(setupRegistry(t, "envelope.proto"))
+
+	body, _, err := buildTestBody(svc, "acme.v1.Envelope", []types.BodyField{
+		{Path: "id", Value: "e1"},
+		{Path: "payload.@type", Value: "acme.v1.Order"},
+		{Path: "payload.sku", Value: "A-1"},
+		{Path: "payload.quantity", Value: "3"},
+		{Path: "payload.note", Value: 42}, // coerced to string via the packed type
+		{Path: "extras[0].@type", Value: "type.googleapis.com/acme.v1.Order"},
+		{Path: "extras[0].sku", Value: "B-2"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+	encoded, ok := body.([]byte)
+	if !ok {
+		t.Fatalf("expected encoded bytes, got %T", body)
+	}
+
+	decoded, err := svc.decodeProtobufResponse("acme.v1.Envelope", encoded)
+	if err != nil {
+		t.Fatalf("decodeProtobufResponse failed: %v", err)
+	}
+	for _, want := range []string{
+		`"@type": "type.googleapis.com/acme.v1.Order"`,
+		`"sku": "A-1"`,
+		`"quantity": "3"`,
+		`"note": "42"`,
+		`"sku": "B-2"`,
+	} {
+		if !strings.Contains(collapseSpaces(decoded), want) {
+			t.Errorf("expected decoded output to contain %s, got:\n%s", want, decoded)
+		}
+	}
+}
+
+func TestAny_UnknownType(t *testing.T) {
+	svc := NewService(setupRegistry(t, "envelope.proto"))
+
+	_, _, err := buildTestBody(svc, "acme.v1.Envelope", []types.BodyField{
+		{Path: "payload.@type", Value: "acme.v1.Missing"},
+		{Path: "payload.sku", Value: "A-1"},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "acme.v1.Missing") {
+		t.Fatalf("expected unresolvable Any type error, got %v", err)
+	}
+}
+
+func TestSchema_MarksAnyFields(t *testing.T) {
+	reg := setupRegistry(t, "envelope.proto")
+	schema, err := reg.GetSchemaService().GetMessageSchema("acme.v1.Envelope")
+	if err != nil {
+		t.Fatalf("GetMessageSchema failed: %v", err)
+	}
+	for _, f := range schema.Fields {
+		if want := f.Name != "id"; f.IsAny != want {
+			t.Errorf("field %s: isAny=%v, want %v", f.Name, f.IsAny, want)
+		}
+	}
+}
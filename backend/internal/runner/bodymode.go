@@ -0,0 +1,378 @@
+package runner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datahopper/backend/internal/dotpath"
+	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// FieldErrorInvalidBody is the FieldError.Code of a raw JSON or text-format body that does
+// not parse as its message; the error has no path
+const FieldErrorInvalidBody = "invalid_body"
+
+// isRawBodyMode reports whether a body mode keeps the body in RawBody
+func isRawBodyMode(mode string) bool {
+	return mode == types.BodyModeJSON || mode == types.BodyModeText
+}
+
+// requestBody builds the body of req in its body mode, with the warnings of field bodies.
+// Field values are interpolated one by one and raw bodies as a whole before they are parsed.
+func (s *Service) requestBody(req *RunReq, vars map[string]string) (interface{}, []FieldError, error) {
+	switch {
+	case req.BodyMode == "" || req.BodyMode == types.BodyModeFields:
+		return s.buildBody(req.ProtoMessage, interpolateFields(req.Body, vars), req.OneofSelections, req.fileOwner())
+	case isRawBodyMode(req.BodyMode):
+		body, err := s.buildRawBody(req.ProtoMessage, req.BodyMode, interpolateRawBody(req.BodyMode, req.RawBody, vars))
+		return body, nil, err
+	}
+	return nil, nil, fmt.Errorf("unknown body mode %q (want %s, %s or %s)", req.BodyMode, types.BodyModeFields, types.BodyModeJSON, types.BodyModeText)
+}
+
+// interpolateRawBody replaces variables in a raw body. Values placed inside the string
+// literals of JSON bodies are escaped for them.
+func interpolateRawBody(mode, raw string, vars map[string]string) string {
+	if mode == types.BodyModeJSON {
+		return interpolate.JSON(raw, vars)
+	}
+	return interpolate.String(raw, vars)
+}
+
+// interpolateFields replaces variables in the values of body fields. A value that is a whole
+// placeholder takes the typed value of its variable, except for encoded bytes, which are text.
+func interpolateFields(fields []types.BodyField, vars map[string]string) []types.BodyField {
+	if len(vars) == 0 {
+		return fields
+	}
+	out := make([]types.BodyField, len(fields))
+	for i, field := range fields {
+		if text, ok := field.Value.(string); ok && field.Encoding != "" {
+			field.Value = interpolate.String(text, vars)
+		} else {
+			field.Value = interpolate.Deep(field.Value, vars)
+		}
+		out[i] = field
+	}
+	return out
+}
+
+// fieldValues lists the values of body fields for interpolate.WithDynamic
+func fieldValues(fields []types.BodyField) []interface{} {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = field.Value
+	}
+	return values
+}
+
+// buildRawBody parses a JSON or text-format document as messageType and encodes it. JSON
+// bodies without a message type are sent as they are once they parse. Parse failures are
+// returned as a *BodyValidationError. Returns nil for an empty document.
+func (s *Service) buildRawBody(messageType, mode, raw string) (interface{}, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	if messageType == "" {
+		if mode != types.BodyModeJSON {
+			return nil, fmt.Errorf("%s bodies need a message type", mode)
+		}
+		if !json.Valid([]byte(raw)) {
+			return nil, &BodyValidationError{Errors: []FieldError{{Code: FieldErrorInvalidBody, Message: "body is not valid JSON"}}}
+		}
+		return raw, nil
+	}
+
+	md, err := s.registry.GetMessageDescriptor(messageType)
+	if err != nil {
+		return nil, fmt.Errorf("message descriptor not found: %s", messageType)
+	}
+	msg, err := s.parseRawBody(md, mode, raw)
+	if err != nil {
+		return nil, &BodyValidationError{MessageType: messageType, Errors: []FieldError{{Code: FieldErrorInvalidBody, Message: err.Error()}}}
+	}
+	encoded, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal protobuf: %w", err)
+	}
+	return encoded, nil
+}
+
+// parseRawBody parses a protojson or text-format document into a message of type md
+func (s *Service) parseRawBody(md protoreflect.MessageDescriptor, mode, raw string) (*dynamicpb.Message, error) {
+	msg := dynamicpb.NewMessage(md)
+	var err error
+	switch mode {
+	case types.BodyModeJSON:
+		err = protojson.UnmarshalOptions{Resolver: s.typeResolver()}.Unmarshal([]byte(raw), msg)
+	case types.BodyModeText:
+		err = prototext.UnmarshalOptions{Resolver: s.typeResolver()}.Unmarshal([]byte(raw), msg)
+	default:
+		return nil, fmt.Errorf("body mode %q has no raw document", mode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %v", mode, err)
+	}
+	return msg, nil
+}
+
+// validateRawBody reports the parse error of a raw body as its only FieldError
+func (s *Service) validateRawBody(req *RunReq) ([]byte, []FieldError, error) {
+	vars, err := interpolate.WithDynamic(req.Variables, req.RawBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := variableCycle(vars, req.RawBody); err != nil {
+		return nil, nil, err
+	}
+	body, err := s.buildRawBody(req.ProtoMessage, req.BodyMode, interpolateRawBody(req.BodyMode, req.RawBody, vars))
+	var validationErr *BodyValidationError
+	if errors.As(err, &validationErr) {
+		return nil, validationErr.Errors, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	encoded, _ := body.([]byte)
+	return encoded, []FieldError{}, nil
+}
+
+// ConvertBody rewrites a body in another body mode through the message it describes, so
+// nothing the message can hold is lost on the way
+func (s *Service) ConvertBody(req *ConvertBodyReq) (*ConvertBodyRes, error) {
+	if s.registry == nil {
+		return nil, fmt.Errorf("registry not configured")
+	}
+	md, err := s.registry.GetMessageDescriptor(req.MessageType)
+	if err != nil {
+		return nil, fmt.Errorf("message descriptor not found: %s", req.MessageType)
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	switch from := req.From; {
+	case from == "" || from == types.BodyModeFields:
+		body, _, err := s.buildBody(req.MessageType, req.Body, req.OneofSelections, req.fileOwner())
+		if err != nil {
+			return nil, err
+		}
+		if encoded, ok := body.([]byte); ok {
+			if err := (proto.UnmarshalOptions{Resolver: s.typeResolver()}).Unmarshal(encoded, msg); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal protobuf: %w", err)
+			}
+		}
+	case isRawBodyMode(from):
+		if strings.TrimSpace(req.RawBody) != "" {
+			parsed, err := s.parseRawBody(md, from, req.RawBody)
+			if err != nil {
+				return nil, &BodyValidationError{MessageType: req.MessageType, Errors: []FieldError{{Code: FieldErrorInvalidBody, Message: err.Error()}}}
+			}
+			msg = parsed
+		}
+	default:
+		return nil, fmt.Errorf("unknown body mode %q", from)
+	}
+
+	res := &ConvertBodyRes{Mode: req.To}
+	switch req.To {
+	case types.BodyModeFields:
+		fields := []types.BodyField{}
+		if err := s.flattenMessage(msg, "", &fields); err != nil {
+			return nil, err
+		}
+		res.Body = fields
+	case types.BodyModeJSON:
+		out, err := protojson.MarshalOptions{Multiline: true, Indent: "  ", Resolver: s.typeResolver()}.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal to JSON: %w", err)
+		}
+		res.RawBody = string(out)
+	case types.BodyModeText:
+		out, err := prototext.MarshalOptions{Multiline: true, Indent: "  ", Resolver: s.typeResolver()}.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal to text format: %w", err)
+		}
+		res.RawBody = string(out)
+	default:
+		return nil, fmt.Errorf("unknown body mode %q", req.To)
+	}
+	return res, nil
+}
+
+// FlattenBody turns a JSON object into the dot-path fields that rebuild it. With a message
+// type the document is read as protojson, so unpopulated fields are dropped and values take
+// the same form ConvertBody gives them; otherwise it is flattened as plain JSON.
+func (s *Service) FlattenBody(req *FlattenBodyReq) ([]types.BodyField, error) {
+	if req.MessageType == "" {
+		fields, err := dotpath.FlattenJSON([]byte(req.JSON))
+		if err != nil {
+			return nil, &BodyValidationError{Errors: []FieldError{{Code: FieldErrorInvalidBody, Message: err.Error()}}}
+		}
+		return fields, nil
+	}
+	if s.registry == nil {
+		return nil, fmt.Errorf("registry not configured")
+	}
+	md, err := s.registry.GetMessageDescriptor(req.MessageType)
+	if err != nil {
+		return nil, fmt.Errorf("message descriptor not found: %s", req.MessageType)
+	}
+	msg, err := s.parseRawBody(md, types.BodyModeJSON, req.JSON)
+	if err != nil {
+		return nil, &BodyValidationError{MessageType: req.MessageType, Errors: []FieldError{{Code: FieldErrorInvalidBody, Message: err.Error()}}}
+	}
+	fields := []types.BodyField{}
+	if err := s.flattenMessage(msg, "", &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// flattenMessage appends a dot-path field for every value set in m, in field order. Set
+// fields holding their default are marked PresenceSet, empty messages become {} and Any
+// values are unpacked under their "@type".
+func (s *Service) flattenMessage(m protoreflect.Message, prefix string, out *[]types.BodyField) error {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		path := joinPath(prefix, fd.JSONName())
+		v := m.Get(fd)
+		switch {
+		case fd.IsMap():
+			entries := v.Map()
+			keys := make([]protoreflect.MapKey, 0, entries.Len())
+			entries.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, k)
+				return true
+			})
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, k := range keys {
+				if err := s.flattenValue(fd.MapValue(), entries.Get(k), joinPath(path, k.String()), out); err != nil {
+					return err
+				}
+			}
+		case fd.IsList():
+			list := v.List()
+			for idx := 0; idx < list.Len(); idx++ {
+				if err := s.flattenValue(fd, list.Get(idx), fmt.Sprintf("%s[%d]", path, idx), out); err != nil {
+					return err
+				}
+			}
+		default:
+			before := len(*out)
+			if err := s.flattenValue(fd, v, path, out); err != nil {
+				return err
+			}
+			if fd.Message() == nil && fd.HasPresence() && len(*out) == before+1 && scalarJSONValue(fd, v) == scalarJSONValue(fd, fd.Default()) {
+				(*out)[before].Presence = types.PresenceSet
+			}
+		}
+	}
+	return nil
+}
+
+// flattenValue appends the dot-path fields of a single value of fd
+func (s *Service) flattenValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, path string, out *[]types.BodyField) error {
+	if fd.Message() == nil {
+		*out = append(*out, types.BodyField{Path: path, Value: scalarJSONValue(fd, v)})
+		return nil
+	}
+
+	msg := v.Message()
+	name := string(fd.Message().FullName())
+	switch {
+	case name == anyTypeName:
+		fields := msg.Descriptor().Fields()
+		typeURL := msg.Get(fields.ByName("type_url")).String()
+		if typeURL == "" {
+			*out = append(*out, types.BodyField{Path: path, Value: map[string]interface{}{}})
+			return nil
+		}
+		mt, err := s.typeResolver().FindMessageByURL(typeURL)
+		if err != nil {
+			return fmt.Errorf("%s: message type %s is not registered", path, typeURL)
+		}
+		packed := mt.New()
+		if err := (proto.UnmarshalOptions{Resolver: s.typeResolver()}).Unmarshal(msg.Get(fields.ByName("value")).Bytes(), packed.Interface()); err != nil {
+			return fmt.Errorf("%s: failed to unpack %s: %w", path, typeURL, err)
+		}
+		*out = append(*out, types.BodyField{Path: joinPath(path, anyTypeKey), Value: typeURL})
+		return s.flattenMessage(packed, path, out)
+	case isWellKnownJSONType(name):
+		// Well-known types take their JSON form as a single value
+		encoded, err := protojson.MarshalOptions{Resolver: s.typeResolver()}.Marshal(msg.Interface())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		var value interface{}
+		dec := json.NewDecoder(strings.NewReader(string(encoded)))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		*out = append(*out, types.BodyField{Path: path, Value: value})
+		return nil
+	}
+
+	before := len(*out)
+	if err := s.flattenMessage(msg, path, out); err != nil {
+		return err
+	}
+	if len(*out) == before {
+		// Keep set but empty messages
+		*out = append(*out, types.BodyField{Path: path, Value: map[string]interface{}{}})
+	}
+	return nil
+}
+
+// scalarJSONValue returns the dot-path value of a scalar or enum: 64-bit integers as
+// strings, bytes as base64, enums by name and non-finite floats as protojson spells them
+func scalarJSONValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return int32(v.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return uint32(v.Uint())
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(v.Int(), 10)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(v.Uint(), 10)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return "NaN"
+		case math.IsInf(f, 1):
+			return "Infinity"
+		case math.IsInf(f, -1):
+			return "-Infinity"
+		}
+		return f
+	}
+	return v.Interface()
+}
@@ -0,0 +1,239 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const orderProto = `syntax = "proto3";
+
+package order.v1;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/timestamp.proto";
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+}
+
+message Line {
+  string sku = 1;
+  int64 qty = 2;
+}
+
+message Order {
+  string id = 1;
+  Status status = 2;
+  repeated Line lines = 3;
+  map<string, string> labels = 4;
+  google.protobuf.Timestamp placed_at = 5;
+  optional int32 priority = 6;
+  google.protobuf.Any meta = 7;
+  bytes blob = 8;
+  Line empty = 9;
+}
+`
+
+const orderText = `id: "o-1"
+status: STATUS_OPEN
+lines { sku: "A-1" qty: 9007199254740993 }
+lines { sku: "B-2" }
+labels { key: "env" value: "prod" }
+placed_at { seconds: 1700000000 nanos: 5 }
+priority: 0
+meta { [type.googleapis.com/order.v1.Line] { sku: "packed" } }
+blob: "\x00\xff"
+empty {}
+`
+
+// parseOrder parses text-format Order as the reference message
+func parseOrder(t *testing.T, svc *Service, text string) *dynamicpb.Message {
+	t.Helper()
+	md, _ := svc.registry.GetMessageDescriptor("order.v1.Order")
+	msg := dynamicpb.NewMessage(md)
+	if err := (prototext.UnmarshalOptions{Resolver: svc.typeResolver()}).Unmarshal([]byte(text), msg); err != nil {
+		t.Fatalf("failed to parse reference order: %v", err)
+	}
+	return msg
+}
+
+// decodeOrder decodes an encoded Order body
+func decodeOrder(t *testing.T, svc *Service, body interface{}) *dynamicpb.Message {
+	t.Helper()
+	encoded, ok := body.([]byte)
+	if !ok {
+		t.Fatalf("expected encoded bytes, got %T", body)
+	}
+	md, _ := svc.registry.GetMessageDescriptor("order.v1.Order")
+	msg := dynamicpb.NewMessage(md)
+	if err := (proto.UnmarshalOptions{Resolver: svc.typeResolver()}).Unmarshal(encoded, msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	return msg
+}
+
+func TestBuildRequestContext_RawBodies(t *testing.T) {
+	svc := NewService(setupRegistry(t, "order.proto"))
+	want := parseOrder(t, svc, `id: "o-7" status: STATUS_OPEN lines { qty: 9007199254740993 } priority: 0`)
+
+	for _, req := range []*RunReq{
+		{BodyMode: types.BodyModeText, RawBody: `id: "{{orderId}}" status: STATUS_OPEN lines { qty: 9007199254740993 } priority: 0`},
+		{BodyMode: types.BodyModeJSON, RawBody: `{"id": "{{orderId}}", "status": "STATUS_OPEN", "lines": [{"qty": "9007199254740993"}], "priority": 0}`},
+	} {
+		req.Method, req.URL, req.ProtoMessage = "POST", "http://localhost/orders", "order.v1.Order"
+		req.Variables = map[string]string{"orderId": "o-7"}
+		ctx, err := svc.buildRequestContext(req)
+		if err != nil {
+			t.Fatalf("%s: buildRequestContext failed: %v", req.BodyMode, err)
+		}
+		if got := decodeOrder(t, svc, ctx.Body); !proto.Equal(got, want) {
+			t.Errorf("%s: expected %v, got %v", req.BodyMode, want, got)
+		}
+	}
+}
+
+func TestBuildRequestContext_EscapesJSONStringPlaceholders(t *testing.T) {
+	svc := NewService(setupRegistry(t, "order.proto"))
+	want := parseOrder(t, svc, `id: "o-\"7\"\\" labels { key: "note" value: "a\nb" } priority: 3`)
+
+	ctx, err := svc.buildRequestContext(&RunReq{
+		Method:       "POST",
+		URL:          "http://localhost/orders",
+		ProtoMessage: "order.v1.Order",
+		BodyMode:     types.BodyModeJSON,
+		RawBody:      `{"id": "{{orderId}}", "labels": {"note": "{{note}}"}, "priority": {{priority}}}`,
+		Variables:    map[string]string{"orderId": `o-"7"\`, "note": "a\nb", "priority": "3"},
+	})
+	if err != nil {
+		t.Fatalf("buildRequestContext failed: %v", err)
+	}
+	if got := decodeOrder(t, svc, ctx.Body); !proto.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestConvertBody_RoundTripsWithoutLoss(t *testing.T) {
+	svc := NewService(setupRegistry(t, "order.proto"))
+	want := parseOrder(t, svc, orderText)
+
+	mode, body, raw := types.BodyModeText, []types.BodyField(nil), orderText
+	for _, to := range []string{types.BodyModeFields, types.BodyModeJSON, types.BodyModeFields, types.BodyModeText} {
+		res, err := svc.ConvertBody(&ConvertBodyReq{MessageType: "order.v1.Order", From: mode, To: to, Body: body, RawBody: raw})
+		if err != nil {
+			t.Fatalf("%s -> %s: ConvertBody failed: %v", mode, to, err)
+		}
+		mode, body, raw = res.Mode, res.Body, res.RawBody
+
+		got, _, err := svc.requestBody(&RunReq{ProtoMessage: "order.v1.Order", BodyMode: mode, Body: body, RawBody: raw}, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to encode converted body: %v", mode, err)
+		}
+		if msg := decodeOrder(t, svc, got); !proto.Equal(msg, want) {
+			t.Errorf("%s: expected %v, got %v", mode, want, msg)
+		}
+	}
+}
+
+func TestConvertBody_FlattensToDotPaths(t *testing.T) {
+	svc := NewService(setupRegistry(t, "order.proto"))
+
+	res, err := svc.ConvertBody(&ConvertBodyReq{MessageType: "order.v1.Order", From: types.BodyModeText, To: types.BodyModeFields, RawBody: orderText})
+	if err != nil {
+		t.Fatalf("ConvertBody failed: %v", err)
+	}
+	got := map[string]types.BodyField{}
+	for _, f := range res.Body {
+		got[f.Path] = f
+	}
+	for path, want := range map[string]interface{}{
+		"lines[0].qty": "9007199254740993",
+		"labels.env":   "prod",
+		"placedAt":     "2023-11-14T22:13:20.000000005Z",
+		"meta.@type":   "type.googleapis.com/order.v1.Line",
+		"meta.sku":     "packed",
+		"blob":         "AP8=",
+		"status":       "STATUS_OPEN",
+		"lines[1].sku": "B-2",
+		"priority":     int32(0),
+	} {
+		if got[path].Value != want {
+			t.Errorf("%s: expected %v, got %v", path, want, got[path].Value)
+		}
+	}
+	if got["priority"].Presence != types.PresenceSet {
+		t.Errorf("expected priority marked %s, got %q", types.PresenceSet, got["priority"].Presence)
+	}
+	if empty, ok := got["empty"].Value.(map[string]interface{}); !ok || len(empty) != 0 {
+		t.Errorf("expected empty message as {}, got %v", got["empty"].Value)
+	}
+}
+
+func TestValidateRun_RawBodyErrors(t *testing.T) {
+	svc := NewService(setupRegistry(t, "order.proto"))
+
+	fieldErrors, _, err := svc.ValidateRun(&RunReq{
+		Method:       "POST",
+		URL:          "http://localhost/orders",
+		ProtoMessage: "order.v1.Order",
+		BodyMode:     types.BodyModeText,
+		RawBody:      `id: "o-1" colour: "red"`,
+	})
+	if err != nil {
+		t.Fatalf("ValidateRun failed: %v", err)
+	}
+	if len(fieldErrors) != 1 || fieldErrors[0].Code != FieldErrorInvalidBody {
+		t.Fatalf("expected one %s error, got %+v", FieldErrorInvalidBody, fieldErrors)
+	}
+}
+
+func TestFlattenBody(t *testing.T) {
+	svc := NewService(setupRegistry(t, "order.proto"))
+	decoded := `{
+  "id": "o-1",
+  "status": "STATUS_UNSPECIFIED",
+  "lines": [{"sku": "A-1", "qty": "9007199254740993"}],
+  "labels": {},
+  "priority": 0
+}`
+
+	for _, tc := range []struct {
+		messageType string
+		want        []types.BodyField
+	}{
+		{"order.v1.Order", []types.BodyField{
+			{Path: "id", Value: "o-1"},
+			{Path: "lines[0].sku", Value: "A-1"},
+			{Path: "lines[0].qty", Value: "9007199254740993"},
+			{Path: "priority", Value: int32(0), Presence: types.PresenceSet},
+		}},
+		{"", []types.BodyField{
+			{Path: "id", Value: "o-1"},
+			{Path: "labels", Value: map[string]interface{}{}},
+			{Path: "lines[0].qty", Value: "9007199254740993"},
+			{Path: "lines[0].sku", Value: "A-1"},
+			{Path: "priority", Value: float64(0)},
+			{Path: "status", Value: "STATUS_UNSPECIFIED"},
+		}},
+	} {
+		got, err := svc.FlattenBody(&FlattenBodyReq{MessageType: tc.messageType, JSON: decoded})
+		if err != nil {
+			t.Fatalf("%q: FlattenBody failed: %v", tc.messageType, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: expected %+v, got %+v", tc.messageType, tc.want, got)
+		}
+	}
+
+	_, err := svc.FlattenBody(&FlattenBodyReq{MessageType: "order.v1.Order", JSON: `{"colour": "red"}`})
+	var validationErr *BodyValidationError
+	if !errors.As(err, &validationErr) || validationErr.Errors[0].Code != FieldErrorInvalidBody {
+		t.Errorf("expected a %s error for an unknown field, got %v", FieldErrorInvalidBody, err)
+	}
+}
@@ -0,0 +1,352 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldErrorInvalidBytes is the FieldError.Code of a bytes value that does not decode in its
+// declared encoding, or an encoding given for a field that is not bytes
+const FieldErrorInvalidBytes = "invalid_bytes"
+
+// FileResolver loads the content of a file uploaded with a saved request, referenced by a
+// BytesFile body field of that request
+type FileResolver func(collectionID, requestID, fileID string) ([]byte, error)
+
+// fileOwner is the saved request whose uploaded files a body may reference
+type fileOwner struct {
+	collectionID string
+	requestID    string
+}
+
+// SetFileResolver sets how BytesFile body fields are loaded; without one they fail validation
+func (s *Service) SetFileResolver(resolver FileResolver) {
+	s.files = resolver
+}
+
+// hasBytesEncoding reports whether a field carries a value to decode in its encoding
+func hasBytesEncoding(field types.BodyField) bool {
+	return field.Encoding != "" && field.Value != nil &&
+		field.Presence != types.PresenceUnset && field.Presence != types.PresenceDefault
+}
+
+// decodeBytes checks a field with an encoding against the bytes field it names and records
+// its value as the base64 protojson expects
+func (v *bodyValidator) decodeBytes(field types.BodyField) (string, bool) {
+	fd, msg := v.leafField(field.Path)
+	if fd == nil {
+		v.fail(field.Path, FieldErrorInvalidBytes, "%s", msg)
+		return "", false
+	}
+	if !isBytesField(fd) {
+		v.fail(field.Path, FieldErrorInvalidBytes, "encoding %s applies to bytes fields, not %s field %s", field.Encoding, fd.Kind(), fd.Name())
+		return "", false
+	}
+	text, ok := field.Value.(string)
+	if !ok {
+		v.fail(field.Path, FieldErrorInvalidBytes, "expected a string for %s bytes, got %s", field.Encoding, describeValue(field.Value))
+		return "", false
+	}
+	data, err := v.svc.decodeBytesValue(field.Encoding, text, v.owner)
+	if err != nil {
+		v.fail(field.Path, FieldErrorInvalidBytes, "%v", err)
+		return "", false
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	v.bytes[field.Path] = encoded
+	return encoded, true
+}
+
+// leafField resolves the field a dot-path ends on. Indexed paths resolve to the repeated
+// field and map entries to the map's value field.
+func (v *bodyValidator) leafField(path string) (protoreflect.FieldDescriptor, string) {
+	prefix, name, index, msg := splitField(path)
+	if msg != "" {
+		return nil, msg
+	}
+	md, msg := v.messageAt(prefix)
+	if md == nil {
+		// The path may end on a map entry such as "blobs.avatar"
+		mapPrefix, mapName := splitOneofKey(prefix)
+		if parent, _ := v.messageAt(mapPrefix); parent != nil && prefix != "" && index == nil {
+			if fd := lookupField(parent, mapName); fd != nil && fd.IsMap() {
+				return fd.MapValue(), ""
+			}
+		}
+		return nil, msg
+	}
+	if md.FullName() == anyTypeName {
+		packed, ok := v.anyTypes[prefix]
+		if !ok {
+			return nil, fmt.Sprintf("set %s before the fields of %s", joinPath(prefix, anyTypeKey), anyTypeName)
+		}
+		md = packed
+	}
+	fd := lookupField(md, name)
+	if fd == nil {
+		return nil, fmt.Sprintf("%s has no field %q", md.FullName(), name)
+	}
+	return fd, ""
+}
+
+// decodeBytesValue turns text in the given encoding into raw bytes. Files are only looked
+// up among those of owner.
+func (s *Service) decodeBytesValue(encoding, text string, owner fileOwner) ([]byte, error) {
+	switch encoding {
+	case "", types.BytesBase64:
+		return decodeBase64(text)
+	case types.BytesHex:
+		cleaned := strings.NewReplacer(" ", "", ":", "", "\n", "", "\t", "").Replace(strings.TrimSpace(text))
+		cleaned = strings.TrimPrefix(strings.TrimPrefix(cleaned, "0x"), "0X")
+		data, err := hex.DecodeString(cleaned)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex: %v", err)
+		}
+		return data, nil
+	case types.BytesUTF8:
+		return []byte(text), nil
+	case types.BytesFile:
+		if owner.requestID == "" {
+			return nil, fmt.Errorf("file encoding needs a saved request; %q was not loaded", text)
+		}
+		if s.files == nil {
+			return nil, fmt.Errorf("uploaded files are not available")
+		}
+		data, err := s.files(owner.collectionID, owner.requestID, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load file %q: %v", text, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown bytes encoding %q (want %s, %s, %s or %s)", encoding, types.BytesBase64, types.BytesHex, types.BytesUTF8, types.BytesFile)
+}
+
+// decodeBase64 accepts standard and URL-safe base64, with or without padding
+func decodeBase64(text string) ([]byte, error) {
+	text = strings.TrimRight(strings.TrimSpace(text), "=")
+	if strings.ContainsAny(text, "-_") {
+		if data, err := base64.RawURLEncoding.DecodeString(text); err == nil {
+			return data, nil
+		}
+	}
+	data, err := base64.RawStdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %v", err)
+	}
+	return data, nil
+}
+
+// applyBytesEncodings swaps the values of encoded bytes fields for their base64 form
+func applyBytesEncodings(fields []types.BodyField, encoded map[string]string) []types.BodyField {
+	if len(encoded) == 0 {
+		return fields
+	}
+	out := make([]types.BodyField, len(fields))
+	for i, field := range fields {
+		if value, ok := encoded[field.Path]; ok && hasBytesEncoding(field) {
+			field.Value = value
+		}
+		out[i] = field
+	}
+	return out
+}
+
+// isBytesField reports whether fd holds bytes, directly or through a BytesValue wrapper
+func isBytesField(fd protoreflect.FieldDescriptor) bool {
+	if fd.Kind() == protoreflect.BytesKind {
+		return true
+	}
+	return fd.Message() != nil && fd.Message().FullName() == bytesValueTypeName
+}
+
+// renderBytes re-renders the base64 bytes fields of a decoded JSON message as chosen by
+// display. Field order and everything else are kept; UTF-8 display keeps base64 for bytes
+// that are not valid UTF-8.
+func (s *Service) renderBytes(messageType, decoded string, display types.BytesDisplay) string {
+	if len(display) == 0 || s.registry == nil {
+		return decoded
+	}
+	md, err := s.registry.GetMessageDescriptor(messageType)
+	if err != nil {
+		return decoded
+	}
+	dec := json.NewDecoder(strings.NewReader(decoded))
+	dec.UseNumber()
+	root, err := readOrderedJSON(dec)
+	if err != nil {
+		return decoded
+	}
+	s.renderBytesIn(md, root, "", display)
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return decoded
+	}
+	return string(out)
+}
+
+func (s *Service) renderBytesIn(md protoreflect.MessageDescriptor, node interface{}, path string, display types.BytesDisplay) {
+	obj, ok := node.(*orderedObject)
+	if !ok {
+		return
+	}
+	if md.FullName() == anyTypeName {
+		typeURL, _ := obj.get(anyTypeKey).(string)
+		mt, err := s.typeResolver().FindMessageByURL(typeURL)
+		if err != nil {
+			return
+		}
+		md = mt.Descriptor()
+	}
+	for i, key := range obj.keys {
+		fd := lookupField(md, key)
+		if fd == nil {
+			continue
+		}
+		fieldPath := joinPath(path, fd.JSONName())
+		val := obj.values[i]
+		switch {
+		case fd.IsMap():
+			entries, ok := val.(*orderedObject)
+			if !ok {
+				continue
+			}
+			for j := range entries.values {
+				entries.values[j] = s.renderBytesValue(fd.MapValue(), entries.values[j], fieldPath, display)
+			}
+		case fd.IsList():
+			items, ok := val.([]interface{})
+			if !ok {
+				continue
+			}
+			for j := range items {
+				items[j] = s.renderBytesValue(fd, items[j], fieldPath, display)
+			}
+		default:
+			obj.values[i] = s.renderBytesValue(fd, val, fieldPath, display)
+		}
+	}
+}
+
+// renderBytesValue renders a single value of fd, recursing into messages
+func (s *Service) renderBytesValue(fd protoreflect.FieldDescriptor, val interface{}, path string, display types.BytesDisplay) interface{} {
+	if isBytesField(fd) {
+		text, ok := val.(string)
+		if !ok {
+			return val
+		}
+		mode, ok := display[path]
+		if !ok {
+			mode = display["*"]
+		}
+		return displayBytes(text, mode)
+	}
+	if fd.Message() != nil && !isWellKnownJSONType(string(fd.Message().FullName())) {
+		s.renderBytesIn(fd.Message(), val, path, display)
+	}
+	return val
+}
+
+// displayBytes converts a protojson base64 value to the display mode
+func displayBytes(text, mode string) string {
+	if mode == "" || mode == types.BytesBase64 {
+		return text
+	}
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return text
+	}
+	switch mode {
+	case types.BytesHex:
+		return hex.EncodeToString(data)
+	case types.BytesUTF8:
+		if utf8.Valid(data) {
+			return string(data)
+		}
+	}
+	return text
+}
+
+// orderedObject is a decoded JSON object that keeps its key order when marshalled again
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *orderedObject) get(key string) interface{} {
+	for i, k := range o.keys {
+		if k == key {
+			return o.values[i]
+		}
+	}
+	return nil
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// readOrderedJSON reads one JSON value, decoding objects as *orderedObject
+func readOrderedJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := &orderedObject{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				val, err := readOrderedJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.keys = append(obj.keys, keyTok.(string))
+				obj.values = append(obj.values, val)
+			}
+			_, err := dec.Token()
+			return obj, err
+		case '[':
+			items := []interface{}{}
+			for dec.More() {
+				val, err := readOrderedJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, val)
+			}
+			_, err := dec.Token()
+			return items, err
+		}
+		return nil, fmt.Errorf("unexpected %v", t)
+	}
+	return tok, nil
+}
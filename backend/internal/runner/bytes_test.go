@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const blobProto = `syntax = "proto3";
+
+package blob.v1;
+
+import "google/protobuf/wrappers.proto";
+
+message Blob {
+  string name = 1;
+  bytes data = 2;
+  repeated bytes chunks = 3;
+  map<string, bytes> parts = 4;
+  google.protobuf.BytesValue wrapped = 5;
+  Blob child = 6;
+}
+`
+
+func TestBuildBody_DecodesBytesEncodings(t *testing.T) {
+	reg := setupRegistry(t, "blob.proto")
+	svc := NewService(reg)
+	svc.SetFileResolver(func(collectionID, requestID, id string) ([]byte, error) {
+		if collectionID != "c-1" || requestID != "r-1" || id != "f-1" {
+			return nil, fmt.Errorf("no file %s in request %s", id, requestID)
+		}
+		return []byte{0x00, 0xff}, nil
+	})
+
+	body, _, err := svc.buildBody("blob.v1.Blob", []types.BodyField{
+		{Path: "data", Value: "0xDE:AD be ef", Encoding: types.BytesHex},
+		{Path: "chunks[0]", Value: "héllo", Encoding: types.BytesUTF8},
+		{Path: "chunks[1]", Value: "-_8", Encoding: types.BytesBase64},
+		{Path: "parts.avatar", Value: "f-1", Encoding: types.BytesFile},
+		{Path: "wrapped", Value: "ok", Encoding: types.BytesUTF8},
+		{Path: "child.data", Value: "AQI=", Encoding: types.BytesBase64},
+	}, nil, fileOwner{collectionID: "c-1", requestID: "r-1"})
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+
+	md, _ := reg.GetMessageDescriptor("blob.v1.Blob")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	fields := md.Fields()
+	chunks := msg.Get(fields.ByName("chunks")).List()
+	parts := msg.Get(fields.ByName("parts")).Map()
+	wrapped := msg.Get(fields.ByName("wrapped")).Message()
+	child := msg.Get(fields.ByName("child")).Message()
+
+	checks := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"data", msg.Get(fields.ByName("data")).Bytes(), []byte{0xde, 0xad, 0xbe, 0xef}},
+		{"chunks[0]", chunks.Get(0).Bytes(), []byte("héllo")},
+		{"chunks[1]", chunks.Get(1).Bytes(), []byte{0xfb, 0xff}},
+		{"parts.avatar", parts.Get(protoreflect.ValueOfString("avatar").MapKey()).Bytes(), []byte{0x00, 0xff}},
+		{"wrapped", wrapped.Get(wrapped.Descriptor().Fields().ByName("value")).Bytes(), []byte("ok")},
+		{"child.data", child.Get(fields.ByName("data")).Bytes(), []byte{0x01, 0x02}},
+	}
+	for _, c := range checks {
+		if string(c.got) != string(c.want) {
+			t.Errorf("%s: expected %x, got %x", c.name, c.want, c.got)
+		}
+	}
+}
+
+func TestValidateBody_BytesEncodings(t *testing.T) {
+	svc := NewService(setupRegistry(t, "blob.proto"))
+
+	fieldErrors, err := svc.ValidateBody("blob.v1.Blob", []types.BodyField{
+		{Path: "data", Value: "xyz", Encoding: types.BytesHex},
+		{Path: "name", Value: "n", Encoding: types.BytesUTF8},
+		{Path: "chunks[0]", Value: "f-1", Encoding: types.BytesFile}, // no saved request
+		{Path: "child.data", Value: "AQI=", Encoding: "base32"},
+		{Path: "wrapped", Value: 42, Encoding: types.BytesUTF8},
+		{Path: "parts.ok", Value: "ok", Encoding: types.BytesUTF8},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ValidateBody failed: %v", err)
+	}
+
+	wantPaths := []string{"data", "name", "chunks[0]", "child.data", "wrapped"}
+	if len(fieldErrors) != len(wantPaths) {
+		t.Fatalf("expected %d errors, got %d: %+v", len(wantPaths), len(fieldErrors), fieldErrors)
+	}
+	for i, path := range wantPaths {
+		if fieldErrors[i].Path != path || fieldErrors[i].Code != FieldErrorInvalidBytes {
+			t.Errorf("error %d: expected %s at %q, got %s at %q (%s)", i, FieldErrorInvalidBytes, path, fieldErrors[i].Code, fieldErrors[i].Path, fieldErrors[i].Message)
+		}
+	}
+}
+
+func TestProcessResponse_BytesDisplay(t *testing.T) {
+	reg := setupRegistry(t, "blob.proto")
+	svc := NewService(reg)
+
+	md, _ := reg.GetMessageDescriptor("blob.v1.Blob")
+	msg := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	msg.Set(fields.ByName("name"), protoreflect.ValueOfString("report"))
+	msg.Set(fields.ByName("data"), protoreflect.ValueOfBytes([]byte("plain text")))
+	chunks := msg.Mutable(fields.ByName("chunks")).List()
+	chunks.Append(protoreflect.ValueOfBytes([]byte{0xca, 0xfe}))
+	child := msg.Mutable(fields.ByName("child")).Message()
+	child.Set(fields.ByName("data"), protoreflect.ValueOfBytes([]byte{0xff}))
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+
+	res, err := svc.processResponse(&ResponseContext{
+		Status:      200,
+		Body:        payload,
+		ContentType: "application/x-protobuf",
+	}, responseTypeRules{
+		Success: "blob.v1.Blob",
+		BytesDisplay: types.BytesDisplay{
+			"data":       types.BytesUTF8,
+			"child.data": types.BytesUTF8, // not valid UTF-8, so stays base64
+			"*":          types.BytesHex,
+		},
+	})
+	if err != nil {
+		t.Fatalf("processResponse failed: %v", err)
+	}
+
+	decoded := collapseSpaces(res.Decoded)
+	for _, want := range []string{`"data": "plain text"`, `"chunks": [ "cafe" ]`, `"data": "/w=="`} {
+		if !strings.Contains(decoded, want) {
+			t.Errorf("expected decoded output to contain %s, got:\n%s", want, res.Decoded)
+		}
+	}
+	if strings.Index(decoded, `"name"`) > strings.Index(decoded, `"chunks"`) {
+		t.Errorf("expected field order to be kept, got:\n%s", res.Decoded)
+	}
+}
+
+func TestBuildRequestContext_EncodedBytesStayText(t *testing.T) {
+	reg := setupRegistry(t, "blob.proto")
+	svc := NewService(reg)
+
+	ctx, err := svc.buildRequestContext(&RunReq{
+		Method:       "POST",
+		URL:          "http://localhost/blobs",
+		ProtoMessage: "blob.v1.Blob",
+		Body:         []types.BodyField{{Path: "data", Value: "{{digest}}", Encoding: types.BytesHex}},
+		Variables:    map[string]string{"digest": "1234"},
+	})
+	if err != nil {
+		t.Fatalf("buildRequestContext failed: %v", err)
+	}
+	md, _ := reg.GetMessageDescriptor("blob.v1.Blob")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(ctx.Body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if got := msg.Get(md.Fields().ByName("data")).Bytes(); string(got) != "\x12\x34" {
+		t.Errorf("expected bytes 1234, got %x", got)
+	}
+}
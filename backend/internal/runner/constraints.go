@@ -0,0 +1,316 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/datahopper/backend/internal/registry"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// FieldErrorConstraint is the FieldError.Code of a buf.validate / validate.rules violation
+const FieldErrorConstraint = "constraint_violation"
+
+var (
+	hostnameRegex = regexp.MustCompile(`^(?i:[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)(\.(?i:[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?))*$`)
+	uuidRegex     = regexp.MustCompile(`^(?i:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+)
+
+// constraintPatterns caches compiled pattern rules by source
+var constraintPatterns sync.Map
+
+// checkEncodedConstraints decodes a payload as messageType and checks its validation rules
+func (s *Service) checkEncodedConstraints(messageType string, payload []byte) ([]FieldError, error) {
+	md, err := s.resolveMessageType(messageType)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", messageType, err)
+	}
+	violations := []FieldError{}
+	checkMessageConstraints(msg, "", &violations)
+	return violations, nil
+}
+
+// checkMessageConstraints walks msg and appends a FieldError for every field that breaks
+// its buf.validate or validate.rules options. Paths use dot-path notation with JSON names.
+func checkMessageConstraints(msg protoreflect.Message, prefix string, out *[]FieldError) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := joinPath(prefix, fd.JSONName())
+		c := registry.FieldConstraints(fd)
+
+		if !msg.Has(fd) {
+			if c != nil && c.Required != nil && *c.Required {
+				*out = append(*out, FieldError{Path: path, Code: FieldErrorConstraint, Message: "value is required"})
+				continue
+			}
+			// Unset fields with presence are skipped; implicit scalars, lists and maps are
+			// checked with their zero value as protovalidate does
+			if c == nil || fd.HasPresence() {
+				continue
+			}
+		}
+		value := msg.Get(fd)
+
+		switch {
+		case fd.IsList():
+			list := value.List()
+			if c != nil {
+				checkCount(path, "items", list.Len(), c, out)
+				if c.Unique && !uniqueList(list) {
+					*out = append(*out, FieldError{Path: path, Code: FieldErrorConstraint, Message: "items must be unique"})
+				}
+			}
+			for idx := 0; idx < list.Len(); idx++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, idx)
+				if fd.Message() != nil {
+					checkMessageConstraints(list.Get(idx).Message(), itemPath, out)
+				} else if c != nil && c.Items != nil {
+					checkScalarConstraints(itemPath, fd, list.Get(idx), c.Items, out)
+				}
+			}
+		case fd.IsMap():
+			if c != nil {
+				checkCount(path, "pairs", value.Map().Len(), c, out)
+			}
+			if fd.MapValue().Message() != nil {
+				value.Map().Range(func(key protoreflect.MapKey, mv protoreflect.Value) bool {
+					checkMessageConstraints(mv.Message(), joinPath(path, key.String()), out)
+					return true
+				})
+			}
+		case fd.Message() != nil:
+			checkMessageConstraints(value.Message(), path, out)
+		case c != nil:
+			checkScalarConstraints(path, fd, value, c, out)
+		}
+	}
+}
+
+// checkCount checks MinItems and MaxItems against the number of items or pairs
+func checkCount(path, noun string, n int, c *registry.ValidationConstraints, out *[]FieldError) {
+	if c.MinItems != nil && n < int(*c.MinItems) {
+		*out = append(*out, FieldError{Path: path, Code: FieldErrorConstraint, Message: fmt.Sprintf("must have at least %d %s, got %d", *c.MinItems, noun, n)})
+	}
+	if c.MaxItems != nil && n > int(*c.MaxItems) {
+		*out = append(*out, FieldError{Path: path, Code: FieldErrorConstraint, Message: fmt.Sprintf("must have at most %d %s, got %d", *c.MaxItems, noun, n)})
+	}
+}
+
+// checkScalarConstraints checks one scalar or enum value
+func checkScalarConstraints(path string, fd protoreflect.FieldDescriptor, value protoreflect.Value, c *registry.ValidationConstraints, out *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*out = append(*out, FieldError{Path: path, Code: FieldErrorConstraint, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		str := value.String()
+		if n := utf8.RuneCountInString(str); c.MinLen != nil && n < int(*c.MinLen) {
+			fail("must be at least %d characters, got %d", *c.MinLen, n)
+		} else if c.MaxLen != nil && n > int(*c.MaxLen) {
+			fail("must be at most %d characters, got %d", *c.MaxLen, n)
+		}
+		if c.Pattern != nil && !matchesPattern(*c.Pattern, str) {
+			fail("must match pattern %q", *c.Pattern)
+		}
+		if c.Prefix != nil && !strings.HasPrefix(str, *c.Prefix) {
+			fail("must start with %q", *c.Prefix)
+		}
+		if c.Suffix != nil && !strings.HasSuffix(str, *c.Suffix) {
+			fail("must end with %q", *c.Suffix)
+		}
+		if c.Contains != nil && !strings.Contains(str, *c.Contains) {
+			fail("must contain %q", *c.Contains)
+		}
+		if c.Format != nil && !matchesFormat(*c.Format, str) {
+			fail("must be a valid %s", *c.Format)
+		}
+		checkMembership(str, c, fail)
+
+	case protoreflect.BytesKind:
+		b := value.Bytes()
+		if c.MinLen != nil && len(b) < int(*c.MinLen) {
+			fail("must be at least %d bytes, got %d", *c.MinLen, len(b))
+		} else if c.MaxLen != nil && len(b) > int(*c.MaxLen) {
+			fail("must be at most %d bytes, got %d", *c.MaxLen, len(b))
+		}
+		if c.Pattern != nil && !matchesPattern(*c.Pattern, string(b)) {
+			fail("must match pattern %q", *c.Pattern)
+		}
+
+	case protoreflect.EnumKind:
+		n := value.Enum()
+		if c.DefinedOnly && fd.Enum().Values().ByNumber(n) == nil {
+			fail("%d is not a defined value of %s", n, fd.Enum().FullName())
+		}
+		checkMembership(strconv.FormatInt(int64(n), 10), c, fail)
+
+	case protoreflect.BoolKind:
+		checkMembership(strconv.FormatBool(value.Bool()), c, fail)
+
+	default:
+		_, text := numericValue(fd.Kind(), value)
+		if c.Min != nil {
+			if order, ok := compareBound(fd.Kind(), value, *c.Min, c.ExactMin); ok && (order < 0 || c.ExclusiveMin && order == 0) {
+				fail("must be %s %s, got %s", boundWord(c.ExclusiveMin, "greater than"), boundText(*c.Min, c.ExactMin), text)
+			}
+		}
+		if c.Max != nil {
+			if order, ok := compareBound(fd.Kind(), value, *c.Max, c.ExactMax); ok && (order > 0 || c.ExclusiveMax && order == 0) {
+				fail("must be %s %s, got %s", boundWord(c.ExclusiveMax, "less than"), boundText(*c.Max, c.ExactMax), text)
+			}
+		}
+		checkMembership(text, c, fail)
+	}
+}
+
+// checkMembership checks In and NotIn, which hold values in their string form
+func checkMembership(text string, c *registry.ValidationConstraints, fail func(string, ...interface{})) {
+	if len(c.In) > 0 && !containsString(c.In, text) {
+		fail("must be one of [%s], got %s", strings.Join(c.In, ", "), text)
+	}
+	if containsString(c.NotIn, text) {
+		fail("must not be one of [%s]", strings.Join(c.NotIn, ", "))
+	}
+}
+
+// numericValue returns a numeric field value as float64 for range checks and in the
+// string form used by In and NotIn
+func numericValue(kind protoreflect.Kind, value protoreflect.Value) (float64, string) {
+	switch kind {
+	case protoreflect.FloatKind:
+		f := value.Float()
+		return f, strconv.FormatFloat(f, 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		f := value.Float()
+		return f, strconv.FormatFloat(f, 'g', -1, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		u := value.Uint()
+		return float64(u), strconv.FormatUint(u, 10)
+	default:
+		i := value.Int()
+		return float64(i), strconv.FormatInt(i, 10)
+	}
+}
+
+// compareBound compares a numeric field value with a bound like cmp.Compare; ok is false
+// for NaN, which no bound rejects. 64-bit integers are compared as int64 or uint64 against
+// the exact bound when the rule has one, since float64 cannot hold them all.
+func compareBound(kind protoreflect.Kind, value protoreflect.Value, bound float64, exact string) (order int, ok bool) {
+	switch kind {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if b, err := strconv.ParseInt(exact, 10, 64); err == nil {
+			return cmp.Compare(value.Int(), b), true
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if b, err := strconv.ParseUint(exact, 10, 64); err == nil {
+			return cmp.Compare(value.Uint(), b), true
+		}
+	}
+	num, _ := numericValue(kind, value)
+	if math.IsNaN(num) {
+		return 0, false
+	}
+	return cmp.Compare(num, bound), true
+}
+
+// boundText renders a bound for messages, exactly when the rule gave it as written
+func boundText(bound float64, exact string) string {
+	if exact != "" {
+		return exact
+	}
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+func boundWord(exclusive bool, word string) string {
+	if exclusive {
+		return word
+	}
+	return word + " or equal to"
+}
+
+// matchesPattern reports whether s matches an RE2 pattern; invalid patterns never match
+func matchesPattern(pattern, s string) bool {
+	if cached, ok := constraintPatterns.Load(pattern); ok {
+		re, _ := cached.(*regexp.Regexp)
+		return re != nil && re.MatchString(s)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		constraintPatterns.Store(pattern, (*regexp.Regexp)(nil))
+		return false
+	}
+	constraintPatterns.Store(pattern, re)
+	return re.MatchString(s)
+}
+
+// matchesFormat checks the well-known string formats of StringRules
+func matchesFormat(format, s string) bool {
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "hostname":
+		return len(s) <= 253 && hostnameRegex.MatchString(s)
+	case "ip":
+		return net.ParseIP(s) != nil
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "uri_ref":
+		_, err := url.Parse(s)
+		return err == nil
+	case "uuid":
+		return uuidRegex.MatchString(s)
+	}
+	return true
+}
+
+// uniqueList reports whether all scalar items of a list are distinct; message lists pass
+func uniqueList(list protoreflect.List) bool {
+	seen := make(map[interface{}]bool, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		key := list.Get(i).Interface()
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		if _, isMessage := key.(protoreflect.Message); isMessage {
+			return true
+		}
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
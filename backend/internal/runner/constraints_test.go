@@ -0,0 +1,228 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/datahopper/backend/internal/registry"
+	"github.com/datahopper/backend/internal/types"
+)
+
+// bufValidateProto is a trimmed copy of buf/validate/validate.proto that keeps the
+// upstream field numbers
+const bufValidateProto = `syntax = "proto2";
+
+package buf.validate;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+  optional FieldRules field = 1159;
+}
+
+message FieldRules {
+  optional bool required = 25;
+  oneof type {
+    Int32Rules int32 = 3;
+    Int64Rules int64 = 4;
+    UInt64Rules uint64 = 6;
+    StringRules string = 14;
+    RepeatedRules repeated = 18;
+  }
+}
+
+message Int32Rules {
+  optional int32 gte = 5;
+}
+
+message Int64Rules {
+  optional int64 lt = 2;
+}
+
+message UInt64Rules {
+  optional uint64 lte = 3;
+  optional uint64 gt = 4;
+}
+
+message StringRules {
+  optional uint64 min_len = 2;
+  optional bool email = 12;
+}
+
+message RepeatedRules {
+  optional uint64 max_items = 2;
+  optional FieldRules items = 4;
+}
+`
+
+const memberProto = `syntax = "proto3";
+
+package club.v1;
+
+import "buf_validate.proto";
+
+message Card {
+  string holder = 1 [(buf.validate.field).string.min_len = 2];
+}
+
+message Member {
+  string email = 1 [(buf.validate.field).string.email = true];
+  int32 age = 2 [(buf.validate.field).int32.gte = 18];
+  repeated string nicknames = 3 [(buf.validate.field).repeated = {max_items: 2, items: {string: {min_len: 3}}}];
+  Card card = 4 [(buf.validate.field).required = true];
+  repeated Card extras = 5;
+}
+
+message Ledger {
+  int64 balance = 1 [(buf.validate.field).int64.lt = 9007199254740993];
+  uint64 quota = 2 [(buf.validate.field).uint64.gt = 18446744073709551614];
+  uint64 allowance = 3 [(buf.validate.field).uint64.lte = 18014398509481985];
+}
+`
+
+var invalidMemberBody = []types.BodyField{
+	{Path: "email", Value: "not-an-email"},
+	{Path: "age", Value: 16},
+	{Path: "nicknames[0]", Value: "Al"},
+	{Path: "nicknames[1]", Value: "Alex"},
+	{Path: "nicknames[2]", Value: "Alexander"},
+	{Path: "extras[0].holder", Value: "X"},
+}
+
+func TestValidateRun_EnforceConstraints(t *testing.T) {
+	svc := NewService(setupRegistry(t, "buf_validate.proto", "member.proto"))
+	req := &RunReq{Method: "POST", URL: "http://example.com", ProtoMessage: "club.v1.Member", Body: invalidMemberBody}
+
+	// Rules are only checked on request
+	fieldErrors, _, err := svc.ValidateRun(req)
+	if err != nil || len(fieldErrors) != 0 {
+		t.Fatalf("expected no errors without enforcement, got %+v / %v", fieldErrors, err)
+	}
+
+	req.EnforceConstraints = true
+	fieldErrors, _, err = svc.ValidateRun(req)
+	if err != nil {
+		t.Fatalf("ValidateRun failed: %v", err)
+	}
+	want := []string{"email", "age", "nicknames", "nicknames[0]", "card", "extras[0].holder"}
+	if len(fieldErrors) != len(want) {
+		t.Fatalf("expected %d violations, got %d: %+v", len(want), len(fieldErrors), fieldErrors)
+	}
+	for i, path := range want {
+		if fieldErrors[i].Path != path || fieldErrors[i].Code != FieldErrorConstraint {
+			t.Errorf("violation %d: expected %s at %q, got %s at %q (%s)", i, FieldErrorConstraint, path, fieldErrors[i].Code, fieldErrors[i].Path, fieldErrors[i].Message)
+		}
+	}
+}
+
+func TestValidateRun_64BitBounds(t *testing.T) {
+	svc := NewService(setupRegistry(t, "buf_validate.proto", "member.proto"))
+
+	// Each value rounds to the same float64 as its bound
+	tests := []struct {
+		balance, quota string
+		invalid        string
+	}{
+		{"9007199254740992", "18446744073709551615", ""},
+		{"9007199254740993", "18446744073709551615", "balance"},
+		{"9007199254740992", "18446744073709551614", "quota"},
+	}
+	for _, tt := range tests {
+		fieldErrors, _, err := svc.ValidateRun(&RunReq{
+			ProtoMessage:       "club.v1.Ledger",
+			Body:               []types.BodyField{{Path: "balance", Value: tt.balance}, {Path: "quota", Value: tt.quota}},
+			EnforceConstraints: true,
+		})
+		if err != nil {
+			t.Fatalf("ValidateRun(%s, %s) failed: %v", tt.balance, tt.quota, err)
+		}
+		var got string
+		if len(fieldErrors) > 0 {
+			got = fieldErrors[0].Path
+		}
+		if len(fieldErrors) > 1 || got != tt.invalid {
+			t.Errorf("balance=%s quota=%s: expected violation at %q, got %+v", tt.balance, tt.quota, tt.invalid, fieldErrors)
+		}
+	}
+}
+
+func TestValidateRun_Uint64InclusiveBound(t *testing.T) {
+	svc := NewService(setupRegistry(t, "buf_validate.proto", "member.proto"))
+
+	// The bound is 2^54+1; it and the value past it both round to 2^54 as float64
+	for value, invalid := range map[string]bool{
+		"18014398509481985": false,
+		"18014398509481986": true,
+	} {
+		fieldErrors, _, err := svc.ValidateRun(&RunReq{
+			ProtoMessage:       "club.v1.Ledger",
+			Body:               []types.BodyField{{Path: "quota", Value: "18446744073709551615"}, {Path: "allowance", Value: value}},
+			EnforceConstraints: true,
+		})
+		if err != nil {
+			t.Fatalf("ValidateRun(%s) failed: %v", value, err)
+		}
+		if got := len(fieldErrors) == 1 && fieldErrors[0].Path == "allowance"; got != invalid || len(fieldErrors) > 1 {
+			t.Errorf("allowance=%s: expected violation %v, got %+v", value, invalid, fieldErrors)
+		}
+	}
+}
+
+func TestBuildRequestContext_RejectsConstraintViolations(t *testing.T) {
+	svc := NewService(setupRegistry(t, "buf_validate.proto", "member.proto"))
+
+	_, err := svc.buildRequestContext(&RunReq{
+		Method:             "POST",
+		URL:                "http://example.com",
+		ProtoMessage:       "club.v1.Member",
+		Body:               []types.BodyField{{Path: "email", Value: "a@example.com"}, {Path: "age", Value: 18}},
+		EnforceConstraints: true,
+	})
+	var validationErr *BodyValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a BodyValidationError, got %v", err)
+	}
+	if len(validationErr.Errors) != 1 || validationErr.Errors[0].Path != "card" {
+		t.Fatalf("expected only the missing card to be reported, got %+v", validationErr.Errors)
+	}
+}
+
+func TestProcessResponse_ReportsViolations(t *testing.T) {
+	svc := NewService(setupRegistry(t, "buf_validate.proto", "member.proto"))
+
+	// field 1: "x", field 2: varint 5, field 4: Card{holder: "Jo"}
+	body := []byte{0x0a, 0x01, 'x', 0x10, 0x05, 0x22, 0x04, 0x0a, 0x02, 'J', 'o'}
+	res, err := svc.processResponse(&ResponseContext{
+		Status:      200,
+		Body:        body,
+		ContentType: "application/x-protobuf",
+	}, responseTypeRules{Success: "club.v1.Member"})
+	if err != nil {
+		t.Fatalf("processResponse failed: %v", err)
+	}
+	if res.Decoded == "" {
+		t.Fatalf("expected the response to decode despite violations, got %q", res.DecodeError)
+	}
+	if len(res.Violations) != 2 || res.Violations[0].Path != "email" || res.Violations[1].Path != "age" {
+		t.Fatalf("expected email and age violations, got %+v", res.Violations)
+	}
+}
+
+func TestValidateRun_GeneratedExamplesSatisfyConstraints(t *testing.T) {
+	reg := setupRegistry(t, "buf_validate.proto", "member.proto")
+	svc := NewService(reg)
+
+	for _, mode := range []registry.ExampleMode{registry.ExampleMinimal, registry.ExampleFull} {
+		body, err := reg.GetSchemaService().GenerateExample("club.v1.Member", mode)
+		if err != nil {
+			t.Fatalf("GenerateExample(%s) failed: %v", mode, err)
+		}
+		fieldErrors, _, err := svc.ValidateRun(&RunReq{ProtoMessage: "club.v1.Member", Body: body, EnforceConstraints: true})
+		if err != nil {
+			t.Fatalf("ValidateRun(%s) failed: %v", mode, err)
+		}
+		if len(fieldErrors) != 0 {
+			t.Errorf("%s example %+v has violations: %+v", mode, body, fieldErrors)
+		}
+	}
+}
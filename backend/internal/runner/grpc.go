@@ -0,0 +1,260 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// runGRPC executes a unary gRPC call for the method named in req.ServiceMethod
+func (s *Service) runGRPC(req *RunReq) (*RunRes, error) {
+	resolved, method, err := s.resolveRPCMethod(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gRPC method: %w", err)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is streaming and cannot be executed as a unary call", req.ServiceMethod)
+	}
+
+	// Build request context using the method's input type
+	ctx, err := s.buildRequestContext(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request context: %w", err)
+	}
+
+	// Execute gRPC call
+	resp, err := s.executeGRPC(ctx, method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+
+	// Process response
+	result, err := s.processResponse(resp, responseRulesFor(resolved))
+	if err != nil {
+		return nil, fmt.Errorf("failed to process response: %w", err)
+	}
+	result.Warnings = ctx.Unresolved
+	result.FieldWarnings = ctx.FieldWarnings
+
+	return result, nil
+}
+
+// resolveRPCMethod looks up req.ServiceMethod in the registry and returns a copy of req
+// with ProtoMessage and ResponseType taken from the method's input and output types
+func (s *Service) resolveRPCMethod(req *RunReq) (*RunReq, protoreflect.MethodDescriptor, error) {
+	if s.registry == nil {
+		return nil, nil, fmt.Errorf("registry not configured")
+	}
+	if strings.TrimSpace(req.ServiceMethod) == "" {
+		return nil, nil, fmt.Errorf("serviceMethod is required for %s requests", req.Protocol)
+	}
+
+	method, err := s.registry.GetMethodDescriptor(req.ServiceMethod)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved := *req
+	resolved.ProtoMessage = string(method.Input().FullName())
+	resolved.ResponseType = string(method.Output().FullName())
+	return &resolved, method, nil
+}
+
+// executeGRPC invokes a unary gRPC method and captures status, headers and trailers
+func (s *Service) executeGRPC(ctx *RequestContext, method protoreflect.MethodDescriptor) (*ResponseContext, error) {
+	conn, err := s.grpcConn(ctx.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild the encoded body as a dynamic message of the method's input type
+	input := dynamicpb.NewMessage(method.Input())
+	if body, ok := ctx.Body.([]byte); ok {
+		if err := proto.Unmarshal(body, input); err != nil {
+			return nil, fmt.Errorf("failed to prepare gRPC request message: %w", err)
+		}
+	}
+	output := dynamicpb.NewMessage(method.Output())
+
+	callCtx, cancel := context.WithTimeout(context.Background(), time.Duration(ctx.TimeoutSeconds)*time.Second)
+	defer cancel()
+	callCtx = metadata.NewOutgoingContext(callCtx, outgoingMetadata(ctx.Headers))
+
+	s.logger.Debug().Str("method", grpcMethodPath(method)).Str("target", conn.Target()).Msg("Sending gRPC request")
+
+	var header, trailer metadata.MD
+	callErr := conn.Invoke(callCtx, grpcMethodPath(method), input, output, grpc.Header(&header), grpc.Trailer(&trailer))
+	st := status.Convert(callErr)
+
+	responseCtx := &ResponseContext{
+		Status:      httpStatusFromGRPCCode(st.Code()),
+		Headers:     metadataToMap(header),
+		Trailers:    metadataToMap(trailer),
+		ContentType: "application/grpc",
+		GRPCStatus:  grpcStatusFrom(st),
+	}
+	if ct := header.Get("content-type"); len(ct) > 0 {
+		responseCtx.ContentType = ct[0]
+	}
+
+	if callErr != nil {
+		responseCtx.ErrorDetails = st.Proto().GetDetails()
+	} else {
+		bodyBytes, err := proto.Marshal(output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal gRPC response: %w", err)
+		}
+		responseCtx.Body = bodyBytes
+	}
+
+	return responseCtx, nil
+}
+
+// grpcConn returns the client connection for a target URL, creating it on first use. Calls
+// to the same target share the connection, as HTTP runs share the service's client.
+func (s *Service) grpcConn(rawURL string) (*grpc.ClientConn, error) {
+	target, useTLS := parseGRPCTarget(rawURL)
+	if target == "" {
+		return nil, fmt.Errorf("gRPC target address is required")
+	}
+	key := target
+	if useTLS {
+		key = "tls:" + target
+	}
+
+	s.grpcMu.Lock()
+	defer s.grpcMu.Unlock()
+	if conn, ok := s.grpcConns[key]; ok {
+		return conn, nil
+	}
+	conn, err := dialGRPC(target, useTLS)
+	if err != nil {
+		return nil, err
+	}
+	s.grpcConns[key] = conn
+	return conn, nil
+}
+
+// dialGRPC creates a client connection for a target address. The connection is made when
+// the first call needs it.
+func dialGRPC(target string, useTLS bool) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for %s: %w", target, err)
+	}
+	return conn, nil
+}
+
+// parseGRPCTarget strips the scheme and any path from a URL, reporting whether TLS is implied
+func parseGRPCTarget(rawURL string) (string, bool) {
+	target := strings.TrimSpace(rawURL)
+	useTLS := false
+	for _, scheme := range []string{"grpcs://", "https://"} {
+		if strings.HasPrefix(target, scheme) {
+			target = strings.TrimPrefix(target, scheme)
+			useTLS = true
+		}
+	}
+	for _, scheme := range []string{"grpc://", "http://"} {
+		target = strings.TrimPrefix(target, scheme)
+	}
+	if idx := strings.Index(target, "/"); idx >= 0 {
+		target = target[:idx]
+	}
+	return target, useTLS
+}
+
+// grpcMethodPath returns the HTTP/2 path for a method, e.g. /pkg.Service/Method
+func grpcMethodPath(method protoreflect.MethodDescriptor) string {
+	return "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+}
+
+// outgoingMetadata converts request headers into gRPC metadata. Values of binary
+// (-bin) keys are base64-decoded when possible.
+func outgoingMetadata(headers map[string]string) metadata.MD {
+	md := metadata.MD{}
+	for key, value := range headers {
+		k := strings.ToLower(key)
+		if strings.HasSuffix(k, "-bin") {
+			if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+				value = string(decoded)
+			}
+		}
+		md.Append(k, value)
+	}
+	return md
+}
+
+// metadataToMap flattens gRPC metadata for display. Binary (-bin) values are base64-encoded.
+func metadataToMap(md metadata.MD) map[string]string {
+	out := make(map[string]string, len(md))
+	for key, values := range md {
+		rendered := make([]string, len(values))
+		for i, v := range values {
+			if strings.HasSuffix(key, "-bin") {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			rendered[i] = v
+		}
+		out[key] = strings.Join(rendered, ", ")
+	}
+	return out
+}
+
+// grpcStatusFrom converts a gRPC status into its API representation
+func grpcStatusFrom(st *status.Status) *GRPCStatus {
+	return &GRPCStatus{
+		Code:    int(st.Code()),
+		Name:    st.Code().String(),
+		Message: st.Message(),
+	}
+}
+
+// httpStatusFromGRPCCode maps a gRPC status code to the closest HTTP status
+func httpStatusFromGRPCCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,197 @@
+package runner
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/registry"
+	"github.com/datahopper/backend/internal/types"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const greeterProto = `syntax = "proto3";
+
+package greet.v1;
+
+message HelloRequest {
+  string name = 1;
+}
+
+message HelloReply {
+  string message = 1;
+}
+
+service Greeter {
+  rpc SayHello(HelloRequest) returns (HelloReply);
+}
+`
+
+// startGreeterServer starts an in-process gRPC server that answers SayHello using dynamic messages.
+// Requests with name "missing" fail with NOT_FOUND; "invalid" fails with INVALID_ARGUMENT
+// carrying a registered HelloReply detail and an unregistered one.
+func startGreeterServer(t *testing.T, reg *registry.Service) string {
+	t.Helper()
+	method, err := reg.GetMethodDescriptor("greet.v1.Greeter/SayHello")
+	if err != nil {
+		t.Fatalf("method lookup failed: %v", err)
+	}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		in := dynamicpb.NewMessage(method.Input())
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		name := in.Get(method.Input().Fields().ByName("name")).String()
+
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		_ = stream.SetHeader(metadata.Pairs("x-echo-token", strings.Join(md.Get("x-token"), ",")))
+		stream.SetTrailer(metadata.Pairs("x-served-by", "greeter"))
+
+		if name == "missing" {
+			return status.Error(codes.NotFound, "no such person")
+		}
+		if name == "invalid" {
+			return status.FromProto(invalidNameStatus(t, method.Output())).Err()
+		}
+
+		out := dynamicpb.NewMessage(method.Output())
+		out.Set(method.Output().Fields().ByName("message"), protoreflect.ValueOfString("Hello "+name))
+		return stream.SendMsg(out)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(handler))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+// invalidNameStatus builds an INVALID_ARGUMENT google.rpc.Status with two Any details
+func invalidNameStatus(t *testing.T, replyDesc protoreflect.MessageDescriptor) *spb.Status {
+	t.Helper()
+	reply := dynamicpb.NewMessage(replyDesc)
+	reply.Set(replyDesc.Fields().ByName("message"), protoreflect.ValueOfString("try another name"))
+	replyBytes, err := proto.Marshal(reply)
+	if err != nil {
+		t.Fatalf("failed to marshal detail: %v", err)
+	}
+	return &spb.Status{
+		Code:    int32(codes.InvalidArgument),
+		Message: "bad name",
+		Details: []*anypb.Any{
+			{TypeUrl: "type.googleapis.com/greet.v1.HelloReply", Value: replyBytes},
+			{TypeUrl: "type.googleapis.com/acme.v1.Unknown", Value: []byte{0x08, 0x01}},
+		},
+	}
+}
+
+func TestRunGRPC_UnaryCall(t *testing.T) {
+	reg := setupRegistry(t, "greet.proto")
+	addr := startGreeterServer(t, reg)
+	svc := NewService(reg)
+
+	res, err := svc.Run(&RunReq{
+		Method:         "POST",
+		URL:            "grpc://{{host}}",
+		Protocol:       ProtocolGRPC,
+		ServiceMethod:  "greet.v1.Greeter/SayHello",
+		Headers:        map[string]string{"X-Token": "abc"},
+		Body:           []types.BodyField{{Path: "name", Value: "Ada"}},
+		TimeoutSeconds: 5,
+		Variables:      map[string]string{"host": addr},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if res.Status != 200 {
+		t.Fatalf("expected status 200, got %d", res.Status)
+	}
+	if res.GRPCStatus == nil || res.GRPCStatus.Code != 0 {
+		t.Fatalf("expected OK grpc status, got %+v", res.GRPCStatus)
+	}
+	if !strings.Contains(res.Decoded, "Hello Ada") {
+		t.Fatalf("expected decoded reply, got %q (decodeError=%q)", res.Decoded, res.DecodeError)
+	}
+	if res.Headers["x-echo-token"] != "abc" {
+		t.Fatalf("expected header metadata to round-trip, got %v", res.Headers)
+	}
+	if res.Trailers["x-served-by"] != "greeter" {
+		t.Fatalf("expected trailer metadata, got %v", res.Trailers)
+	}
+}
+
+func TestRunGRPC_ErrorStatus(t *testing.T) {
+	reg := setupRegistry(t, "greet.proto")
+	addr := startGreeterServer(t, reg)
+	svc := NewService(reg)
+
+	res, err := svc.Run(&RunReq{
+		Method:         "POST",
+		URL:            addr,
+		Protocol:       ProtocolGRPC,
+		ServiceMethod:  "/greet.v1.Greeter/SayHello",
+		Body:           []types.BodyField{{Path: "name", Value: "missing"}},
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if res.Status != 404 {
+		t.Fatalf("expected status 404, got %d", res.Status)
+	}
+	if res.GRPCStatus == nil || res.GRPCStatus.Name != "NotFound" || res.GRPCStatus.Message != "no such person" {
+		t.Fatalf("unexpected grpc status: %+v", res.GRPCStatus)
+	}
+	if res.Decoded != "" {
+		t.Fatalf("expected no decoded body on error, got %q", res.Decoded)
+	}
+}
+
+func TestRunGRPC_UnknownMethod(t *testing.T) {
+	reg := setupRegistry(t, "greet.proto")
+	svc := NewService(reg)
+
+	_, err := svc.Run(&RunReq{
+		Method:        "POST",
+		URL:           "127.0.0.1:1",
+		Protocol:      ProtocolGRPC,
+		ServiceMethod: "greet.v1.Greeter/SayGoodbye",
+	})
+	if err == nil {
+		t.Fatal("expected error for unknown method")
+	}
+}
+
+func TestParseGRPCTarget(t *testing.T) {
+	tests := []struct {
+		in     string
+		target string
+		useTLS bool
+	}{
+		{"localhost:50051", "localhost:50051", false},
+		{"grpc://localhost:50051", "localhost:50051", false},
+		{"grpcs://api.example.com:443", "api.example.com:443", true},
+		{"https://api.example.com/ignored/path", "api.example.com", true},
+	}
+	for _, tt := range tests {
+		target, useTLS := parseGRPCTarget(tt.in)
+		if target != tt.target || useTLS != tt.useTLS {
+			t.Errorf("parseGRPCTarget(%q) = (%q, %v), want (%q, %v)", tt.in, target, useTLS, tt.target, tt.useTLS)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datahopper/backend/internal/dotpath"
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldErrorInvalidOneof is the FieldError.Code of a oneof selection that names an unknown
+// group or member; the error path is the selection key
+const FieldErrorInvalidOneof = "invalid_oneof_selection"
+
+// resolveOneofSelections checks every selection against the descriptor and records the
+// selected member per group key. Selections under an Any need its "@type" resolved first.
+func (v *bodyValidator) resolveOneofSelections(selections types.OneofSelections) {
+	for _, key := range sortedSelectionKeys(selections) {
+		member := selections[key]
+		prefix, groupName := splitOneofKey(key)
+
+		md, msg := v.messageAt(prefix)
+		if md == nil {
+			v.fail(key, FieldErrorInvalidOneof, "%s", msg)
+			continue
+		}
+		od := md.Oneofs().ByName(protoreflect.Name(groupName))
+		if od == nil || od.IsSynthetic() {
+			v.fail(key, FieldErrorInvalidOneof, "%s has no oneof %q", md.FullName(), groupName)
+			continue
+		}
+		fd := oneofMemberByName(od, member)
+		if fd == nil {
+			v.fail(key, FieldErrorInvalidOneof, "oneof %s has no member %q", od.Name(), member)
+			continue
+		}
+		v.selected[key] = fd.Name()
+	}
+}
+
+// messageAt walks a message path such as "payment", "items[0]" or "offices.42" from the
+// root and returns the message it ends on, or an explanation when it does not
+func (v *bodyValidator) messageAt(path string) (protoreflect.MessageDescriptor, string) {
+	cur := v.root
+	if path == "" {
+		return cur, ""
+	}
+	segs, msg := parseBodyPath(path)
+	if segs == nil {
+		return nil, msg
+	}
+	prefix := ""
+	for i := 0; i < len(segs); i++ {
+		if cur.FullName() == anyTypeName {
+			packed, ok := v.anyTypes[prefix]
+			if !ok {
+				return nil, fmt.Sprintf("set %s before selecting oneofs inside it", joinPath(prefix, anyTypeKey))
+			}
+			cur = packed
+		}
+
+		seg := segs[i]
+		if seg.Kind != dotpath.KeySegment {
+			return nil, fmt.Sprintf("invalid path segment %q: %s has no repeated field to index", seg.Text, cur.FullName())
+		}
+		index := indexAfter(segs, i)
+		fd := lookupField(cur, seg.Key)
+		switch {
+		case fd == nil:
+			return nil, fmt.Sprintf("%s has no field %q", cur.FullName(), seg.Key)
+		case fd.IsMap():
+			if index != nil || i+1 == len(segs) || fd.MapValue().Message() == nil {
+				return nil, fmt.Sprintf("map field %s needs a key and message values", fd.Name())
+			}
+			prefix = joinPath(joinPath(prefix, seg.Key), segs[i+1].Key)
+			cur = fd.MapValue().Message()
+			i++
+			continue
+		case fd.IsList() != (index != nil):
+			return nil, fmt.Sprintf("field %s needs an index exactly when it is repeated", fd.Name())
+		case fd.Message() == nil:
+			return nil, fmt.Sprintf("%s field %s has no oneofs", fd.Kind(), fd.Name())
+		}
+		prefix = joinPath(prefix, seg.Key)
+		if index != nil {
+			prefix += index.String()
+			i++
+		}
+		cur = fd.Message()
+	}
+	return cur, ""
+}
+
+// applyOneofSelections drops the members of selected oneofs that are not selected and sets
+// the selected member to its default value when the body leaves it out, so the oneof case is
+// always sent. prefix is the dot-path of m.
+func applyOneofSelections(md protoreflect.MessageDescriptor, m map[string]interface{}, prefix string, selections types.OneofSelections) {
+	for i := 0; i < md.Oneofs().Len(); i++ {
+		od := md.Oneofs().Get(i)
+		if od.IsSynthetic() {
+			continue
+		}
+		member, ok := selections[joinPath(prefix, string(od.Name()))]
+		if !ok {
+			continue
+		}
+		selected := oneofMemberByName(od, member)
+		if selected == nil {
+			continue
+		}
+		for j := 0; j < od.Fields().Len(); j++ {
+			if fd := od.Fields().Get(j); fd != selected {
+				delete(m, fd.JSONName())
+				delete(m, string(fd.Name()))
+			}
+		}
+		_, hasJSON := m[selected.JSONName()]
+		_, hasName := m[string(selected.Name())]
+		if !hasJSON && !hasName {
+			m[selected.JSONName()] = defaultJSONValue(selected)
+		}
+	}
+}
+
+// hasSelectionUnder reports whether any selection is for a oneof inside the message at path
+func hasSelectionUnder(selections types.OneofSelections, path string) bool {
+	for key := range selections {
+		if strings.HasPrefix(key, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// oneofMemberByName finds a member of od by proto or JSON name
+func oneofMemberByName(od protoreflect.OneofDescriptor, name string) protoreflect.FieldDescriptor {
+	for i := 0; i < od.Fields().Len(); i++ {
+		fd := od.Fields().Get(i)
+		if string(fd.Name()) == name || fd.JSONName() == name {
+			return fd
+		}
+	}
+	return nil
+}
+
+// splitOneofKey splits a selection key into the message path and the oneof name
+func splitOneofKey(key string) (string, string) {
+	if prefix, name, ok := splitLastKey(key); ok {
+		return prefix, name
+	}
+	return "", key
+}
+
+// splitField splits a field path into the path of the message holding its last field, the
+// field name and the index or append segment after it, if any
+func splitField(path string) (string, string, *dotpath.Segment, string) {
+	segs, msg := parseBodyPath(path)
+	if segs == nil {
+		return "", "", nil, msg
+	}
+	last := len(segs) - 1
+	var index *dotpath.Segment
+	if segs[last].Kind != dotpath.KeySegment && last > 0 {
+		index = &segs[last]
+		last--
+	}
+	if segs[last].Kind != dotpath.KeySegment {
+		return "", "", nil, fmt.Sprintf("invalid path segment %q: expected a field name", segs[last].Text)
+	}
+	return dotpath.FormatPath(segs[:last]), segs[last].Key, index, ""
+}
+
+func sortedSelectionKeys(selections types.OneofSelections) []string {
+	keys := make([]string, 0, len(selections))
+	for k := range selections {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
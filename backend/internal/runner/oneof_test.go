@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const checkoutProto = `syntax = "proto3";
+
+package shop.v1;
+
+message Card {
+  string number = 1;
+}
+
+message Wallet {
+  string id = 1;
+}
+
+message Method {
+  oneof kind {
+    Card card = 1;
+    Wallet wallet = 2;
+    string cash_note = 3;
+  }
+}
+
+message Checkout {
+  oneof payer {
+    string email = 1;
+    string phone = 2;
+  }
+  Method primary = 3;
+  repeated Method fallbacks = 4;
+  map<string, Method> by_region = 5;
+}
+`
+
+// whichOneof returns the name of the member set in the named oneof of msg
+func whichOneof(msg protoreflect.Message, name string) string {
+	fd := msg.WhichOneof(msg.Descriptor().Oneofs().ByName(protoreflect.Name(name)))
+	if fd == nil {
+		return ""
+	}
+	return string(fd.Name())
+}
+
+func TestBuildBody_HonoursOneofSelections(t *testing.T) {
+	reg := setupRegistry(t, "checkout.proto")
+	svc := NewService(reg)
+
+	body, _, err := buildTestBody(svc, "shop.v1.Checkout", []types.BodyField{
+		{Path: "phone", Value: "555"},
+		{Path: "fallbacks[0].cashNote", Value: "exact change"},
+		{Path: "fallbacks[1].card.number", Value: "4242"},
+		{Path: "byRegion.eu.wallet.id", Value: "w-1"},
+	}, types.OneofSelections{
+		"payer":             "phone",
+		"primary.kind":      "wallet", // nothing set under primary, so it is sent empty
+		"fallbacks[0].kind": "cash_note",
+		"fallbacks[1].kind": "card",
+		"byRegion.eu.kind":  "wallet",
+	})
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+
+	md, _ := reg.GetMessageDescriptor("shop.v1.Checkout")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	primary := msg.Get(md.Fields().ByName("primary")).Message()
+	fallbacks := msg.Get(md.Fields().ByName("fallbacks")).List()
+	eu := msg.Get(md.Fields().ByName("by_region")).Map().Get(protoreflect.ValueOfString("eu").MapKey()).Message()
+
+	checks := []struct {
+		msg        protoreflect.Message
+		oneof      string
+		wantMember string
+	}{
+		{msg, "payer", "phone"},
+		{primary, "kind", "wallet"},
+		{fallbacks.Get(0).Message(), "kind", "cash_note"},
+		{fallbacks.Get(1).Message(), "kind", "card"},
+		{eu, "kind", "wallet"},
+	}
+	for i, c := range checks {
+		if got := whichOneof(c.msg, c.oneof); got != c.wantMember {
+			t.Errorf("check %d: expected %s set in %s, got %q", i, c.wantMember, c.oneof, got)
+		}
+	}
+}
+
+func TestValidateBody_OneofSelections(t *testing.T) {
+	svc := NewService(setupRegistry(t, "checkout.proto"))
+
+	fieldErrors, err := svc.ValidateBody("shop.v1.Checkout", []types.BodyField{
+		{Path: "email", Value: "a@example.com"},
+		{Path: "phone", Value: "555"},
+		{Path: "primary.card.number", Value: "4242"},
+	}, types.OneofSelections{
+		"payer":            "phone",
+		"primary.kind":     "wallet",
+		"primary.method":   "card",
+		"payer.kind":       "card",
+		"fallbacks.kind":   "card",
+		"byRegion.eu.kind": "coupon",
+	})
+	if err != nil {
+		t.Fatalf("ValidateBody failed: %v", err)
+	}
+
+	want := []FieldError{
+		{Path: "byRegion.eu.kind", Code: FieldErrorInvalidOneof},
+		{Path: "fallbacks.kind", Code: FieldErrorInvalidOneof},
+		{Path: "payer.kind", Code: FieldErrorInvalidOneof},
+		{Path: "primary.method", Code: FieldErrorInvalidOneof},
+		{Path: "email", Code: FieldErrorOneofConflict},
+		{Path: "primary.card.number", Code: FieldErrorOneofConflict},
+	}
+	if len(fieldErrors) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %+v", len(want), len(fieldErrors), fieldErrors)
+	}
+	for i, w := range want {
+		if fieldErrors[i].Path != w.Path || fieldErrors[i].Code != w.Code {
+			t.Errorf("error %d: expected %s at %q, got %s at %q (%s)", i, w.Code, w.Path, fieldErrors[i].Code, fieldErrors[i].Path, fieldErrors[i].Message)
+		}
+	}
+}
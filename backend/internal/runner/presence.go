@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldErrorPresence is the FieldError.Code of a BodyField presence mode that is unknown or
+// cannot be honoured for its field
+const FieldErrorPresence = "invalid_presence"
+
+// checkPresence resolves the field a presence mode applies to. Modes other than the
+// default one only make sense for singular fields with explicit presence.
+func (v *bodyValidator) checkPresence(field types.BodyField) {
+	switch field.Presence {
+	case "":
+		return
+	case types.PresenceSet, types.PresenceUnset, types.PresenceDefault:
+	default:
+		v.fail(field.Path, FieldErrorPresence, "unknown presence %q (want %s, %s or %s)", field.Presence, types.PresenceSet, types.PresenceUnset, types.PresenceDefault)
+		return
+	}
+
+	fd, msg := v.fieldAt(field.Path)
+	if fd == nil {
+		v.fail(field.Path, FieldErrorPresence, "%s", msg)
+		return
+	}
+	if field.Presence != types.PresenceUnset && !fd.HasPresence() {
+		v.fail(field.Path, FieldErrorPresence, "field %s has no presence, so its default value is never sent", fd.Name())
+		return
+	}
+	v.presence[field.Path] = fd
+}
+
+// fieldAt resolves the singular field a dot-path ends on
+func (v *bodyValidator) fieldAt(path string) (protoreflect.FieldDescriptor, string) {
+	prefix, name, index, msg := splitField(path)
+	if msg != "" {
+		return nil, msg
+	}
+	md, msg := v.messageAt(prefix)
+	if md == nil {
+		return nil, msg
+	}
+	if md.FullName() == anyTypeName {
+		packed, ok := v.anyTypes[prefix]
+		if !ok {
+			return nil, fmt.Sprintf("set %s before the fields of %s", joinPath(prefix, anyTypeKey), anyTypeName)
+		}
+		md = packed
+	}
+	fd := lookupField(md, name)
+	switch {
+	case fd == nil:
+		return nil, fmt.Sprintf("%s has no field %q", md.FullName(), name)
+	case fd.IsList() || fd.IsMap() || index != nil:
+		return nil, fmt.Sprintf("presence applies to singular fields, not %s", fd.Name())
+	}
+	return fd, ""
+}
+
+// applyPresence rewrites body fields for encoding: unset fields are dropped and fields
+// marked default, or set without a value, carry the field's default. presence holds the
+// fields resolved by checkPresence.
+func applyPresence(fields []types.BodyField, presence map[string]protoreflect.FieldDescriptor) []types.BodyField {
+	if len(presence) == 0 {
+		return fields
+	}
+	out := make([]types.BodyField, 0, len(fields))
+	for _, field := range fields {
+		if fd, ok := presence[field.Path]; ok {
+			value, send := presenceValue(fd, field)
+			if !send {
+				continue
+			}
+			field.Value = value
+		}
+		out = append(out, field)
+	}
+	return out
+}
+
+// presenceValue returns the value to encode for a field with a presence mode, and whether
+// the field is sent at all
+func presenceValue(fd protoreflect.FieldDescriptor, field types.BodyField) (interface{}, bool) {
+	switch field.Presence {
+	case types.PresenceUnset:
+		return nil, false
+	case types.PresenceDefault:
+		return defaultJSONValue(fd), true
+	case types.PresenceSet:
+		if isEmptyPresenceValue(fd, field.Value) {
+			return defaultJSONValue(fd), true
+		}
+	}
+	return field.Value, true
+}
+
+// isEmptyPresenceValue reports whether a value set on a presence field carries nothing to
+// send; empty strings are real values for string fields
+func isEmptyPresenceValue(fd protoreflect.FieldDescriptor, value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	s = strings.TrimSpace(s)
+	if fd.Kind() == protoreflect.StringKind {
+		return s == "null"
+	}
+	return s == "" || s == "null"
+}
+
+// defaultJSONValue returns the JSON value protojson reads as the default of fd, honouring
+// proto2 [default = ...] options
+func defaultJSONValue(fd protoreflect.FieldDescriptor) interface{} {
+	if md := fd.Message(); md != nil {
+		switch string(md.FullName()) {
+		case listValueTypeName:
+			return []interface{}{}
+		case valueTypeName:
+			return nil
+		case int32ValueTypeName, int64ValueTypeName, uint32ValueTypeName, uint64ValueTypeName,
+			floatValueTypeName, doubleValueTypeName, boolValueTypeName, stringValueTypeName, bytesValueTypeName:
+			return defaultJSONValue(md.Fields().ByName("value"))
+		}
+		return map[string]interface{}{}
+	}
+
+	return scalarJSONValue(fd, fd.Default())
+}
+
+// dropUnsetFields removes the fields marked unset
+func dropUnsetFields(fields []types.BodyField) []types.BodyField {
+	out := fields[:0:0]
+	for _, field := range fields {
+		if field.Presence != types.PresenceUnset {
+			out = append(out, field)
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const patchProto = `syntax = "proto3";
+
+package patch.v1;
+
+import "google/protobuf/wrappers.proto";
+
+message Settings {
+  optional int32 retries = 1;
+  optional string label = 2;
+  optional bool enabled = 3;
+  google.protobuf.Int64Value quota = 4;
+  int32 plain = 5;
+  repeated string tags = 6;
+}
+`
+
+const legacyProto = `syntax = "proto2";
+
+package patch.v1;
+
+message Legacy {
+  optional int32 limit = 1 [default = 25];
+  optional string mode = 2 [default = "fast"];
+}
+`
+
+func TestBuildBody_HonoursPresence(t *testing.T) {
+	reg := setupRegistry(t, "patch.proto", "legacy.proto")
+	svc := NewService(reg)
+
+	body, _, err := buildTestBody(svc, "patch.v1.Settings", []types.BodyField{
+		{Path: "retries", Value: "", Presence: types.PresenceSet},
+		{Path: "label", Value: "ignored", Presence: types.PresenceUnset},
+		{Path: "enabled", Value: true, Presence: types.PresenceDefault},
+		{Path: "quota", Presence: types.PresenceSet},
+		{Path: "plain", Value: 7},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+
+	md, _ := reg.GetMessageDescriptor("patch.v1.Settings")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	fields := md.Fields()
+	for name, want := range map[string]bool{"retries": true, "label": false, "enabled": true, "quota": true} {
+		fd := fields.ByName(protoreflect.Name(name))
+		if got := msg.Has(fd); got != want {
+			t.Errorf("field %s: present=%v, want %v", name, got, want)
+		}
+	}
+	if got := msg.Get(fields.ByName("retries")).Int(); got != 0 {
+		t.Errorf("expected retries to be sent as 0, got %d", got)
+	}
+	if msg.Get(fields.ByName("enabled")).Bool() {
+		t.Error("expected enabled to be sent as its default, not the value given")
+	}
+}
+
+func TestBuildBody_PresenceUsesProto2Defaults(t *testing.T) {
+	reg := setupRegistry(t, "patch.proto", "legacy.proto")
+	svc := NewService(reg)
+
+	body, _, err := buildTestBody(svc, "patch.v1.Legacy", []types.BodyField{
+		{Path: "limit", Presence: types.PresenceDefault},
+		{Path: "mode", Value: nil, Presence: types.PresenceSet},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+
+	md, _ := reg.GetMessageDescriptor("patch.v1.Legacy")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	limit, mode := md.Fields().ByName("limit"), md.Fields().ByName("mode")
+	if !msg.Has(limit) || msg.Get(limit).Int() != 25 {
+		t.Errorf("expected limit sent as 25, got present=%v value=%d", msg.Has(limit), msg.Get(limit).Int())
+	}
+	if !msg.Has(mode) || msg.Get(mode).String() != "fast" {
+		t.Errorf("expected mode sent as \"fast\", got present=%v value=%q", msg.Has(mode), msg.Get(mode).String())
+	}
+}
+
+func TestValidateBody_Presence(t *testing.T) {
+	svc := NewService(setupRegistry(t, "patch.proto", "legacy.proto"))
+
+	fieldErrors, err := svc.ValidateBody("patch.v1.Settings", []types.BodyField{
+		{Path: "retries", Value: "many", Presence: types.PresenceUnset}, // left out, so not type checked
+		{Path: "label", Value: "x", Presence: "maybe"},
+		{Path: "plain", Presence: types.PresenceDefault},
+		{Path: "plain", Presence: types.PresenceUnset},
+		{Path: "tags[0]", Presence: types.PresenceSet},
+		{Path: "missing", Presence: types.PresenceSet},
+		{Path: "enabled", Value: "yes", Presence: types.PresenceSet},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ValidateBody failed: %v", err)
+	}
+
+	want := []FieldError{
+		{Path: "label", Code: FieldErrorPresence},
+		{Path: "plain", Code: FieldErrorPresence},
+		{Path: "tags[0]", Code: FieldErrorPresence},
+		{Path: "missing", Code: FieldErrorPresence},
+		{Path: "enabled", Code: FieldErrorTypeMismatch},
+	}
+	if len(fieldErrors) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %+v", len(want), len(fieldErrors), fieldErrors)
+	}
+	for i, w := range want {
+		if fieldErrors[i].Path != w.Path || fieldErrors[i].Code != w.Code {
+			t.Errorf("error %d: expected %s at %q, got %s at %q (%s)", i, w.Code, w.Path, fieldErrors[i].Code, fieldErrors[i].Path, fieldErrors[i].Message)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/datahopper/backend/internal/types"
+)
+
+// messageTypeHeader lets a server name the message type of its response body
+const messageTypeHeader = "X-Message-Type"
+
+// messageTypeParams are the Content-Type parameters accepted as a message type hint,
+// e.g. application/x-protobuf; messageType=foo.Bar
+var messageTypeParams = []string{"messagetype", "proto"}
+
+// defaultStatusMatcher matches any status
+const defaultStatusMatcher = "default"
+
+// responseTypeRules selects the message type used to decode a response
+type responseTypeRules struct {
+	Success  string                      // Legacy type for 2xx responses
+	Error    string                      // Legacy type for everything else
+	Matchers []types.ResponseTypeMatcher // Ordered status matchers, checked before the legacy types
+
+	BytesDisplay types.BytesDisplay // How bytes fields of decoded messages are shown
+}
+
+// responseRulesFor collects the response type settings of a request
+func responseRulesFor(req *RunReq) responseTypeRules {
+	return responseTypeRules{
+		Success:  req.ResponseType,
+		Error:    req.ErrorResponseType,
+		Matchers: req.ResponseTypes,
+
+		BytesDisplay: req.BytesDisplay,
+	}
+}
+
+// selectType picks the decode type for a response: a hint sent by the server wins when known
+// reports it as a registered type, then the first matching status matcher, then ResponseType
+// for 2xx or ErrorResponseType otherwise
+func (r responseTypeRules) selectType(resp *ResponseContext, known func(string) bool) string {
+	if hint := messageTypeHint(resp); hint != "" && known(hint) {
+		return hint
+	}
+
+	for _, m := range r.Matchers {
+		if ok, err := matchStatus(m.Status, resp.Status); err == nil && ok {
+			return m.MessageType
+		}
+	}
+
+	if resp.Status < 200 || resp.Status >= 300 {
+		if r.Error != "" {
+			return r.Error
+		}
+	}
+	return r.Success
+}
+
+// messageTypeHint reads a message type named by the X-Message-Type header or a Content-Type parameter
+func messageTypeHint(resp *ResponseContext) string {
+	if hint, ok := lookupHeader(resp.Headers, messageTypeHeader); ok && strings.TrimSpace(hint) != "" {
+		return strings.Trim(strings.TrimSpace(hint), `"`)
+	}
+
+	if resp.ContentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(resp.ContentType)
+	if err != nil {
+		return ""
+	}
+	for _, name := range messageTypeParams {
+		if hint := strings.TrimSpace(params[name]); hint != "" {
+			return hint
+		}
+	}
+	return ""
+}
+
+// matchStatus reports whether status satisfies pattern: an exact code ("404"), a class
+// ("5xx"), an inclusive range ("400-499") or "default"
+func matchStatus(pattern string, status int) (bool, error) {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	switch {
+	case p == defaultStatusMatcher || p == "*":
+		return true, nil
+
+	case len(p) == 3 && strings.HasSuffix(p, "xx"):
+		class, err := strconv.Atoi(p[:1])
+		if err != nil || class < 1 || class > 5 {
+			return false, fmt.Errorf("invalid status class %q", pattern)
+		}
+		return status/100 == class, nil
+
+	case strings.Contains(p, "-"):
+		lo, hi, _ := strings.Cut(p, "-")
+		low, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		high, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || low > high {
+			return false, fmt.Errorf("invalid status range %q", pattern)
+		}
+		return status >= low && status <= high, nil
+	}
+
+	code, err := strconv.Atoi(p)
+	if err != nil {
+		return false, fmt.Errorf("invalid status matcher %q", pattern)
+	}
+	return status == code, nil
+}
+
+// ValidateResponseTypes rejects matchers with malformed patterns or no message type
+func ValidateResponseTypes(matchers []types.ResponseTypeMatcher) error {
+	for i, m := range matchers {
+		if _, err := matchStatus(m.Status, 0); err != nil {
+			return fmt.Errorf("responseTypes[%d]: %w", i, err)
+		}
+		if strings.TrimSpace(m.MessageType) == "" {
+			return fmt.Errorf("responseTypes[%d]: messageType is required", i)
+		}
+	}
+	return nil
+}
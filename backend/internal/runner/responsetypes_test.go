@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+)
+
+func TestMatchStatus(t *testing.T) {
+	tests := []struct {
+		pattern string
+		status  int
+		want    bool
+		wantErr bool
+	}{
+		{"404", 404, true, false},
+		{"404", 400, false, false},
+		{"4xx", 409, true, false},
+		{"5XX", 409, false, false},
+		{"400-499", 499, true, false},
+		{"500-503", 504, false, false},
+		{"default", 201, true, false},
+		{"abc", 200, false, true},
+		{"9xx", 200, false, true},
+		{"500-400", 450, false, true},
+	}
+	for _, tt := range tests {
+		got, err := matchStatus(tt.pattern, tt.status)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("matchStatus(%q, %d) = (%v, %v), want (%v, err=%v)", tt.pattern, tt.status, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestResponseTypeRules_SelectType(t *testing.T) {
+	rules := responseTypeRules{
+		Success: "api.Ok",
+		Error:   "api.Error",
+		Matchers: []types.ResponseTypeMatcher{
+			{Status: "404", MessageType: "api.NotFound"},
+			{Status: "409", MessageType: "api.Conflict"},
+			{Status: "4xx", MessageType: "api.BadRequest"},
+			{Status: "503", MessageType: "api.Unavailable"},
+		},
+	}
+
+	tests := []struct {
+		resp *ResponseContext
+		want string
+	}{
+		{&ResponseContext{Status: 404}, "api.NotFound"},
+		{&ResponseContext{Status: 409}, "api.Conflict"},
+		{&ResponseContext{Status: 400}, "api.BadRequest"},
+		{&ResponseContext{Status: 503}, "api.Unavailable"},
+		{&ResponseContext{Status: 500}, "api.Error"},
+		{&ResponseContext{Status: 200}, "api.Ok"},
+		{&ResponseContext{Status: 404, ContentType: "application/x-protobuf; messageType=api.Custom"}, "api.Custom"},
+		{&ResponseContext{Status: 200, Headers: map[string]string{"X-Message-Type": "api.FromHeader"}}, "api.FromHeader"},
+		{&ResponseContext{Status: 404, Headers: map[string]string{"X-Message-Type": "api.Unregistered"}}, "api.NotFound"},
+		{&ResponseContext{Status: 200, ContentType: "application/x-protobuf; proto=api.Unregistered"}, "api.Ok"},
+	}
+	known := func(fqn string) bool { return fqn != "api.Unregistered" }
+	for _, tt := range tests {
+		if got := rules.selectType(tt.resp, known); got != tt.want {
+			t.Errorf("selectType(status=%d, ct=%q) = %q, want %q", tt.resp.Status, tt.resp.ContentType, got, tt.want)
+		}
+	}
+
+	withDefault := responseTypeRules{Success: "api.Ok", Matchers: []types.ResponseTypeMatcher{{Status: "default", MessageType: "api.Any"}}}
+	if got := withDefault.selectType(&ResponseContext{Status: 200}, known); got != "api.Any" {
+		t.Errorf("expected default matcher to win over ResponseType, got %q", got)
+	}
+}
+
+func TestValidateResponseTypes(t *testing.T) {
+	if err := ValidateResponseTypes([]types.ResponseTypeMatcher{{Status: "4xx", MessageType: "a.B"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateResponseTypes([]types.ResponseTypeMatcher{{Status: "nope", MessageType: "a.B"}}); err == nil {
+		t.Fatal("expected error for malformed status")
+	}
+	if err := ValidateResponseTypes([]types.ResponseTypeMatcher{{Status: "404"}}); err == nil {
+		t.Fatal("expected error for missing message type")
+	}
+}
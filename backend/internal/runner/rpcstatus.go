@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// rpcStatusType is the message type of google.rpc.Status error bodies
+const rpcStatusType = "google.rpc.Status"
+
+// statusDetailsTrailer carries a serialized google.rpc.Status alongside gRPC errors
+const statusDetailsTrailer = "grpc-status-details-bin"
+
+// connectErrorDetail is one entry of a Connect error's details array
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"` // base64, padding optional
+}
+
+// decodeRPCStatus parses a google.rpc.Status body and unpacks its details
+func (s *Service) decodeRPCStatus(body []byte) (*ErrorStatus, error) {
+	var st spb.Status
+	if err := proto.Unmarshal(body, &st); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", rpcStatusType, err)
+	}
+	return s.errorStatusFrom(st.GetCode(), st.GetMessage(), st.GetDetails()), nil
+}
+
+// errorStatusFrom builds the API representation of an RPC error with unpacked details
+func (s *Service) errorStatusFrom(code int32, message string, details []*anypb.Any) *ErrorStatus {
+	errStatus := &ErrorStatus{
+		Code:    int(code),
+		Name:    codes.Code(code).String(),
+		Message: message,
+		Details: make([]ErrorDetail, 0, len(details)),
+	}
+	for _, detail := range details {
+		errStatus.Details = append(errStatus.Details, s.decodeAnyDetail(detail))
+	}
+	return errStatus
+}
+
+// decodeAnyDetail resolves an Any's type URL against the registry and renders the message
+// as JSON, keeping the raw bytes when the type is unknown or the value does not decode
+func (s *Service) decodeAnyDetail(detail *anypb.Any) ErrorDetail {
+	typeURL := detail.GetTypeUrl()
+	out := ErrorDetail{
+		TypeURL: typeURL,
+		Type:    typeURL[strings.LastIndex(typeURL, "/")+1:],
+	}
+
+	md, err := s.resolveMessageType(out.Type)
+	if err != nil {
+		out.Raw = base64.StdEncoding.EncodeToString(detail.GetValue())
+		out.DecodeError = err.Error()
+		return out
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(detail.GetValue(), msg); err != nil {
+		out.Raw = base64.StdEncoding.EncodeToString(detail.GetValue())
+		out.DecodeError = fmt.Sprintf("failed to unmarshal %s: %v", out.Type, err)
+		return out
+	}
+	jsonBytes, err := protojson.MarshalOptions{Resolver: s.typeResolver()}.Marshal(msg)
+	if err != nil {
+		out.Raw = base64.StdEncoding.EncodeToString(detail.GetValue())
+		out.DecodeError = fmt.Sprintf("failed to marshal %s to JSON: %v", out.Type, err)
+		return out
+	}
+	out.Value = jsonBytes
+	return out
+}
+
+// resolveMessageType finds a message descriptor in the registry, falling back to types
+// linked into the binary such as the well-known types
+func (s *Service) resolveMessageType(fqn string) (protoreflect.MessageDescriptor, error) {
+	if s.registry != nil {
+		if md, err := s.registry.GetMessageDescriptor(fqn); err == nil {
+			return md, nil
+		}
+	}
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(fqn)); err == nil {
+		return mt.Descriptor(), nil
+	}
+	return nil, fmt.Errorf("message type not registered: %s", fqn)
+}
+
+// statusDetailsFromTrailers reads Any details from a base64-encoded grpc-status-details-bin trailer
+func statusDetailsFromTrailers(trailers map[string]string) []*anypb.Any {
+	value, ok := trailers[statusDetailsTrailer]
+	if !ok {
+		return nil
+	}
+	raw, err := decodeBase64Lenient(value)
+	if err != nil {
+		return nil
+	}
+	var st spb.Status
+	if err := proto.Unmarshal(raw, &st); err != nil {
+		return nil
+	}
+	return st.GetDetails()
+}
+
+// connectDetailsToAny converts Connect JSON error details into Any messages
+func connectDetailsToAny(details []json.RawMessage) []*anypb.Any {
+	out := make([]*anypb.Any, 0, len(details))
+	for _, raw := range details {
+		var detail connectErrorDetail
+		if err := json.Unmarshal(raw, &detail); err != nil || detail.Type == "" {
+			continue
+		}
+		value, err := decodeBase64Lenient(detail.Value)
+		if err != nil {
+			continue
+		}
+		out = append(out, &anypb.Any{TypeUrl: defaultTypeURLPrefix + detail.Type, Value: value})
+	}
+	return out
+}
+
+// decodeBase64Lenient decodes standard base64 with or without padding
+func decodeBase64Lenient(value string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(value), "="))
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// assertInvalidNameDetails checks the details produced by invalidNameStatus
+func assertInvalidNameDetails(t *testing.T, errStatus *ErrorStatus) {
+	t.Helper()
+	if errStatus == nil || errStatus.Name != "InvalidArgument" || errStatus.Message != "bad name" {
+		t.Fatalf("unexpected error status: %+v", errStatus)
+	}
+	if len(errStatus.Details) != 2 {
+		t.Fatalf("expected 2 details, got %+v", errStatus.Details)
+	}
+
+	known := errStatus.Details[0]
+	if known.Type != "greet.v1.HelloReply" || !strings.Contains(string(known.Value), "try another name") || known.Raw != "" {
+		t.Fatalf("expected decoded registered detail, got %+v", known)
+	}
+
+	unknown := errStatus.Details[1]
+	if unknown.Type != "acme.v1.Unknown" || unknown.Value != nil || unknown.DecodeError == "" {
+		t.Fatalf("expected unregistered detail to stay raw, got %+v", unknown)
+	}
+	if raw, _ := base64.StdEncoding.DecodeString(unknown.Raw); string(raw) != "\x08\x01" {
+		t.Fatalf("expected raw detail bytes, got %q", unknown.Raw)
+	}
+}
+
+func TestRunGRPC_StatusDetails(t *testing.T) {
+	reg := setupRegistry(t, "greet.proto")
+	addr := startGreeterServer(t, reg)
+	svc := NewService(reg)
+
+	res, err := svc.Run(&RunReq{
+		Method:         "POST",
+		URL:            addr,
+		Protocol:       ProtocolGRPC,
+		ServiceMethod:  "greet.v1.Greeter/SayHello",
+		Body:           []types.BodyField{{Path: "name", Value: "invalid"}},
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Status != 400 {
+		t.Fatalf("expected status 400, got %d", res.Status)
+	}
+	assertInvalidNameDetails(t, res.ErrorStatus)
+}
+
+func TestProcessResponse_RPCStatusBody(t *testing.T) {
+	reg := setupRegistry(t, "greet.proto")
+	svc := NewService(reg)
+	method, _ := reg.GetMethodDescriptor("greet.v1.Greeter/SayHello")
+
+	body, err := proto.Marshal(invalidNameStatus(t, method.Output()))
+	if err != nil {
+		t.Fatalf("failed to marshal status: %v", err)
+	}
+	res, err := svc.processResponse(&ResponseContext{
+		Status:      400,
+		Body:        body,
+		ContentType: "application/x-protobuf",
+	}, responseTypeRules{Error: rpcStatusType})
+	if err != nil {
+		t.Fatalf("processResponse failed: %v", err)
+	}
+	if res.DecodeError != "" {
+		t.Fatalf("unexpected decode error: %s", res.DecodeError)
+	}
+	assertInvalidNameDetails(t, res.ErrorStatus)
+
+	var decoded ErrorStatus
+	if err := json.Unmarshal([]byte(res.Decoded), &decoded); err != nil || decoded.Code != 3 {
+		t.Fatalf("expected decoded JSON status, got %q (%v)", res.Decoded, err)
+	}
+}
+
+func TestConnectDetailsToAny(t *testing.T) {
+	details := []json.RawMessage{
+		json.RawMessage(`{"type":"greet.v1.HelloReply","value":"CgJoaQ","debug":{"message":"hi"}}`),
+		json.RawMessage(`{"value":"missing type"}`),
+	}
+	anys := connectDetailsToAny(details)
+	if len(anys) != 1 || anys[0].TypeUrl != "type.googleapis.com/greet.v1.HelloReply" || string(anys[0].Value) != "\n\x02hi" {
+		t.Fatalf("unexpected conversion: %+v", anys)
+	}
+}
@@ -10,13 +10,17 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/datahopper/backend/internal/dotpath"
 	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/rawproto"
 	"github.com/datahopper/backend/internal/registry"
 	"github.com/datahopper/backend/internal/types"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -25,16 +29,23 @@ import (
 
 // Service provides HTTP request execution with Protobuf support
 type Service struct {
-	registry *registry.Service
-	logger   zerolog.Logger
-	client   *http.Client
+	registry  *registry.Service
+	logger    zerolog.Logger
+	client    *http.Client
+	streamsMu sync.Mutex
+	streams   map[string]*StreamSession
+	files     FileResolver // Loads uploaded files for BytesFile body fields
+	grpcMu    sync.Mutex
+	grpcConns map[string]*grpc.ClientConn // Client connection per gRPC target
 }
 
 // NewService creates a new runner service
 func NewService(registry *registry.Service) *Service {
 	return &Service{
-		registry: registry,
-		client:   &http.Client{},
+		registry:  registry,
+		client:    &http.Client{},
+		streams:   make(map[string]*StreamSession),
+		grpcConns: make(map[string]*grpc.ClientConn),
 	}
 }
 
@@ -45,6 +56,18 @@ func (s *Service) Run(req *RunReq) (*RunRes, error) {
 		Str("url", req.URL).
 		Msg("Executing request")
 
+	// gRPC calls resolve their message types from the method descriptor
+	if req.Protocol == ProtocolGRPC {
+		return s.runGRPC(req)
+	}
+	if isWebRPCProtocol(req.Protocol) {
+		resolved, err := s.resolveWebRPC(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s method: %w", req.Protocol, err)
+		}
+		req = resolved
+	}
+
 	// Build request context
 	ctx, err := s.buildRequestContext(req)
 	if err != nil {
@@ -57,33 +80,150 @@ func (s *Service) Run(req *RunReq) (*RunRes, error) {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 
+	// Strip Connect / gRPC-Web framing and parse Twirp errors
+	if err := unwrapWebRPCResponse(req.Protocol, resp); err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", req.Protocol, err)
+	}
+	if req.Protocol == ProtocolTwirpJSON {
+		if err := s.decodeTwirpJSON(req.ResponseType, resp); err != nil {
+			return nil, fmt.Errorf("failed to read %s response: %w", req.Protocol, err)
+		}
+	}
+
 	// Process response
-	result, err := s.processResponse(resp, req.ResponseType, req.ErrorResponseType)
+	result, err := s.processResponse(resp, responseRulesFor(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to process response: %w", err)
 	}
+	result.Warnings = ctx.Unresolved
+	result.FieldWarnings = ctx.FieldWarnings
 
 	return result, nil
 }
 
 // buildRequestContext builds the request context from RunReq
 func (s *Service) buildRequestContext(req *RunReq) (*RequestContext, error) {
-	// Merge variables (collection -> environment -> request)
-	mergedVars := req.Variables
-	if mergedVars == nil {
-		mergedVars = make(map[string]string)
+	if err := ValidateResponseTypes(req.ResponseTypes); err != nil {
+		return nil, err
+	}
+
+	// Merge variables (collection -> environment -> request) and generate the dynamic
+	// ones, once for the whole request
+	mergedVars, err := interpolate.WithDynamic(req.Variables, req.URL, req.Headers, req.RawBody, fieldValues(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	if err := variableCycle(mergedVars, req.URL, req.Headers, req.RawBody, fieldValues(req.Body)); err != nil {
+		return nil, err
+	}
+
+	// Strict runs stop at placeholders without a value; lenient ones send them as they are
+	unresolved := unresolvedVariables(req, mergedVars)
+	if req.StrictVariables && len(unresolved) > 0 {
+		return nil, &UnresolvedVariablesError{Variables: unresolved}
 	}
 
 	// Interpolate URL and headers
 	interpolatedURL := interpolate.String(req.URL, mergedVars)
 	interpolatedHeaders := interpolate.Deep(req.Headers, mergedVars).(map[string]string)
 
-	// Build body from dot-path fields
+	// Build body from dot-path fields or a raw document, encoding as Protobuf if specified
+	body, fieldWarnings, err := s.requestBody(req, mergedVars)
+	if err != nil {
+		return nil, err
+	}
+	if encoded, ok := body.([]byte); ok && req.EnforceConstraints {
+		violations, err := s.checkEncodedConstraints(req.ProtoMessage, encoded)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 {
+			return nil, &BodyValidationError{MessageType: req.ProtoMessage, Errors: violations}
+		}
+	}
+
+	// Set default headers
+	if interpolatedHeaders == nil {
+		interpolatedHeaders = make(map[string]string)
+	}
+
+	// Set timeout
+	timeout := req.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = 30 // Default 30 seconds
+	}
+
+	// Set Content-Type based on protocol and body type
+	switch {
+	case req.Protocol == ProtocolGRPC:
+		// gRPC manages its own content negotiation
+	case isWebRPCProtocol(req.Protocol):
+		if req.Protocol == ProtocolTwirpJSON {
+			if body, err = s.twirpJSONBody(req.ProtoMessage, body); err != nil {
+				return nil, err
+			}
+		}
+		framed, err := frameWebRPCBody(req.Protocol, body)
+		if err != nil {
+			return nil, err
+		}
+		body = framed
+		for key, value := range webRPCHeaders(req.Protocol, timeout) {
+			interpolatedHeaders[key] = value
+		}
+	default:
+		if req.ProtoMessage != "" {
+			interpolatedHeaders["Content-Type"] = "application/x-protobuf"
+		} else if body != nil {
+			interpolatedHeaders["Content-Type"] = "application/json"
+		}
+
+		// Set Accept header for protobuf responses
+		if req.ResponseType != "" {
+			interpolatedHeaders["Accept"] = "application/x-protobuf, application/octet-stream"
+		}
+	}
+
+	return &RequestContext{
+		Method:            req.Method,
+		URL:               interpolatedURL,
+		Headers:           interpolatedHeaders,
+		Body:              body,
+		TimeoutSeconds:    timeout,
+		ProtoMessage:      req.ProtoMessage,
+		ResponseType:      req.ResponseType,
+		ErrorResponseType: req.ErrorResponseType,
+		Unresolved:        unresolved,
+		FieldWarnings:     fieldWarnings,
+	}, nil
+}
+
+// buildBody builds a request body from dot-path fields and encodes it as Protobuf
+// when messageType is set, honouring the oneof selections. Protobuf bodies are validated
+// against the descriptor first and rejected with a *BodyValidationError; deprecated fields
+// are sent and returned as warnings. Returns nil when there are no fields or selections.
+func (s *Service) buildBody(messageType string, bodyFields []types.BodyField, oneofs types.OneofSelections, owner fileOwner) (interface{}, []FieldError, error) {
+	var warnings []FieldError
+	if messageType != "" && (len(bodyFields) > 0 || len(oneofs) > 0) {
+		v, err := s.validateBody(messageType, bodyFields, oneofs, owner)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(v.errors) > 0 {
+			return nil, nil, &BodyValidationError{MessageType: messageType, Errors: v.errors}
+		}
+		warnings = v.warnings
+		bodyFields = applyPresence(applyBytesEncodings(bodyFields, v.bytes), v.presence)
+	} else {
+		// Without a descriptor there are no defaults to send, only fields to leave out
+		bodyFields = dropUnsetFields(bodyFields)
+	}
+
 	var body interface{}
-	if len(req.Body) > 0 {
+	if len(bodyFields) > 0 {
 		// Convert types.BodyField to interface{} slice for dotpath.BuildFromFields
-		fields := make([]interface{}, len(req.Body))
-		for i, field := range req.Body {
+		fields := make([]interface{}, len(bodyFields))
+		for i, field := range bodyFields {
 			fields[i] = map[string]interface{}{
 				"path":  field.Path,
 				"value": field.Value,
@@ -91,92 +231,45 @@ func (s *Service) buildRequestContext(req *RunReq) (*RequestContext, error) {
 		}
 		bodyMap, err := dotpath.BuildFromFields(fields)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build body from fields: %w", err)
+			return nil, nil, fmt.Errorf("failed to build body from fields: %w", err)
 		}
 		body = bodyMap
+	} else if messageType != "" && len(oneofs) > 0 {
+		// A selection alone still sets its member
+		body = map[string]interface{}{}
 	}
 
 	// Encode body as Protobuf if specified
-	if req.ProtoMessage != "" && body != nil {
+	if messageType != "" && body != nil {
 		// Log the body structure for debugging
 		if bodyMap, ok := body.(map[string]interface{}); ok {
 			bodyJSON, _ := json.MarshalIndent(bodyMap, "", "  ")
 			s.logger.Debug().
-				Str("messageType", req.ProtoMessage).
+				Str("messageType", messageType).
 				Str("body", string(bodyJSON)).
 				Msg("Building protobuf body")
 		}
 
-		encodedBody, err := s.encodeProtobufBody(req.ProtoMessage, body, req.Body)
+		encodedBody, err := s.encodeProtobufBody(messageType, body, oneofs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to encode protobuf body: %w", err)
+			return nil, nil, fmt.Errorf("failed to encode protobuf body: %w", err)
 		}
 		body = encodedBody
 	}
 
-	// Set default headers
-	if interpolatedHeaders == nil {
-		interpolatedHeaders = make(map[string]string)
-	}
-
-	// Set Content-Type based on body type
-	if req.ProtoMessage != "" {
-		interpolatedHeaders["Content-Type"] = "application/x-protobuf"
-	} else if body != nil {
-		interpolatedHeaders["Content-Type"] = "application/json"
-	}
-
-	// Set Accept header for protobuf responses
-	if req.ResponseType != "" {
-		interpolatedHeaders["Accept"] = "application/x-protobuf, application/octet-stream"
-	}
-
-	// Set timeout
-	timeout := req.TimeoutSeconds
-	if timeout <= 0 {
-		timeout = 30 // Default 30 seconds
-	}
-
-	return &RequestContext{
-		Method:            req.Method,
-		URL:               interpolatedURL,
-		Headers:           interpolatedHeaders,
-		Body:              body,
-		TimeoutSeconds:    timeout,
-		ProtoMessage:      req.ProtoMessage,
-		ResponseType:      req.ResponseType,
-		ErrorResponseType: req.ErrorResponseType,
-	}, nil
+	return body, warnings, nil
 }
 
 // encodeProtobufBody encodes a JSON body as Protobuf
-func (s *Service) encodeProtobufBody(messageType string, body interface{}, originalFields []types.BodyField) ([]byte, error) {
+func (s *Service) encodeProtobufBody(messageType string, body interface{}, oneofs types.OneofSelections) ([]byte, error) {
 	// Get message descriptor
 	msgDesc, err := s.registry.GetMessageDescriptor(messageType)
 	if err != nil {
 		return nil, fmt.Errorf("message descriptor not found: %s", messageType)
 	}
 
-	// Clean up the body to handle potential oneof conflicts using the descriptor and original field order
-	cleanedBody := s.cleanBodyForProtobufWithDescriptor(msgDesc, body, originalFields)
-
-	// Debug: Log the cleaned body structure before JSON marshaling
-	if bodyMap, ok := cleanedBody.(map[string]interface{}); ok {
-		// Check the last4 field specifically
-		if details, ok := bodyMap["paymentMethod"].(map[string]interface{}); ok {
-			if cc, ok := details["details"].(map[string]interface{}); ok {
-				if ccDetails, ok := cc["cc"].(map[string]interface{}); ok {
-					if last4, exists := ccDetails["last4"]; exists {
-						s.logger.Debug().
-							Str("field", "last4").
-							Interface("value", last4).
-							Str("type", fmt.Sprintf("%T", last4)).
-							Msg("last4 field before JSON marshaling")
-					}
-				}
-			}
-		}
-	}
+	// Apply the oneof selections throughout the body
+	cleanedBody := s.cleanBodyForProtobufWithDescriptor(msgDesc, body, "", oneofs)
 
 	// Create dynamic message
 	dynamicMsg := dynamicpb.NewMessage(msgDesc)
@@ -204,6 +297,7 @@ func (s *Service) encodeProtobufBody(messageType string, body interface{}, origi
 	unmarshalOpts := protojson.UnmarshalOptions{
 		DiscardUnknown: true, // Allow unknown fields
 		AllowPartial:   true, // Allow partial messages
+		Resolver:       s.typeResolver(),
 	}
 	if err := unmarshalOpts.Unmarshal(jsonBytes, dynamicMsg); err != nil {
 		// If unmarshaling fails, try to provide a more helpful error
@@ -219,10 +313,9 @@ func (s *Service) encodeProtobufBody(messageType string, body interface{}, origi
 	return protoBytes, nil
 }
 
-// cleanBodyForProtobufWithDescriptor cleans up the body to handle potential oneof conflicts
-// by pruning multiple members of the same oneof, keeping the last-set one based on the
-// order of original dot-path fields.
-func (s *Service) cleanBodyForProtobufWithDescriptor(md protoreflect.MessageDescriptor, body interface{}, originalFields []types.BodyField) interface{} {
+// cleanBodyForProtobufWithDescriptor applies the oneof selections to every message in the
+// body (see applyOneofSelections). prefix is the dot-path of body.
+func (s *Service) cleanBodyForProtobufWithDescriptor(md protoreflect.MessageDescriptor, body interface{}, prefix string, oneofs types.OneofSelections) interface{} {
 	if bodyMap, ok := body.(map[string]interface{}); ok {
 		cleaned := make(map[string]interface{})
 
@@ -239,27 +332,50 @@ func (s *Service) cleanBodyForProtobufWithDescriptor(md protoreflect.MessageDesc
 			cleaned[key] = bodyMap[key]
 		}
 
-		// Prune oneof conflicts at this level
-		pruneOneofConflicts(md, cleaned, "", originalFields)
+		// Apply oneof selections at this level
+		applyOneofSelections(md, cleaned, prefix, oneofs)
 
 		// Recurse into nested message fields
 		for i := 0; i < md.Fields().Len(); i++ {
 			fd := md.Fields().Get(i)
 			jsonName := fd.JSONName()
+			path := joinPath(prefix, jsonName)
 			val, exists := cleaned[jsonName]
+			if !exists && fd.Message() != nil && !fd.IsList() && !fd.IsMap() && hasSelectionUnder(oneofs, path) {
+				// Selections inside a message the body leaves out still set their members
+				val, exists = map[string]interface{}{}, true
+				cleaned[jsonName] = val
+			}
 			if !exists {
 				continue
 			}
+			if fd.IsMap() {
+				// Map values are messages keyed by the map key in dot-paths
+				valueMd := fd.MapValue().Message()
+				entries, ok := val.(map[string]interface{})
+				if valueMd == nil || !ok || isWellKnownJSONType(string(valueMd.FullName())) {
+					continue
+				}
+				for key, entry := range entries {
+					if entryMap, ok := entry.(map[string]interface{}); ok {
+						entries[key] = s.cleanBodyForProtobufWithDescriptor(s.messageDescriptorFor(valueMd, entryMap), entryMap, joinPath(path, key), oneofs)
+					}
+				}
+				continue
+			}
 			if fd.Kind() == protoreflect.MessageKind {
 				childMd := fd.Message()
-				// Map value
+				if isWellKnownJSONType(string(childMd.FullName())) {
+					// Free-form JSON such as a Struct has no oneofs
+					continue
+				}
 				if childMap, ok := val.(map[string]interface{}); ok {
-					cleaned[jsonName] = s.cleanBodyForProtobufWithDescriptor(childMd, childMap, originalFields)
+					cleaned[jsonName] = s.cleanBodyForProtobufWithDescriptor(s.messageDescriptorFor(childMd, childMap), childMap, path, oneofs)
 				} else if arr, ok := val.([]interface{}); ok {
 					// Recurse for each element if it's a map
 					for idx, item := range arr {
 						if itemMap, ok := item.(map[string]interface{}); ok {
-							arr[idx] = s.cleanBodyForProtobufWithDescriptor(childMd, itemMap, originalFields)
+							arr[idx] = s.cleanBodyForProtobufWithDescriptor(s.messageDescriptorFor(childMd, itemMap), itemMap, fmt.Sprintf("%s[%d]", path, idx), oneofs)
 						}
 					}
 					cleaned[jsonName] = arr
@@ -274,8 +390,9 @@ func (s *Service) cleanBodyForProtobufWithDescriptor(md protoreflect.MessageDesc
 }
 
 // transformWellKnownTypesForJSON walks the body using the descriptor and converts well-known
-// types into the JSON shapes expected by protojson. Currently handles google.protobuf.Timestamp
-// by turning {seconds, nanos} objects into RFC3339 strings. Operates in-place on m.
+// types from their dot-path input forms into the JSON shapes expected by protojson (see
+// normalizeWellKnownValue). Singular, repeated and map-valued fields are handled. Operates
+// in-place on m.
 func (s *Service) transformWellKnownTypesForJSON(md protoreflect.MessageDescriptor, m map[string]interface{}) {
 	for i := 0; i < md.Fields().Len(); i++ {
 		fd := md.Fields().Get(i)
@@ -285,61 +402,43 @@ func (s *Service) transformWellKnownTypesForJSON(md protoreflect.MessageDescript
 			continue
 		}
 
-		if fd.Kind() == protoreflect.MessageKind {
-			childMd := fd.Message()
-			fullName := string(childMd.FullName())
-
-			// Handle google.protobuf.Timestamp
-			if fullName == "google.protobuf.Timestamp" {
-				switch vv := val.(type) {
-				case map[string]interface{}:
-					seconds := extractInt64(vv["seconds"]) // default 0 if missing
-					nanos := extractInt64(vv["nanos"])     // default 0 if missing
-					if nanos < 0 {
-						nanos = 0
-					}
-					if nanos > 999999999 {
-						nanos = 999999999
-					}
-					m[jsonName] = time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
-				case []interface{}:
-					for idx, item := range vv {
-						if itemMap, ok := item.(map[string]interface{}); ok {
-							seconds := extractInt64(itemMap["seconds"]) // default 0 if missing
-							nanos := extractInt64(itemMap["nanos"])     // default 0 if missing
-							if nanos < 0 {
-								nanos = 0
-							}
-							if nanos > 999999999 {
-								nanos = 999999999
-							}
-							vv[idx] = time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
-						}
-					}
-					m[jsonName] = vv
-				default:
-					// Already a string or other type; leave as-is
-				}
+		if fd.IsMap() {
+			valueMd := fd.MapValue().Message()
+			entries, ok := val.(map[string]interface{})
+			if valueMd == nil || !ok {
 				continue
 			}
+			for key, entry := range entries {
+				entries[key] = s.transformWellKnownValue(valueMd, entry)
+			}
+			continue
+		}
 
-			// Recurse into nested message fields
-			switch typed := val.(type) {
-			case map[string]interface{}:
-				s.transformWellKnownTypesForJSON(childMd, typed)
-			case []interface{}:
-				for idx, item := range typed {
-					if itemMap, ok := item.(map[string]interface{}); ok {
-						s.transformWellKnownTypesForJSON(childMd, itemMap)
-						typed[idx] = itemMap
-					}
+		if fd.Kind() == protoreflect.MessageKind {
+			childMd := fd.Message()
+			if items, ok := val.([]interface{}); ok && fd.IsList() {
+				for idx, item := range items {
+					items[idx] = s.transformWellKnownValue(childMd, item)
 				}
-				m[jsonName] = typed
+				m[jsonName] = items
+				continue
 			}
+			m[jsonName] = s.transformWellKnownValue(childMd, val)
 		}
 	}
 }
 
+// transformWellKnownValue normalizes a single message value, recursing into regular messages
+func (s *Service) transformWellKnownValue(md protoreflect.MessageDescriptor, val interface{}) interface{} {
+	if normalized, ok := normalizeWellKnownValue(string(md.FullName()), val); ok {
+		return normalized
+	}
+	if typed, ok := val.(map[string]interface{}); ok {
+		s.transformWellKnownTypesForJSON(s.messageDescriptorFor(md, typed), typed)
+	}
+	return val
+}
+
 // coerceJSONTypesToProtoKinds walks the JSON-like map and coerces values to match the
 // protobuf field kinds where safe/obvious. Currently:
 // - For string fields, convert numeric and boolean inputs to their string representation.
@@ -359,23 +458,43 @@ func (s *Service) coerceJSONTypesToProtoKinds(md protoreflect.MessageDescriptor,
 				// Repeated string: coerce each element
 				for idx, item := range v {
 					switch iv := item.(type) {
-					case int, int32, int64, uint, uint32, uint64, float32, float64, bool:
+					case int, int32, int64, uint, uint32, uint64, float32, float64, bool, json.Number:
+						v[idx] = formatPrimitiveAsString(iv)
+					}
+				}
+				m[jsonName] = v
+			case int, int32, int64, uint, uint32, uint64, float32, float64, bool, json.Number:
+				m[jsonName] = formatPrimitiveAsString(v)
+			}
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+			protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			// Send 64-bit integers as decimal strings, the canonical protojson form, so
+			// json.Number values are never re-read as float64
+			switch v := val.(type) {
+			case []interface{}:
+				for idx, item := range v {
+					switch iv := item.(type) {
+					case float32, float64, json.Number:
 						v[idx] = formatPrimitiveAsString(iv)
 					}
 				}
 				m[jsonName] = v
-			case int, int32, int64, uint, uint32, uint64, float32, float64, bool:
+			case float32, float64, json.Number:
 				m[jsonName] = formatPrimitiveAsString(v)
 			}
 		case protoreflect.MessageKind:
 			childMd := fd.Message()
+			if isWellKnownJSONType(string(childMd.FullName())) {
+				// Already in protojson form; free-form Struct keys must not be coerced
+				continue
+			}
 			switch typed := val.(type) {
 			case map[string]interface{}:
-				s.coerceJSONTypesToProtoKinds(childMd, typed)
+				s.coerceJSONTypesToProtoKinds(s.messageDescriptorFor(childMd, typed), typed)
 			case []interface{}:
 				for idx, item := range typed {
 					if itemMap, ok := item.(map[string]interface{}); ok {
-						s.coerceJSONTypesToProtoKinds(childMd, itemMap)
+						s.coerceJSONTypesToProtoKinds(s.messageDescriptorFor(childMd, itemMap), itemMap)
 						typed[idx] = itemMap
 					}
 				}
@@ -398,6 +517,12 @@ func extractInt64(v interface{}) int64 {
 		return int64(t)
 	case float64:
 		return int64(t)
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		f, _ := t.Float64()
+		return int64(f)
 	case string:
 		if t == "" {
 			return 0
@@ -438,70 +563,15 @@ func formatPrimitiveAsString(v interface{}) string {
 			return "true"
 		}
 		return "false"
+	case json.Number:
+		return t.String()
 	default:
 		return fmt.Sprint(v)
 	}
 }
 
-// pruneOneofConflicts removes conflicting oneof members from the given map, keeping the last-set one
-// based on the order in originalFields.
-func pruneOneofConflicts(md protoreflect.MessageDescriptor, m map[string]interface{}, basePath string, originalFields []types.BodyField) {
-	// Build a quick path order index for lookup
-	orderedPaths := make([]string, len(originalFields))
-	for i, bf := range originalFields {
-		orderedPaths[i] = bf.Path
-	}
-
-	// For each oneof, find present members and keep the last one
-	for i := 0; i < md.Oneofs().Len(); i++ {
-		od := md.Oneofs().Get(i)
-		present := make([]string, 0)
-		for j := 0; j < od.Fields().Len(); j++ {
-			fd := od.Fields().Get(j)
-			jsonName := fd.JSONName()
-			if _, exists := m[jsonName]; exists {
-				present = append(present, jsonName)
-			}
-		}
-		if len(present) <= 1 {
-			continue
-		}
-
-		// Determine which to keep based on latest occurrence in originalPaths
-		keepKey := present[0]
-		keepIdx := -1
-		for _, key := range present {
-			candidatePrefix := joinPath(basePath, key)
-			idx := lastIndexWithPrefix(orderedPaths, candidatePrefix)
-			if idx > keepIdx {
-				keepIdx = idx
-				keepKey = key
-			}
-		}
-
-		for _, key := range present {
-			if key != keepKey {
-				delete(m, key)
-			}
-		}
-	}
-}
-
 func joinPath(base, key string) string {
-	if base == "" {
-		return key
-	}
-	return base + "." + key
-}
-
-func lastIndexWithPrefix(paths []string, prefix string) int {
-	last := -1
-	for i, p := range paths {
-		if strings.HasPrefix(p, prefix) {
-			last = i
-		}
-	}
-	return last
+	return dotpath.JoinKey(base, key)
 }
 
 // executeRequest executes the HTTP request
@@ -571,35 +641,86 @@ func (s *Service) executeRequest(ctx *RequestContext) (*ResponseContext, error)
 }
 
 // processResponse processes the HTTP response
-func (s *Service) processResponse(resp *ResponseContext, responseType string, errorResponseType string) (*RunRes, error) {
+func (s *Service) processResponse(resp *ResponseContext, rules responseTypeRules) (*RunRes, error) {
 	result := &RunRes{
-		Status:  resp.Status,
-		Headers: resp.Headers,
+		Status:     resp.Status,
+		Headers:    resp.Headers,
+		Trailers:   resp.Trailers,
+		GRPCStatus: resp.GRPCStatus,
+		TwirpError: resp.TwirpError,
 	}
 
-	// Pick which message type to use for decoding: success 2xx -> responseType, else errorResponseType if provided
-	selectedType := responseType
-	if resp.Status < 200 || resp.Status >= 300 {
-		if errorResponseType != "" {
-			selectedType = errorResponseType
-		}
-	}
+	// Pick which message type to use for decoding: server hint, status matchers, then 2xx -> ResponseType, else ErrorResponseType
+	selectedType := rules.selectType(resp, s.hasMessageType)
 
 	// Always set the raw response body for reference
 	result.Raw = string(resp.Body)
-	
+
+	// Enveloped protocols deliver zero or more messages; otherwise the body is the message.
+	// A failed gRPC call carries no response message to decode.
+	payloads := resp.Messages
+	if payloads == nil && !(resp.GRPCStatus != nil && resp.GRPCStatus.Code != 0 && len(resp.Body) == 0) {
+		payloads = [][]byte{resp.Body}
+	}
+
+	// Unpack google.rpc.Status details carried with a failed RPC
+	if resp.GRPCStatus != nil && resp.GRPCStatus.Code != 0 && len(resp.ErrorDetails) > 0 {
+		result.ErrorStatus = s.errorStatusFrom(int32(resp.GRPCStatus.Code), resp.GRPCStatus.Message, resp.ErrorDetails)
+	}
+
 	// Try to decode protobuf response if specified
-	if selectedType != "" && s.isProtobufResponse(resp.ContentType) {
-		decoded, err := s.decodeProtobufResponse(selectedType, resp.Body)
+	if selectedType == rpcStatusType && len(payloads) == 1 && s.isProtobufResponse(resp.ContentType) {
+		// google.rpc.Status bodies are decoded with their Any details unpacked
+		errStatus, err := s.decodeRPCStatus(payloads[0])
 		if err != nil {
-			s.logger.Warn().Err(err).Msg("Failed to decode protobuf response, using raw")
 			result.DecodeError = err.Error()
 		} else {
-			result.Decoded = decoded
+			result.ErrorStatus = errStatus
+			if decoded, err := json.MarshalIndent(errStatus, "", "  "); err == nil {
+				result.Decoded = string(decoded)
+			}
+		}
+	} else if selectedType != "" && len(payloads) > 0 && s.isProtobufResponse(resp.ContentType) {
+		decodedMessages := make([]string, 0, len(payloads))
+		for _, payload := range payloads {
+			decoded, err := s.decodeProtobufResponse(selectedType, payload)
+			if err != nil {
+				s.logger.Warn().Err(err).Msg("Failed to decode protobuf response, using raw")
+				result.DecodeError = err.Error()
+				break
+			}
+			decoded = s.renderBytes(selectedType, decoded, rules.BytesDisplay)
 			// Heuristic: decoded to an empty structure though body had content → likely wrong type
 			trimmed := strings.TrimSpace(decoded)
-			if len(resp.Body) > 0 && (trimmed == "{}" || trimmed == "[]") {
+			if len(payload) > 0 && (trimmed == "{}" || trimmed == "[]") {
 				result.DecodeError = "Decoded to an empty structure; the selected message type may be incorrect."
+				if result.SuggestedTypes == nil {
+					result.SuggestedTypes = s.suggestMessageTypes(payload, selectedType)
+				}
+			}
+			decodedMessages = append(decodedMessages, decoded)
+
+			// Report contract violations without failing the run
+			if violations, err := s.checkEncodedConstraints(selectedType, payload); err == nil {
+				for _, v := range violations {
+					if len(payloads) > 1 {
+						v.Path = fmt.Sprintf("[%d].%s", len(decodedMessages)-1, v.Path)
+					}
+					result.Violations = append(result.Violations, v)
+				}
+			}
+		}
+		if len(decodedMessages) == len(payloads) {
+			result.Decoded = joinDecodedMessages(decodedMessages)
+		}
+	}
+
+	// Fall back to a schema-free decode of binary bodies when no type applied or decoding failed
+	if len(payloads) == 1 && (selectedType == "" || result.DecodeError != "") {
+		payload := payloads[0]
+		if len(payload) > 0 && (s.isProtobufResponse(resp.ContentType) || !utf8.Valid(payload)) {
+			if fields, err := rawproto.Decode(payload); err == nil {
+				result.RawDecoded = fields
 			}
 		}
 	}
@@ -607,10 +728,49 @@ func (s *Service) processResponse(resp *ResponseContext, responseType string, er
 	return result, nil
 }
 
+// hasMessageType reports whether a message type can be decoded: it is registered, or is
+// google.rpc.Status, which is always understood
+func (s *Service) hasMessageType(fqn string) bool {
+	if fqn == rpcStatusType {
+		return true
+	}
+	if s.registry == nil {
+		return false
+	}
+	_, err := s.registry.GetMessageDescriptor(fqn)
+	return err == nil
+}
+
 // isProtobufResponse checks if the response is a protobuf message
 func (s *Service) isProtobufResponse(contentType string) bool {
 	return strings.Contains(contentType, "application/x-protobuf") ||
-		strings.Contains(contentType, "application/octet-stream")
+		strings.Contains(contentType, "application/octet-stream") ||
+		strings.Contains(contentType, "application/grpc") ||
+		strings.Contains(contentType, "application/proto") ||
+		strings.Contains(contentType, "application/protobuf") ||
+		strings.Contains(contentType, "application/connect+proto")
+}
+
+// suggestMessageTypes ranks registered types that could decode payload, excluding the type that failed
+func (s *Service) suggestMessageTypes(payload []byte, exclude string) []registry.TypeCandidate {
+	if s.registry == nil {
+		return nil
+	}
+	var suggestions []registry.TypeCandidate
+	for _, candidate := range s.registry.InferMessageTypes(payload, suggestedTypeLimit+1) {
+		if candidate.FQN != exclude && len(suggestions) < suggestedTypeLimit {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// joinDecodedMessages returns a single decoded message as-is and several as a JSON array
+func joinDecodedMessages(decoded []string) string {
+	if len(decoded) == 1 {
+		return decoded[0]
+	}
+	return "[\n" + strings.Join(decoded, ",\n") + "\n]"
 }
 
 // decodeProtobufResponse decodes a protobuf response to JSON
@@ -633,6 +793,7 @@ func (s *Service) decodeProtobufResponse(messageType string, bodyBytes []byte) (
 	marshalOpts := protojson.MarshalOptions{
 		Multiline: true,
 		Indent:    "  ",
+		Resolver:  s.typeResolver(),
 	}
 	jsonBytes, err := marshalOpts.Marshal(dynamicMsg)
 	if err != nil {
@@ -1,9 +1,56 @@
 package runner
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/registry"
+	"github.com/datahopper/backend/internal/types"
 )
 
+// testProtos holds the inline proto fixtures of this package's tests, keyed by file name
+var testProtos = map[string]string{
+	"account.proto":      accountProto,
+	"blob.proto":         blobProto,
+	"buf_validate.proto": bufValidateProto,
+	"checkout.proto":     checkoutProto,
+	"envelope.proto":     envelopeProto,
+	"feed.proto":         feedProto,
+	"greet.proto":        greeterProto,
+	"legacy.proto":       legacyProto,
+	"member.proto":       memberProto,
+	"order.proto":        orderProto,
+	"patch.proto":        patchProto,
+	"settings.proto":     wellKnownProto,
+}
+
+// setupRegistry registers the named proto fixtures in a fresh registry
+func setupRegistry(t *testing.T, names ...string) *registry.Service {
+	t.Helper()
+	files := make(map[string][]byte, len(names))
+	for _, name := range names {
+		src, ok := testProtos[name]
+		if !ok {
+			t.Fatalf("unknown proto fixture %s", name)
+		}
+		files[name] = []byte(src)
+	}
+	reg := registry.NewService()
+	if err := reg.RegisterFromVirtualFS(files); err != nil {
+		t.Fatalf("failed to register protos: %v", err)
+	}
+	return reg
+}
+
+// buildTestBody builds a fields body for an unsaved request, which has no uploaded files
+func buildTestBody(svc *Service, messageType string, fields []types.BodyField, oneofs types.OneofSelections) (interface{}, []FieldError, error) {
+	return svc.buildBody(messageType, fields, oneofs, fileOwner{})
+}
+
 func TestRunnerService(t *testing.T) {
 	// Create registry service
 	runner := NewService(nil)
@@ -27,3 +74,195 @@ func TestRunnerService(t *testing.T) {
 		}
 	})
 }
+
+func TestProcessResponse_RawDecodeFallback(t *testing.T) {
+	svc := NewService(nil)
+
+	// field 1: "hi", field 2: varint 150
+	body := []byte{0x0a, 0x02, 'h', 'i', 0x10, 0x96, 0x01}
+	res, err := svc.processResponse(&ResponseContext{
+		Status:      200,
+		Body:        body,
+		ContentType: "application/x-protobuf",
+	}, responseTypeRules{})
+	if err != nil {
+		t.Fatalf("processResponse failed: %v", err)
+	}
+	if len(res.RawDecoded) != 2 || res.RawDecoded[0].Value != "hi" || res.RawDecoded[1].Value != "150" {
+		t.Fatalf("expected schema-free decode, got %+v", res.RawDecoded)
+	}
+
+	// JSON bodies are left alone
+	res, _ = svc.processResponse(&ResponseContext{
+		Status:      200,
+		Body:        []byte(`{"ok":true}`),
+		ContentType: "application/json",
+	}, responseTypeRules{})
+	if res.RawDecoded != nil {
+		t.Fatalf("expected no raw decode for JSON, got %+v", res.RawDecoded)
+	}
+}
+
+func TestProcessResponse_SuggestsTypes(t *testing.T) {
+	reg := setupRegistry(t, "greet.proto")
+	svc := NewService(reg)
+
+	// field 5 is unknown to HelloReply (so it decodes to {}) and to both Greeter messages
+	body := []byte{0x28, 0x01}
+	res, _ := svc.processResponse(&ResponseContext{Status: 200, Body: body, ContentType: "application/x-protobuf"}, responseTypeRules{Success: "greet.v1.HelloReply"})
+	if res.DecodeError == "" || len(res.SuggestedTypes) != 0 {
+		t.Fatalf("expected empty-structure warning with no suggestions, got %q / %+v", res.DecodeError, res.SuggestedTypes)
+	}
+
+	// A string in field 1 fits both Greeter messages
+	body = []byte{0x0a, 0x02, 'h', 'i'}
+	res, _ = svc.processResponse(&ResponseContext{Status: 200, Body: body, ContentType: "application/x-protobuf"}, responseTypeRules{Success: "greet.v1.HelloReply"})
+	if res.DecodeError != "" || res.SuggestedTypes != nil {
+		t.Fatalf("expected clean decode without suggestions, got %q / %+v", res.DecodeError, res.SuggestedTypes)
+	}
+}
+
+func TestBuildBody_Int64Lossless(t *testing.T) {
+	svc := NewService(setupRegistry(t, "envelope.proto"))
+
+	// Values arrive through the API as JSON; numbers must not pass through float64
+	var req RunReq
+	if err := json.Unmarshal([]byte(`{"body": [
+		{"path": "payload.@type", "value": "acme.v1.Order"},
+		{"path": "payload.quantity", "value": 1234567890123456789},
+		{"path": "payload.note", "value": 9007199254740993}
+	]}`), &req); err != nil {
+		t.Fatalf("failed to unmarshal request: %v", err)
+	}
+	req.Body = append(req.Body, types.BodyField{Path: "id", Value: "9223372036854775807"})
+
+	body, _, err := buildTestBody(svc, "acme.v1.Envelope", req.Body, nil)
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+	decoded, err := svc.decodeProtobufResponse("acme.v1.Envelope", body.([]byte))
+	if err != nil {
+		t.Fatalf("decodeProtobufResponse failed: %v", err)
+	}
+	for _, want := range []string{
+		`"quantity": "1234567890123456789"`,
+		`"note": "9007199254740993"`,
+		`"id": "9223372036854775807"`,
+	} {
+		if !strings.Contains(collapseSpaces(decoded), want) {
+			t.Errorf("expected decoded output to contain %s, got:\n%s", want, decoded)
+		}
+	}
+}
+
+// collapseSpaces normalizes whitespace in protojson output, whose spacing is deliberately unstable
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func TestBuildRequestContext_DynamicVariables(t *testing.T) {
+	svc := NewService(nil)
+	req := &RunReq{
+		Method:    "POST",
+		URL:       "http://localhost/orders/{{$uuid}}",
+		Headers:   map[string]string{"Idempotency-Key": "{{ $uuid }}"},
+		Body:      []types.BodyField{{Path: "id", Value: "{{$uuid}}"}, {Path: "note", Value: "{{$base64 \"hi\"}} for {{name}}"}},
+		Variables: map[string]string{"name": "Ada"},
+	}
+
+	first, err := svc.buildRequestContext(req)
+	if err != nil {
+		t.Fatalf("buildRequestContext failed: %v", err)
+	}
+	id := strings.TrimPrefix(first.URL, "http://localhost/orders/")
+	body := first.Body.(map[string]interface{})
+	if len(id) != 36 || first.Headers["Idempotency-Key"] != id || body["id"] != id {
+		t.Errorf("expected one uuid across URL, headers and body, got %s, %s and %v", first.URL, first.Headers["Idempotency-Key"], body["id"])
+	}
+	if body["note"] != "aGk= for Ada" {
+		t.Errorf("expected interpolated note, got %v", body["note"])
+	}
+
+	second, err := svc.buildRequestContext(req)
+	if err != nil {
+		t.Fatalf("buildRequestContext failed: %v", err)
+	}
+	if second.URL == first.URL {
+		t.Errorf("expected a fresh uuid on every send, got %s twice", first.URL)
+	}
+	if _, exists := req.Variables["$uuid"]; exists {
+		t.Error("expected the request variables to be left untouched")
+	}
+
+	req.URL = "http://localhost/{{$nope}}"
+	if _, err := svc.buildRequestContext(req); err == nil || !strings.Contains(err.Error(), "$nope") {
+		t.Errorf("expected an unknown dynamic variable error, got %v", err)
+	}
+}
+
+func TestBuildRequestContext_UnresolvedVariables(t *testing.T) {
+	svc := NewService(nil)
+	req := &RunReq{
+		Method:  "POST",
+		URL:     "{{host}}/orders/{{id}}?page={{page | default \"1\"}}",
+		Headers: map[string]string{"X-Trace": "{{trace}}", "Authorization": "Bearer {{token}}"},
+		Body: []types.BodyField{
+			{Path: "customer", Value: map[string]interface{}{"id": "{{customerId}}"}},
+			{Path: "note", Value: "{{$uuid}} for {{host}}"},
+		},
+		Variables: map[string]string{"host": "http://localhost", "token": "{{secret}}"},
+	}
+	want := []UnresolvedVariable{
+		{Name: "id", Location: LocationURL},
+		{Name: "secret", Location: LocationHeader, Key: "Authorization"},
+		{Name: "trace", Location: LocationHeader, Key: "X-Trace"},
+		{Name: "customerId", Location: LocationBody, Key: "customer"},
+	}
+
+	ctx, err := svc.buildRequestContext(req)
+	if err != nil {
+		t.Fatalf("lenient buildRequestContext failed: %v", err)
+	}
+	if !reflect.DeepEqual(ctx.Unresolved, want) {
+		t.Errorf("expected warnings %+v, got %+v", want, ctx.Unresolved)
+	}
+	if ctx.URL != "http://localhost/orders/{{id}}?page=1" {
+		t.Errorf("expected the placeholder to be sent as is, got %s", ctx.URL)
+	}
+
+	req.StrictVariables = true
+	_, err = svc.buildRequestContext(req)
+	var unresolvedErr *UnresolvedVariablesError
+	if !errors.As(err, &unresolvedErr) || !reflect.DeepEqual(unresolvedErr.Variables, want) {
+		t.Fatalf("expected an unresolved variables error listing %+v, got %v", want, err)
+	}
+	if !strings.Contains(err.Error(), "secret (header Authorization)") {
+		t.Errorf("expected the location in the message, got %q", err.Error())
+	}
+
+	req.BodyMode, req.RawBody = types.BodyModeJSON, `{"id": "{{orderId}}"}`
+	req.URL, req.Headers = "{{host}}/orders", nil
+	_, err = svc.buildRequestContext(req)
+	if !errors.As(err, &unresolvedErr) || len(unresolvedErr.Variables) != 1 || unresolvedErr.Variables[0] != (UnresolvedVariable{Name: "orderId", Location: LocationBody}) {
+		t.Errorf("expected orderId unresolved in the raw body, got %v", err)
+	}
+}
+
+func TestBuildRequestContext_VariableCycle(t *testing.T) {
+	svc := NewService(nil)
+	vars := map[string]string{"host": "http://localhost", "a": "{{b}}", "b": "x{{a}}"}
+
+	for _, req := range []*RunReq{
+		{URL: "{{host}}/orders/{{a}}"},
+		{URL: "{{host}}/orders", Headers: map[string]string{"X-Trace": "{{b}}"}},
+		{URL: "{{host}}/orders", Body: []types.BodyField{{Path: "id", Value: "{{a}}"}}},
+		{URL: "{{host}}/orders", BodyMode: types.BodyModeJSON, RawBody: `{"id": "{{a}}"}`},
+	} {
+		req.Method, req.Variables = "POST", vars
+		_, err := svc.buildRequestContext(req)
+		var cycleErr *interpolate.CycleError
+		if !errors.As(err, &cycleErr) {
+			t.Errorf("%+v: expected a variable cycle error, got %v", req, err)
+		}
+	}
+}
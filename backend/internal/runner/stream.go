@@ -0,0 +1,290 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/types"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// streamEventBuffer is how many events a session buffers before the receiver waits for a consumer
+const streamEventBuffer = 64
+
+// streamIdleTimeout is how long a session waits for a consumer when its buffer is full, and
+// how long a finished session stays reachable for its buffered events
+var streamIdleTimeout = 2 * time.Minute
+
+// StreamSession is an open streaming gRPC call. Messages can be sent until the send side
+// is half-closed; received messages are decoded and delivered through Events.
+type StreamSession struct {
+	ID              string `json:"id"`
+	ServiceMethod   string `json:"serviceMethod"`
+	ClientStreaming bool   `json:"clientStreaming"`
+	ServerStreaming bool   `json:"serverStreaming"`
+
+	svc    *Service
+	method protoreflect.MethodDescriptor
+	stream grpc.ClientStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan StreamEvent
+	sendMu sync.Mutex
+	vars   map[string]string // Merged variables with the dynamic values generated so far; guarded by sendMu
+	owner  fileOwner         // Saved request whose files BytesFile fields may load
+	strict bool              // Messages with unresolved placeholders are rejected
+}
+
+// OpenStream starts a streaming gRPC call, sends req.Messages and optionally half-closes
+func (s *Service) OpenStream(req *StreamReq) (*StreamSession, error) {
+	if s.registry == nil {
+		return nil, fmt.Errorf("registry not configured")
+	}
+	if strings.TrimSpace(req.ServiceMethod) == "" {
+		return nil, fmt.Errorf("serviceMethod is required")
+	}
+
+	method, err := s.registry.GetMethodDescriptor(req.ServiceMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gRPC method: %w", err)
+	}
+
+	messages := make([]interface{}, len(req.Messages))
+	for i, fields := range req.Messages {
+		messages[i] = fieldValues(fields)
+	}
+	vars, err := interpolate.WithDynamic(req.Variables, req.URL, req.Headers, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := variableCycle(vars, req.URL, req.Headers, messages); err != nil {
+		return nil, err
+	}
+	if unresolved := unresolvedVariables(&RunReq{URL: req.URL, Headers: req.Headers}, vars); req.StrictVariables && len(unresolved) > 0 {
+		return nil, &UnresolvedVariablesError{Variables: unresolved}
+	}
+	target := interpolate.String(req.URL, vars)
+	headers := interpolate.Deep(req.Headers, vars).(map[string]string)
+
+	conn, err := s.grpcConn(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if req.TimeoutSeconds > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(req.TimeoutSeconds)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	ctx = metadata.NewOutgoingContext(ctx, outgoingMetadata(headers))
+
+	desc := &grpc.StreamDesc{
+		StreamName:    string(method.Name()),
+		ClientStreams: method.IsStreamingClient(),
+		ServerStreams: method.IsStreamingServer(),
+	}
+	stream, err := conn.NewStream(ctx, desc, grpcMethodPath(method))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+
+	sess := &StreamSession{
+		ID:              uuid.NewString(),
+		ServiceMethod:   grpcMethodPath(method),
+		ClientStreaming: method.IsStreamingClient(),
+		ServerStreaming: method.IsStreamingServer(),
+		svc:             s,
+		method:          method,
+		stream:          stream,
+		ctx:             ctx,
+		cancel:          cancel,
+		events:          make(chan StreamEvent, streamEventBuffer),
+		vars:            vars,
+		owner:           req.fileOwner(),
+		strict:          req.StrictVariables,
+	}
+
+	s.streamsMu.Lock()
+	s.streams[sess.ID] = sess
+	s.streamsMu.Unlock()
+
+	s.logger.Info().Str("streamId", sess.ID).Str("method", sess.ServiceMethod).Str("target", target).Msg("Opened gRPC stream")
+
+	go sess.receive()
+
+	for i, fields := range req.Messages {
+		if err := sess.Send(fields); err != nil {
+			sess.Cancel()
+			return nil, fmt.Errorf("failed to send message %d: %w", i, err)
+		}
+	}
+	if req.HalfClose {
+		if err := sess.CloseSend(); err != nil {
+			sess.Cancel()
+			return nil, err
+		}
+	}
+
+	return sess, nil
+}
+
+// GetStream returns an open stream session by ID
+func (s *Service) GetStream(id string) (*StreamSession, bool) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	sess, ok := s.streams[id]
+	return sess, ok
+}
+
+// removeStream forgets a stream session
+func (s *Service) removeStream(id string) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	delete(s.streams, id)
+}
+
+// Send builds a message from dot-path fields, interpolated with the session variables, and
+// sends it on the stream. Dynamic values keep the value they were first given in the session.
+// Strict sessions reject messages with placeholders the variables do not resolve.
+func (sess *StreamSession) Send(fields []types.BodyField) error {
+	sess.sendMu.Lock()
+	defer sess.sendMu.Unlock()
+
+	vars, err := interpolate.WithDynamic(sess.vars, fieldValues(fields))
+	if err != nil {
+		return err
+	}
+	if err := variableCycle(vars, fieldValues(fields)); err != nil {
+		return err
+	}
+	if unresolved := unresolvedVariables(&RunReq{Body: fields}, vars); sess.strict && len(unresolved) > 0 {
+		return &UnresolvedVariablesError{Variables: unresolved}
+	}
+	sess.vars = vars
+	body, _, err := sess.svc.buildBody(string(sess.method.Input().FullName()), interpolateFields(fields, vars), nil, sess.owner)
+	if err != nil {
+		return err
+	}
+
+	msg := dynamicpb.NewMessage(sess.method.Input())
+	if encoded, ok := body.([]byte); ok {
+		if err := proto.Unmarshal(encoded, msg); err != nil {
+			return fmt.Errorf("failed to prepare stream message: %w", err)
+		}
+	}
+
+	if err := sess.stream.SendMsg(msg); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("stream closed by server")
+		}
+		return fmt.Errorf("failed to send stream message: %w", err)
+	}
+	return nil
+}
+
+// CloseSend half-closes the stream; the server can keep sending until it finishes
+func (sess *StreamSession) CloseSend() error {
+	sess.sendMu.Lock()
+	defer sess.sendMu.Unlock()
+	if err := sess.stream.CloseSend(); err != nil {
+		return fmt.Errorf("failed to half-close stream: %w", err)
+	}
+	return nil
+}
+
+// Cancel aborts the call and releases the session
+func (sess *StreamSession) Cancel() {
+	sess.cancel()
+	sess.svc.removeStream(sess.ID)
+}
+
+// Events returns the channel of stream events. It is closed after the end event.
+func (sess *StreamSession) Events() <-chan StreamEvent {
+	return sess.events
+}
+
+// receive reads header metadata and response messages until the call ends. A finished
+// session is released once a late consumer has had streamIdleTimeout to read its events.
+func (sess *StreamSession) receive() {
+	defer time.AfterFunc(streamIdleTimeout, func() { sess.svc.removeStream(sess.ID) })
+	defer close(sess.events)
+	defer sess.cancel()
+
+	if header, err := sess.stream.Header(); err == nil && len(header) > 0 {
+		if !sess.emit(StreamEvent{Type: StreamEventHeader, Headers: metadataToMap(header)}) {
+			return
+		}
+	}
+
+	outputType := string(sess.method.Output().FullName())
+	for index := 0; ; index++ {
+		out := dynamicpb.NewMessage(sess.method.Output())
+		if err := sess.stream.RecvMsg(out); err != nil {
+			st := status.New(codes.OK, "")
+			if err != io.EOF {
+				st = status.Convert(err)
+			}
+			end := StreamEvent{
+				Type:       StreamEventEnd,
+				Index:      index,
+				Trailers:   metadataToMap(sess.stream.Trailer()),
+				GRPCStatus: grpcStatusFrom(st),
+			}
+			if details := st.Proto().GetDetails(); len(details) > 0 {
+				end.ErrorStatus = sess.svc.errorStatusFrom(int32(st.Code()), st.Message(), details)
+			}
+			sess.emit(end)
+			sess.svc.logger.Info().Str("streamId", sess.ID).Str("status", st.Code().String()).Int("received", index).Msg("gRPC stream ended")
+			return
+		}
+
+		event := StreamEvent{Type: StreamEventMessage, Index: index}
+		bodyBytes, err := proto.Marshal(out)
+		if err != nil {
+			event.DecodeError = err.Error()
+		} else if decoded, err := sess.svc.decodeProtobufResponse(outputType, bodyBytes); err != nil {
+			event.DecodeError = err.Error()
+		} else {
+			event.Decoded = decoded
+		}
+		if !sess.emit(event) {
+			sess.svc.logger.Warn().Str("streamId", sess.ID).Int("received", index).Msg("gRPC stream abandoned with no consumer")
+			return
+		}
+	}
+}
+
+// emit delivers an event. When the buffer is full it waits for a consumer until the session
+// is cancelled or streamIdleTimeout passes, then cancels the call and reports false.
+func (sess *StreamSession) emit(event StreamEvent) bool {
+	select {
+	case sess.events <- event:
+		return true
+	default:
+	}
+	idle := time.NewTimer(streamIdleTimeout)
+	defer idle.Stop()
+	select {
+	case sess.events <- event:
+		return true
+	case <-sess.ctx.Done():
+	case <-idle.C:
+		sess.cancel()
+	}
+	return false
+}
@@ -0,0 +1,367 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datahopper/backend/internal/interpolate"
+	"github.com/datahopper/backend/internal/registry"
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const feedProto = `syntax = "proto3";
+
+package feed.v1;
+
+message Subscribe {
+  string topic = 1;
+  int32 count = 2;
+}
+
+message Event {
+  string topic = 1;
+  int32 seq = 2;
+  bytes payload = 3;
+}
+
+message Ack {
+  int32 received = 1;
+}
+
+service Feed {
+  rpc Watch(Subscribe) returns (stream Event);
+  rpc Upload(stream Event) returns (Ack);
+  rpc Chat(stream Event) returns (stream Event);
+}
+`
+
+// startFeedServer registers the Feed service and serves it in-process with dynamic messages.
+func startFeedServer(t *testing.T) (*registry.Service, string) {
+	t.Helper()
+	reg := setupRegistry(t, "feed.proto")
+	watch, _ := reg.GetMethodDescriptor("feed.v1.Feed/Watch")
+	upload, _ := reg.GetMethodDescriptor("feed.v1.Feed/Upload")
+
+	eventDesc := watch.Output()
+	newEvent := func(topic string, seq int32) *dynamicpb.Message {
+		ev := dynamicpb.NewMessage(eventDesc)
+		ev.Set(eventDesc.Fields().ByName("topic"), protoreflect.ValueOfString(topic))
+		ev.Set(eventDesc.Fields().ByName("seq"), protoreflect.ValueOfInt32(seq))
+		return ev
+	}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		fullMethod, _ := grpc.MethodFromServerStream(stream)
+		switch fullMethod {
+		case "/feed.v1.Feed/Watch":
+			sub := dynamicpb.NewMessage(watch.Input())
+			if err := stream.RecvMsg(sub); err != nil {
+				return err
+			}
+			topic := sub.Get(watch.Input().Fields().ByName("topic")).String()
+			count := int32(sub.Get(watch.Input().Fields().ByName("count")).Int())
+			for i := int32(0); i < count; i++ {
+				if err := stream.SendMsg(newEvent(topic, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "/feed.v1.Feed/Upload":
+			received := int32(0)
+			for {
+				ev := dynamicpb.NewMessage(eventDesc)
+				if err := stream.RecvMsg(ev); err == io.EOF {
+					break
+				} else if err != nil {
+					return err
+				}
+				received++
+			}
+			ack := dynamicpb.NewMessage(upload.Output())
+			ack.Set(upload.Output().Fields().ByName("received"), protoreflect.ValueOfInt32(received))
+			return stream.SendMsg(ack)
+		default: // Chat echoes every event back
+			for {
+				ev := dynamicpb.NewMessage(eventDesc)
+				if err := stream.RecvMsg(ev); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if err := stream.SendMsg(ev); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(handler))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return reg, lis.Addr().String()
+}
+
+// nextEvent waits for the next stream event, failing the test on timeout or a closed channel.
+func nextEvent(t *testing.T, sess *StreamSession) StreamEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-sess.Events():
+		if !ok {
+			t.Fatal("event channel closed unexpectedly")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream event")
+	}
+	return StreamEvent{}
+}
+
+// nextNonHeaderEvent skips header events.
+func nextNonHeaderEvent(t *testing.T, sess *StreamSession) StreamEvent {
+	t.Helper()
+	for {
+		ev := nextEvent(t, sess)
+		if ev.Type != StreamEventHeader {
+			return ev
+		}
+	}
+}
+
+func TestOpenStream_ServerStreaming(t *testing.T) {
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+
+	sess, err := svc.OpenStream(&StreamReq{
+		URL:           addr,
+		ServiceMethod: "feed.v1.Feed/Watch",
+		Messages: [][]types.BodyField{{
+			{Path: "topic", Value: "orders"},
+			{Path: "count", Value: "3"},
+		}},
+		HalfClose: true,
+	})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	if !sess.ServerStreaming || sess.ClientStreaming {
+		t.Fatalf("unexpected streaming flags: %+v", sess)
+	}
+
+	for i := 0; i < 3; i++ {
+		ev := nextNonHeaderEvent(t, sess)
+		if ev.Type != StreamEventMessage || ev.Index != i {
+			t.Fatalf("expected message %d, got %+v", i, ev)
+		}
+		if !strings.Contains(ev.Decoded, "orders") {
+			t.Fatalf("expected decoded event, got %q (decodeError=%q)", ev.Decoded, ev.DecodeError)
+		}
+	}
+
+	end := nextNonHeaderEvent(t, sess)
+	if end.Type != StreamEventEnd || end.GRPCStatus == nil || end.GRPCStatus.Code != 0 {
+		t.Fatalf("expected OK end event, got %+v", end)
+	}
+	if _, open := <-sess.Events(); open {
+		t.Fatal("expected event channel to be closed after end")
+	}
+}
+
+func TestOpenStream_ClientStreaming(t *testing.T) {
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+
+	sess, err := svc.OpenStream(&StreamReq{URL: addr, ServiceMethod: "feed.v1.Feed/Upload"})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := sess.Send([]types.BodyField{{Path: "topic", Value: "t"}}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	if err := sess.CloseSend(); err != nil {
+		t.Fatalf("CloseSend failed: %v", err)
+	}
+
+	ev := nextNonHeaderEvent(t, sess)
+	if ev.Type != StreamEventMessage || !strings.Contains(ev.Decoded, "2") {
+		t.Fatalf("expected ack for 2 messages, got %+v", ev)
+	}
+	end := nextNonHeaderEvent(t, sess)
+	if end.Type != StreamEventEnd || end.GRPCStatus.Code != 0 {
+		t.Fatalf("expected OK end event, got %+v", end)
+	}
+}
+
+func TestOpenStream_BidiCancel(t *testing.T) {
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+
+	sess, err := svc.OpenStream(&StreamReq{URL: addr, ServiceMethod: "feed.v1.Feed/Chat"})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	if err := sess.Send([]types.BodyField{{Path: "topic", Value: "ping"}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	ev := nextNonHeaderEvent(t, sess)
+	if ev.Type != StreamEventMessage || !strings.Contains(ev.Decoded, "ping") {
+		t.Fatalf("expected echoed message, got %+v", ev)
+	}
+
+	sess.Cancel()
+	if _, ok := svc.GetStream(sess.ID); ok {
+		t.Fatal("expected cancelled session to be released")
+	}
+	end := nextNonHeaderEvent(t, sess)
+	if end.Type != StreamEventEnd || end.GRPCStatus == nil || end.GRPCStatus.Name != "Canceled" {
+		t.Fatalf("expected Canceled end event, got %+v", end)
+	}
+}
+
+func TestOpenStream_InterpolatesMessages(t *testing.T) {
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+
+	sess, err := svc.OpenStream(&StreamReq{
+		URL:           "{{host}}",
+		ServiceMethod: "feed.v1.Feed/Chat",
+		Messages:      [][]types.BodyField{{{Path: "topic", Value: "{{topic}}-{{$uuid}}"}, {Path: "seq", Value: "{{seq}}"}}},
+		Variables:     map[string]string{"host": addr, "topic": "orders", "seq": "7"},
+	})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	defer sess.Cancel()
+
+	first := nextNonHeaderEvent(t, sess)
+	if !strings.Contains(first.Decoded, `"orders-`) || !strings.Contains(strings.ReplaceAll(first.Decoded, " ", ""), `"seq":7`) {
+		t.Fatalf("expected interpolated message, got %q", first.Decoded)
+	}
+	if err := sess.Send([]types.BodyField{{Path: "topic", Value: "{{topic}}-{{$uuid}}"}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	second := nextNonHeaderEvent(t, sess)
+	topic := func(decoded string) string {
+		start := strings.Index(decoded, `"orders-`)
+		return decoded[start : start+len(`"orders-`)+36]
+	}
+	if topic(second.Decoded) != topic(first.Decoded) {
+		t.Errorf("expected $uuid to keep its value within the session, got %q and %q", first.Decoded, second.Decoded)
+	}
+}
+
+func TestOpenStream_StrictVariables(t *testing.T) {
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+
+	sess, err := svc.OpenStream(&StreamReq{
+		URL:             "{{host}}",
+		ServiceMethod:   "feed.v1.Feed/Chat",
+		Variables:       map[string]string{"host": addr, "a": "{{b}}", "b": "{{a}}"},
+		StrictVariables: true,
+	})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	defer sess.Cancel()
+
+	err = sess.Send([]types.BodyField{{Path: "topic", Value: "{{topic}}"}})
+	var unresolvedErr *UnresolvedVariablesError
+	if !errors.As(err, &unresolvedErr) {
+		t.Fatalf("expected an unresolved variables error, got %v", err)
+	}
+	if want := []UnresolvedVariable{{Name: "topic", Location: LocationBody, Key: "topic"}}; !reflect.DeepEqual(unresolvedErr.Variables, want) {
+		t.Errorf("expected %+v, got %+v", want, unresolvedErr.Variables)
+	}
+
+	var cycleErr *interpolate.CycleError
+	if err := sess.Send([]types.BodyField{{Path: "topic", Value: "{{a}}"}}); !errors.As(err, &cycleErr) {
+		t.Errorf("expected a variable cycle error, got %v", err)
+	}
+
+	if _, err := svc.OpenStream(&StreamReq{URL: "{{host}}", ServiceMethod: "feed.v1.Feed/Chat", StrictVariables: true}); !errors.As(err, &unresolvedErr) {
+		t.Errorf("expected an unresolved host to stop the stream from opening, got %v", err)
+	}
+}
+
+func TestOpenStream_LoadsSavedRequestFiles(t *testing.T) {
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+	svc.SetFileResolver(func(collectionID, requestID, id string) ([]byte, error) {
+		if collectionID != "c-1" || requestID != "r-1" {
+			return nil, fmt.Errorf("no file %s in request %s", id, requestID)
+		}
+		return []byte{0x00, 0xff}, nil
+	})
+	file := []types.BodyField{{Path: "payload", Value: "f-1", Encoding: types.BytesFile}}
+
+	if _, err := svc.OpenStream(&StreamReq{URL: addr, ServiceMethod: "feed.v1.Feed/Chat", Messages: [][]types.BodyField{file}}); err == nil || !strings.Contains(err.Error(), "needs a saved request") {
+		t.Fatalf("expected a file without a saved request to be rejected, got %v", err)
+	}
+
+	sess, err := svc.OpenStream(&StreamReq{
+		URL:           addr,
+		ServiceMethod: "feed.v1.Feed/Chat",
+		CollectionID:  "c-1",
+		RequestID:     "r-1",
+	})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	defer sess.Cancel()
+	if err := sess.Send(file); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if ev := nextNonHeaderEvent(t, sess); !strings.Contains(ev.Decoded, `"AP8="`) {
+		t.Errorf("expected the uploaded file as payload, got %q", ev.Decoded)
+	}
+}
+
+func TestOpenStream_ReleasesAbandonedSessions(t *testing.T) {
+	defer func(timeout time.Duration) { streamIdleTimeout = timeout }(streamIdleTimeout)
+	streamIdleTimeout = 50 * time.Millisecond
+
+	reg, addr := startFeedServer(t)
+	svc := NewService(reg)
+
+	// More events than the buffer holds, with nobody reading them
+	sess, err := svc.OpenStream(&StreamReq{
+		URL:           addr,
+		ServiceMethod: "feed.v1.Feed/Watch",
+		Messages:      [][]types.BodyField{{{Path: "topic", Value: "orders"}, {Path: "count", Value: "200"}}},
+		HalfClose:     true,
+	})
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := svc.GetStream(sess.ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the abandoned session to be released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sess.ctx.Err() == nil {
+		t.Error("expected the abandoned call to be cancelled")
+	}
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// twirpPathPrefix is the route prefix Twirp servers mount services under
+const twirpPathPrefix = "/twirp"
+
+// twirpContentType is the Content-Type of protobuf-encoded Twirp requests and responses
+const twirpContentType = "application/protobuf"
+
+// twirpJSONContentType is the Content-Type of JSON-encoded Twirp requests and responses
+const twirpJSONContentType = "application/json"
+
+// unwrapTwirp parses the JSON error envelope of a failed Twirp call
+func unwrapTwirp(resp *ResponseContext) {
+	if resp.Status == http.StatusOK || !strings.Contains(resp.ContentType, "application/json") {
+		return
+	}
+
+	var twirpErr TwirpError
+	if err := json.Unmarshal(resp.Body, &twirpErr); err != nil || twirpErr.Code == "" {
+		return
+	}
+	resp.TwirpError = &twirpErr
+}
+
+// twirpJSONBody rewrites an encoded request message as protojson for a Twirp JSON call. An
+// empty body is sent as {}.
+func (s *Service) twirpJSONBody(messageType string, body interface{}) ([]byte, error) {
+	switch b := body.(type) {
+	case nil:
+		return []byte("{}"), nil
+	case []byte:
+		md, err := s.registry.GetMessageDescriptor(messageType)
+		if err != nil {
+			return nil, fmt.Errorf("message type %s not found: %w", messageType, err)
+		}
+		msg := dynamicpb.NewMessage(md)
+		if err := (proto.UnmarshalOptions{Resolver: s.typeResolver()}).Unmarshal(b, msg); err != nil {
+			return nil, err
+		}
+		return protojson.MarshalOptions{Resolver: s.typeResolver()}.Marshal(msg)
+	}
+	return nil, fmt.Errorf("%s requests require a protobuf message type", ProtocolTwirpJSON)
+}
+
+// decodeTwirpJSON re-encodes a successful Twirp JSON response as the protobuf message the
+// response decoder expects. Body keeps the JSON for RunRes.Raw.
+func (s *Service) decodeTwirpJSON(messageType string, resp *ResponseContext) error {
+	if resp.Status != http.StatusOK || messageType == "" || !strings.Contains(resp.ContentType, twirpJSONContentType) {
+		return nil
+	}
+	md, err := s.registry.GetMessageDescriptor(messageType)
+	if err != nil {
+		return fmt.Errorf("message type %s not found: %w", messageType, err)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := (protojson.UnmarshalOptions{Resolver: s.typeResolver(), DiscardUnknown: true}).Unmarshal(resp.Body, msg); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+	encoded, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp.Messages = [][]byte{encoded}
+	resp.ContentType = twirpContentType
+	return nil
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestRunTwirp(t *testing.T) {
+	svc := NewService(setupRegistry(t, "greet.proto"))
+	method, _ := svc.registry.GetMethodDescriptor("greet.v1.Greeter/SayHello")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/twirp/greet.v1.Greeter/SayHello" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"bad_route","msg":"no handler for path"}`))
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/protobuf" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		in := dynamicpb.NewMessage(method.Input())
+		_ = proto.Unmarshal(body, in)
+		name := in.Get(method.Input().Fields().ByName("name")).String()
+
+		if name == "missing" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"not_found","msg":"no such person","meta":{"name":"missing"}}`))
+			return
+		}
+		out := dynamicpb.NewMessage(method.Output())
+		out.Set(method.Output().Fields().ByName("message"), protoreflect.ValueOfString("Hello "+name))
+		b, _ := proto.Marshal(out)
+		w.Header().Set("Content-Type", "application/protobuf")
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	run := func(name string) *RunRes {
+		res, err := svc.Run(&RunReq{
+			Method:        "GET",
+			URL:           srv.URL + "/api",
+			Protocol:      ProtocolTwirp,
+			ServiceMethod: "greet.v1.Greeter/SayHello",
+			Body:          []types.BodyField{{Path: "name", Value: name}},
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return res
+	}
+
+	res := run("Ada")
+	if res.Status != 200 || res.TwirpError != nil {
+		t.Fatalf("expected success, got %d / %+v", res.Status, res.TwirpError)
+	}
+	if !strings.Contains(res.Decoded, "Hello Ada") {
+		t.Fatalf("expected decoded reply, got %q (decodeError=%q)", res.Decoded, res.DecodeError)
+	}
+
+	res = run("missing")
+	if res.Status != 404 || res.TwirpError == nil {
+		t.Fatalf("expected Twirp error, got %d / %+v", res.Status, res.TwirpError)
+	}
+	if res.TwirpError.Code != "not_found" || res.TwirpError.Msg != "no such person" || res.TwirpError.Meta["name"] != "missing" {
+		t.Fatalf("unexpected Twirp error: %+v", res.TwirpError)
+	}
+}
+
+func TestRunTwirp_JSON(t *testing.T) {
+	svc := NewService(setupRegistry(t, "greet.proto"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/twirp/greet.v1.Greeter/SayHello" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s with content type %q", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var in struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("expected a JSON body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"message": "Hello ` + in.Name + `"}`))
+	}))
+	defer srv.Close()
+
+	res, err := svc.Run(&RunReq{
+		Method:        "POST",
+		URL:           srv.URL,
+		Protocol:      ProtocolTwirpJSON,
+		ServiceMethod: "greet.v1.Greeter/SayHello",
+		Body:          []types.BodyField{{Path: "name", Value: "Ada"}},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Status != 200 || !strings.Contains(res.Decoded, "Hello Ada") {
+		t.Fatalf("expected decoded reply, got %d %q (decodeError=%q)", res.Status, res.Decoded, res.DecodeError)
+	}
+	if res.Raw != `{"message": "Hello Ada"}` {
+		t.Errorf("expected the raw JSON response, got %q", res.Raw)
+	}
+}
@@ -1,47 +1,208 @@
 package runner
 
 import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/datahopper/backend/internal/rawproto"
+	"github.com/datahopper/backend/internal/registry"
 	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Supported values for RunReq.Protocol
+const (
+	ProtocolHTTP          = "http"           // Plain HTTP request (default)
+	ProtocolGRPC          = "grpc"           // Native gRPC call over HTTP/2
+	ProtocolConnect       = "connect"        // Connect unary (application/proto)
+	ProtocolConnectStream = "connect-stream" // Connect streaming (application/connect+proto)
+	ProtocolGRPCWeb       = "grpc-web"       // gRPC-Web binary (application/grpc-web+proto)
+	ProtocolGRPCWebText   = "grpc-web-text"  // gRPC-Web text (application/grpc-web-text+proto)
+	ProtocolTwirp         = "twirp"          // Twirp (POST /twirp/pkg.Service/Method)
+	ProtocolTwirpJSON     = "twirp-json"     // Twirp with protojson bodies (application/json)
 )
 
 // RunReq represents a request to execute an HTTP request
 type RunReq struct {
-	Method          string                    `json:"method" binding:"required"`
-	URL             string                    `json:"url" binding:"required"`
-	ProtoMessage    string                    `json:"protoMessage,omitempty"`    // FQN of request message type
-    ResponseType    string                    `json:"responseType,omitempty"`    // FQN of success response message type
-    ErrorResponseType string                  `json:"errorResponseType,omitempty"` // FQN of error response message type
-	Headers         map[string]string         `json:"headers"`
+	Method             string                      `json:"method"`
+	URL                string                      `json:"url" binding:"required"`
+	ProtoMessage       string                      `json:"protoMessage,omitempty"`      // FQN of request message type
+	ResponseType       string                      `json:"responseType,omitempty"`      // FQN of success response message type
+	ErrorResponseType  string                      `json:"errorResponseType,omitempty"` // FQN of error response message type
+	Headers            map[string]string           `json:"headers"`
+	BodyMode           string                      `json:"bodyMode,omitempty"` // fields (default), json or text
+	Body               []types.BodyField           `json:"body"`
+	RawBody            string                      `json:"rawBody,omitempty"` // Body document for the json and text modes
+	TimeoutSeconds     int                         `json:"timeoutSeconds"`
+	Variables          map[string]string           `json:"variables"`
+	Protocol           string                      `json:"protocol,omitempty"`           // http (default), grpc, connect, connect-stream, grpc-web, grpc-web-text or twirp
+	ServiceMethod      string                      `json:"serviceMethod,omitempty"`      // RPC method, e.g. pkg.Service/Method
+	ResponseTypes      []types.ResponseTypeMatcher `json:"responseTypes,omitempty"`      // Ordered status matchers; first match wins
+	OneofSelections    types.OneofSelections       `json:"oneofSelections,omitempty"`    // Selected member per oneof group, keyed by group dot-path
+	EnforceConstraints bool                        `json:"enforceConstraints,omitempty"` // Reject bodies that break buf.validate / validate.rules options
+	BytesDisplay       types.BytesDisplay          `json:"bytesDisplay,omitempty"`       // How bytes fields of decoded responses are shown, per field path
+	StrictVariables    bool                        `json:"strictVariables,omitempty"`    // Reject runs with unresolved placeholders instead of warning
+	CollectionID       string                      `json:"collectionId,omitempty"`       // Saved request being run, whose uploaded files BytesFile fields reference
+	RequestID          string                      `json:"requestId,omitempty"`
+}
+
+func (r *RunReq) fileOwner() fileOwner {
+	return fileOwner{collectionID: r.CollectionID, requestID: r.RequestID}
+}
+
+// ValidateMethod requires an HTTP method for plain HTTP runs; RPC protocols choose their own
+func (r *RunReq) ValidateMethod() error {
+	if r.Method == "" && (r.Protocol == "" || r.Protocol == ProtocolHTTP) {
+		return fmt.Errorf("method is required for http requests")
+	}
+	return nil
+}
+
+// ConvertBodyReq asks for a request body to be rewritten in another body mode
+type ConvertBodyReq struct {
+	MessageType     string                `json:"messageType" binding:"required"`
+	From            string                `json:"from"` // Mode of the given body; fields when empty
+	To              string                `json:"to" binding:"required"`
 	Body            []types.BodyField     `json:"body"`
-	TimeoutSeconds  int                       `json:"timeoutSeconds"`
-	Variables       map[string]string         `json:"variables"`
+	RawBody         string                `json:"rawBody"`
+	OneofSelections types.OneofSelections `json:"oneofSelections,omitempty"`
+	CollectionID    string                `json:"collectionId,omitempty"` // Saved request whose uploaded files BytesFile fields reference
+	RequestID       string                `json:"requestId,omitempty"`
+}
+
+func (r *ConvertBodyReq) fileOwner() fileOwner {
+	return fileOwner{collectionID: r.CollectionID, requestID: r.RequestID}
+}
+
+// FlattenBodyReq asks for a JSON document, such as a RunRes.Decoded response, to be turned
+// into dot-path body fields
+type FlattenBodyReq struct {
+	MessageType string `json:"messageType,omitempty"` // Reads JSON as protojson of this type when set
+	JSON        string `json:"json" binding:"required"`
+}
+
+// ConvertBodyRes is a body rewritten by ConvertBody; Body is set for the fields mode and
+// RawBody for the others
+type ConvertBodyRes struct {
+	Mode    string            `json:"mode"`
+	Body    []types.BodyField `json:"body,omitempty"`
+	RawBody string            `json:"rawBody,omitempty"`
 }
 
 // RunRes represents the response from executing an HTTP request
 type RunRes struct {
-	Status  int               `json:"status"`
-	Headers map[string]string `json:"headers"`
-	Decoded string            `json:"decoded,omitempty"` // JSON representation if protobuf response
-	Raw     string            `json:"raw,omitempty"`     // Raw response body
-    DecodeError string        `json:"decodeError,omitempty"`
+	Status         int                      `json:"status"`
+	Headers        map[string]string        `json:"headers"`
+	Decoded        string                   `json:"decoded,omitempty"` // JSON representation if protobuf response; a JSON array for multi-message streams
+	Raw            string                   `json:"raw,omitempty"`     // Raw response body
+	DecodeError    string                   `json:"decodeError,omitempty"`
+	Trailers       map[string]string        `json:"trailers,omitempty"`       // RPC trailer metadata
+	GRPCStatus     *GRPCStatus              `json:"grpcStatus,omitempty"`     // Set for gRPC, gRPC-Web and Connect calls
+	TwirpError     *TwirpError              `json:"twirpError,omitempty"`     // Set when a Twirp call returns an error
+	RawDecoded     []rawproto.Field         `json:"rawDecoded,omitempty"`     // Schema-free decode when no type was selected or decoding failed
+	SuggestedTypes []registry.TypeCandidate `json:"suggestedTypes,omitempty"` // Better-fitting types when decoding looks wrong
+	ErrorStatus    *ErrorStatus             `json:"errorStatus,omitempty"`    // google.rpc.Status from the body or error details, with unpacked Any details
+	Violations     []FieldError             `json:"violations,omitempty"`     // Decoded fields that break buf.validate / validate.rules options
+	Warnings       []UnresolvedVariable     `json:"warnings,omitempty"`       // Placeholders sent without a value by a lenient run
+	FieldWarnings  []FieldError             `json:"fieldWarnings,omitempty"`  // Body fields sent despite a finding, such as deprecated fields
+}
+
+// suggestedTypeLimit caps RunRes.SuggestedTypes
+const suggestedTypeLimit = 3
+
+// GRPCStatus carries the status returned by an RPC call. Connect error codes are mapped to
+// their gRPC equivalents.
+type GRPCStatus struct {
+	Code    int    `json:"code"`
+	Name    string `json:"name"`
+	Message string `json:"message,omitempty"`
+}
+
+// ErrorStatus is a decoded google.rpc.Status
+type ErrorStatus struct {
+	Code    int           `json:"code"`
+	Name    string        `json:"name,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Details []ErrorDetail `json:"details"`
+}
+
+// ErrorDetail is one google.protobuf.Any detail of an ErrorStatus. Value holds the decoded
+// message when its type is registered; otherwise Raw keeps the base64-encoded bytes.
+type ErrorDetail struct {
+	TypeURL     string          `json:"typeUrl"`
+	Type        string          `json:"type"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	Raw         string          `json:"raw,omitempty"`
+	DecodeError string          `json:"decodeError,omitempty"`
+}
+
+// TwirpError is the JSON error envelope returned by Twirp services
+type TwirpError struct {
+	Code string            `json:"code"`
+	Msg  string            `json:"msg"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// StreamReq represents a request to open a streaming gRPC call
+type StreamReq struct {
+	URL             string              `json:"url" binding:"required"`
+	ServiceMethod   string              `json:"serviceMethod" binding:"required"` // RPC method, e.g. pkg.Service/Method
+	Headers         map[string]string   `json:"headers"`
+	Messages        [][]types.BodyField `json:"messages"`       // Messages sent as soon as the stream opens
+	HalfClose       bool                `json:"halfClose"`      // Close the send side after Messages are sent
+	TimeoutSeconds  int                 `json:"timeoutSeconds"` // 0 keeps the stream open until it ends or is cancelled
+	Variables       map[string]string   `json:"variables"`
+	StrictVariables bool                `json:"strictVariables,omitempty"` // Reject messages with unresolved placeholders instead of sending them as they are
+	CollectionID    string              `json:"collectionId,omitempty"`    // Saved request being streamed, whose uploaded files BytesFile fields reference
+	RequestID       string              `json:"requestId,omitempty"`
+}
+
+func (r *StreamReq) fileOwner() fileOwner {
+	return fileOwner{collectionID: r.CollectionID, requestID: r.RequestID}
+}
+
+// Stream event types delivered by StreamSession.Events
+const (
+	StreamEventHeader  = "header"  // Response header metadata arrived
+	StreamEventMessage = "message" // A response message was received
+	StreamEventEnd     = "end"     // The call finished; carries trailers and final status
+)
+
+// StreamEvent is a single event observed on a streaming call
+type StreamEvent struct {
+	Type        string            `json:"type"`
+	Index       int               `json:"index"` // Sequence number of received messages, starting at 0
+	Decoded     string            `json:"decoded,omitempty"`
+	DecodeError string            `json:"decodeError,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Trailers    map[string]string `json:"trailers,omitempty"`
+	GRPCStatus  *GRPCStatus       `json:"grpcStatus,omitempty"`
+	ErrorStatus *ErrorStatus      `json:"errorStatus,omitempty"`
 }
 
 // RequestContext contains the context for executing a request
 type RequestContext struct {
-	Method          string
-	URL             string
-	Headers         map[string]string
-	Body            interface{}
-	TimeoutSeconds  int
-	ProtoMessage    string
-    ResponseType    string
-    ErrorResponseType string
+	Method            string
+	URL               string
+	Headers           map[string]string
+	Body              interface{}
+	TimeoutSeconds    int
+	ProtoMessage      string
+	ResponseType      string
+	ErrorResponseType string
+	Unresolved        []UnresolvedVariable // Placeholders left in URL, headers or body
+	FieldWarnings     []FieldError         // Deprecated body fields
 }
 
 // ResponseContext contains the response data
 type ResponseContext struct {
-	Status     int
-	Headers    map[string]string
-	Body       []byte
-	ContentType string
+	Status       int
+	Headers      map[string]string
+	Body         []byte
+	ContentType  string
+	Trailers     map[string]string
+	GRPCStatus   *GRPCStatus
+	TwirpError   *TwirpError
+	ErrorDetails []*anypb.Any // Any details carried with a failed RPC
+	Messages     [][]byte     // Unframed messages for enveloped protocols; nil when Body is the message
 }
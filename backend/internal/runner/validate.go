@@ -0,0 +1,602 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datahopper/backend/internal/dotpath"
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Codes reported in FieldError.Code
+const (
+	FieldErrorInvalidPath    = "invalid_path"
+	FieldErrorUnknownField   = "unknown_field"
+	FieldErrorTypeMismatch   = "type_mismatch"
+	FieldErrorOutOfRange     = "out_of_range"
+	FieldErrorInvalidEnum    = "invalid_enum"
+	FieldErrorOneofConflict  = "oneof_conflict"
+	FieldErrorDeprecated     = "deprecated_field"
+	FieldErrorUnresolvedType = "unresolved_type"
+)
+
+// FieldError describes one body field that does not fit the request message
+type FieldError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BodyValidationError is returned when body fields do not match the request message
+type BodyValidationError struct {
+	MessageType string       `json:"messageType"`
+	Errors      []FieldError `json:"errors"`
+}
+
+func (e *BodyValidationError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("body does not match %s", e.MessageType)
+	}
+	first := e.Errors[0]
+	msg := fmt.Sprintf("body does not match %s: %s: %s", e.MessageType, first.Path, first.Message)
+	if len(e.Errors) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Errors)-1)
+	}
+	return msg
+}
+
+// templateMarker flags values that still hold a {{variable}} and cannot be type-checked yet
+const templateMarker = "{{"
+
+// ValidateRun checks the body of req against the message it will be encoded as,
+// resolving RPC input types the same way Run does, and against the message's validation
+// rules when req.EnforceConstraints is set. Raw JSON and text-format bodies are checked by
+// parsing them. Bodies sent as plain JSON are not checked and yield no errors. Deprecated
+// fields do not stop a run and are returned as warnings.
+func (s *Service) ValidateRun(req *RunReq) (fieldErrors, warnings []FieldError, err error) {
+	switch {
+	case req.Protocol == ProtocolGRPC:
+		resolved, _, err := s.resolveRPCMethod(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve gRPC method: %w", err)
+		}
+		req = resolved
+	case isWebRPCProtocol(req.Protocol):
+		resolved, err := s.resolveWebRPC(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve %s method: %w", req.Protocol, err)
+		}
+		req = resolved
+	}
+	if req.ProtoMessage == "" {
+		return []FieldError{}, nil, nil
+	}
+	if isRawBodyMode(req.BodyMode) {
+		encoded, fieldErrors, err := s.validateRawBody(req)
+		if err != nil || len(fieldErrors) > 0 || !req.EnforceConstraints || encoded == nil {
+			return fieldErrors, nil, err
+		}
+		fieldErrors, err = s.checkEncodedConstraints(req.ProtoMessage, encoded)
+		return fieldErrors, nil, err
+	}
+	v, err := s.validateBody(req.ProtoMessage, req.Body, req.OneofSelections, req.fileOwner())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(v.errors) > 0 || !req.EnforceConstraints {
+		return v.errors, v.warnings, nil
+	}
+
+	// The body fits the descriptor; check its validation rules on the encoded message
+	body, _, err := s.buildBody(req.ProtoMessage, req.Body, req.OneofSelections, req.fileOwner())
+	if err != nil {
+		return nil, nil, err
+	}
+	encoded, ok := body.([]byte)
+	if !ok {
+		return v.errors, v.warnings, nil
+	}
+	fieldErrors, err = s.checkEncodedConstraints(req.ProtoMessage, encoded)
+	return fieldErrors, v.warnings, err
+}
+
+// ValidateBody checks every dot-path field against the descriptor of messageType and
+// reports unknown fields, type mismatches, undefined enum values and oneof conflicts.
+// Paths set for a oneof member other than the one in oneofs are conflicts, as are paths
+// for two members of a group without a selection. Presence modes are checked against the
+// field they name. Deprecated fields are not errors; ValidateRun reports them as warnings.
+// There is no saved request to load files from, so BytesFile fields are reported as
+// needing one; ValidateRun loads them. The error is only set when the message type cannot
+// be resolved.
+func (s *Service) ValidateBody(messageType string, fields []types.BodyField, oneofs types.OneofSelections) ([]FieldError, error) {
+	v, err := s.validateBody(messageType, fields, oneofs, fileOwner{})
+	if err != nil {
+		return nil, err
+	}
+	return v.errors, nil
+}
+
+// validateBody runs ValidateBody and keeps the validator for the fields it resolved
+func (s *Service) validateBody(messageType string, fields []types.BodyField, oneofs types.OneofSelections, owner fileOwner) (*bodyValidator, error) {
+	if s.registry == nil {
+		return nil, fmt.Errorf("registry not configured")
+	}
+	md, err := s.registry.GetMessageDescriptor(messageType)
+	if err != nil {
+		return nil, fmt.Errorf("message descriptor not found: %s", messageType)
+	}
+
+	v := &bodyValidator{
+		svc:        s,
+		owner:      owner,
+		root:       md,
+		anyTypes:   map[string]protoreflect.MessageDescriptor{},
+		oneofs:     map[string]oneofMember{},
+		selected:   map[string]protoreflect.Name{},
+		presence:   map[string]protoreflect.FieldDescriptor{},
+		bytes:      map[string]string{},
+		deprecated: map[string]bool{},
+		errors:     []FieldError{},
+	}
+	// Any fields may list "@type" after their other fields, so resolve those first
+	for _, field := range fields {
+		if prefix, ok := anyTypePrefix(field.Path); ok {
+			v.resolveAny(prefix, dotpath.CoerceValue(field.Value))
+		}
+	}
+	v.resolveOneofSelections(oneofs)
+	for _, field := range fields {
+		if hasBytesEncoding(field) {
+			encoded, ok := v.decodeBytes(field)
+			if !ok {
+				continue
+			}
+			field.Value = encoded
+		}
+		if field.Presence == "" {
+			v.validatePath(field.Path, dotpath.CoerceValue(field.Value))
+			continue
+		}
+		v.checkPresence(field)
+		if fd, ok := v.presence[field.Path]; ok {
+			if value, send := presenceValue(fd, field); send {
+				v.validatePath(field.Path, dotpath.CoerceValue(value))
+			}
+		}
+	}
+	return v, nil
+}
+
+// bodyValidator accumulates errors and warnings across the fields of one body
+type bodyValidator struct {
+	svc        *Service
+	owner      fileOwner // Saved request whose files BytesFile fields may load
+	root       protoreflect.MessageDescriptor
+	anyTypes   map[string]protoreflect.MessageDescriptor // Packed type per Any path prefix
+	oneofs     map[string]oneofMember                    // Member set per message instance and oneof
+	selected   map[string]protoreflect.Name              // Explicitly selected member per oneof group key
+	presence   map[string]protoreflect.FieldDescriptor   // Field per path with a valid presence mode
+	bytes      map[string]string                         // Base64 value per path of bytes fields with an encoding
+	deprecated map[string]bool                           // Deprecated field paths already reported
+	errors     []FieldError
+	warnings   []FieldError // Findings that do not stop the body from being sent
+}
+
+// oneofMember records which member of a oneof was set first, and by which path
+type oneofMember struct {
+	field protoreflect.Name
+	path  string
+}
+
+func (v *bodyValidator) fail(path, code, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Path: path, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *bodyValidator) warn(path, code, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, FieldError{Path: path, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolveAny records the packed type named by an "@type" value for the Any at prefix
+func (v *bodyValidator) resolveAny(prefix string, typeName interface{}) {
+	name, ok := typeName.(string)
+	if !ok {
+		return
+	}
+	if mt, err := v.svc.typeResolver().FindMessageByURL(anyTypeURL(name)); err == nil {
+		v.anyTypes[prefix] = mt.Descriptor()
+	}
+}
+
+// validatePath walks path from the root message and checks value against the field it ends on
+func (v *bodyValidator) validatePath(path string, value interface{}) {
+	segs, msg := parseBodyPath(path)
+	if segs == nil {
+		v.fail(path, FieldErrorInvalidPath, "%s", msg)
+		return
+	}
+	prefix := ""
+	cur := v.root
+
+	for i := 0; i < len(segs); i++ {
+		seg := segs[i]
+		last := i == len(segs)-1
+
+		switch string(cur.FullName()) {
+		case structTypeName, valueTypeName, listValueTypeName:
+			// Free-form JSON below this point
+			return
+		case anyTypeName:
+			if seg.Kind == dotpath.KeySegment && seg.Key == anyTypeKey {
+				if !last {
+					v.fail(path, FieldErrorInvalidPath, "%s has no sub-fields", anyTypeKey)
+				} else if _, ok := v.anyTypes[prefix]; !ok {
+					v.fail(path, FieldErrorUnresolvedType, "message type %v is not registered", value)
+				}
+				return
+			}
+			packed, ok := v.anyTypes[prefix]
+			if !ok {
+				v.fail(path, FieldErrorUnresolvedType, "set %s before the fields of %s", joinPath(prefix, anyTypeKey), anyTypeName)
+				return
+			}
+			cur = packed
+		}
+
+		if seg.Kind != dotpath.KeySegment {
+			v.fail(path, FieldErrorInvalidPath, "invalid path segment %q: %s has no repeated field to index", seg.Text, cur.FullName())
+			return
+		}
+		name := seg.Key
+		index := indexAfter(segs, i)
+
+		fd := lookupField(cur, name)
+		if fd == nil {
+			v.fail(path, FieldErrorUnknownField, "%s has no field %q", cur.FullName(), name)
+			return
+		}
+		if fieldPath := joinPath(prefix, name); isDeprecatedField(fd) && !v.deprecated[fieldPath] {
+			v.deprecated[fieldPath] = true
+			v.warn(fieldPath, FieldErrorDeprecated, "field %s is deprecated", fd.FullName())
+		}
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			key := prefix + "|" + string(od.FullName())
+			if selected, ok := v.selected[joinPath(prefix, string(od.Name()))]; ok {
+				if selected != fd.Name() {
+					v.fail(path, FieldErrorOneofConflict, "oneof %s selects %s, not %s", od.Name(), selected, fd.Name())
+					return
+				}
+			} else if first, exists := v.oneofs[key]; !exists {
+				v.oneofs[key] = oneofMember{field: fd.Name(), path: path}
+			} else if first.field != fd.Name() {
+				v.fail(path, FieldErrorOneofConflict, "oneof %s already has %s set by %q", od.Name(), first.field, first.path)
+			}
+		}
+
+		switch {
+		case fd.IsMap():
+			if index != nil {
+				v.fail(path, FieldErrorInvalidPath, "map field %s does not take an index", fd.Name())
+				return
+			}
+			if last {
+				v.checkValue(path, fd, value, false)
+				return
+			}
+			key := segs[i+1].Key
+			if err := checkMapKey(fd.MapKey(), key); err != "" {
+				v.fail(path, FieldErrorTypeMismatch, "map key %q: %s", key, err)
+				return
+			}
+			if i+1 == len(segs)-1 {
+				v.checkValue(path, fd.MapValue(), value, true)
+				return
+			}
+			if fd.MapValue().Message() == nil {
+				v.fail(path, FieldErrorInvalidPath, "map %s has %s values, which have no sub-fields", fd.Name(), fd.MapValue().Kind())
+				return
+			}
+			prefix = joinPath(joinPath(prefix, name), key)
+			cur = fd.MapValue().Message()
+			i++
+			continue
+
+		case index != nil && !fd.IsList():
+			v.fail(path, FieldErrorInvalidPath, "field %s is not repeated", fd.Name())
+			return
+
+		case fd.IsList() && index == nil && !last:
+			v.fail(path, FieldErrorInvalidPath, "repeated field %s needs an index", fd.Name())
+			return
+		}
+
+		prefix = joinPath(prefix, name)
+		if index != nil {
+			prefix += index.String()
+			i++
+			last = i == len(segs)-1
+		}
+		if last {
+			v.checkValue(path, fd, value, index != nil)
+			return
+		}
+		if fd.Message() == nil {
+			v.fail(path, FieldErrorInvalidPath, "%s field %s has no sub-fields", fd.Kind(), fd.Name())
+			return
+		}
+		cur = fd.Message()
+	}
+}
+
+// parseBodyPath parses a body field path, or explains why it does not parse
+func parseBodyPath(path string) ([]dotpath.Segment, string) {
+	segs, err := dotpath.ParsePath(path)
+	if err == nil {
+		return segs, ""
+	}
+	var pathErr *dotpath.PathError
+	if errors.As(err, &pathErr) {
+		if pathErr.Segment == "" {
+			return nil, pathErr.Reason
+		}
+		return nil, fmt.Sprintf("invalid path segment %q: %s", pathErr.Segment, pathErr.Reason)
+	}
+	return nil, err.Error()
+}
+
+// indexAfter returns the index or append segment that follows segs[i], if any. Repeated
+// fields take one; a second one in a row indexes a nested list, which messages never have.
+func indexAfter(segs []dotpath.Segment, i int) *dotpath.Segment {
+	if i+1 < len(segs) && segs[i+1].Kind != dotpath.KeySegment {
+		return &segs[i+1]
+	}
+	return nil
+}
+
+// checkValue checks a value assigned to fd; element is set when the value is a single
+// entry of a repeated field or map rather than the whole field
+func (v *bodyValidator) checkValue(path string, fd protoreflect.FieldDescriptor, value interface{}, element bool) {
+	if value == nil {
+		return
+	}
+	if s, ok := value.(string); ok && strings.Contains(s, templateMarker) {
+		return
+	}
+
+	if fd.IsMap() && !element {
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, FieldErrorTypeMismatch, "expected an object for map field %s, got %s", fd.Name(), describeValue(value))
+			return
+		}
+		for _, key := range sortedKeys(entries) {
+			v.validatePath(joinPath(path, key), entries[key])
+		}
+		return
+	}
+	if fd.IsList() && !element {
+		items, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, FieldErrorTypeMismatch, "expected a list for repeated field %s, got %s", fd.Name(), describeValue(value))
+			return
+		}
+		for idx, item := range items {
+			v.checkValue(fmt.Sprintf("%s[%d]", path, idx), fd, item, true)
+		}
+		return
+	}
+
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		if isWellKnownJSONType(string(fd.Message().FullName())) {
+			return
+		}
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, FieldErrorTypeMismatch, "expected an object for %s, got %s", fd.Message().FullName(), describeValue(value))
+			return
+		}
+		if fd.Message().FullName() == anyTypeName {
+			v.resolveAny(path, obj[anyTypeKey])
+		}
+		// Check nested objects field by field
+		for _, key := range sortedKeys(obj) {
+			v.validatePath(joinPath(path, key), obj[key])
+		}
+		return
+	}
+
+	if code, msg := checkScalar(fd, value); code != "" {
+		v.fail(path, code, "%s", msg)
+	}
+}
+
+// checkScalar checks a scalar or enum value against the field kind
+func checkScalar(fd protoreflect.FieldDescriptor, value interface{}) (string, string) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if _, ok := value.(bool); !ok {
+			return FieldErrorTypeMismatch, fmt.Sprintf("expected a bool, got %s", describeValue(value))
+		}
+	case protoreflect.StringKind:
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return FieldErrorTypeMismatch, fmt.Sprintf("expected a string, got %s", describeValue(value))
+		}
+	case protoreflect.BytesKind:
+		if _, ok := value.(string); !ok {
+			return FieldErrorTypeMismatch, fmt.Sprintf("expected a base64 string, got %s", describeValue(value))
+		}
+	case protoreflect.EnumKind:
+		return checkEnum(fd.Enum(), value)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		if _, ok := numberText(value); ok {
+			return "", ""
+		}
+		if t, ok := value.(string); ok && (t == "NaN" || t == "Infinity" || t == "-Infinity") {
+			return "", ""
+		}
+		return FieldErrorTypeMismatch, fmt.Sprintf("expected a number, got %s", describeValue(value))
+	default:
+		return checkInteger(fd.Kind(), value)
+	}
+	return "", ""
+}
+
+// checkInteger checks that value is an integer within the range of kind
+func checkInteger(kind protoreflect.Kind, value interface{}) (string, string) {
+	text, ok := numberText(value)
+	if !ok {
+		return FieldErrorTypeMismatch, fmt.Sprintf("expected an integer, got %s", describeValue(value))
+	}
+
+	bits, unsigned := 64, false
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		bits = 32
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		bits, unsigned = 32, true
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		unsigned = true
+	}
+	var err error
+	if unsigned {
+		_, err = strconv.ParseUint(text, 10, bits)
+	} else {
+		_, err = strconv.ParseInt(text, 10, bits)
+	}
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange || unsigned && strings.HasPrefix(text, "-") {
+			return FieldErrorOutOfRange, fmt.Sprintf("%s is out of range for %s", text, kind)
+		}
+		return FieldErrorTypeMismatch, fmt.Sprintf("expected an integer, got %s", describeValue(value))
+	}
+	return "", ""
+}
+
+// checkEnum accepts a defined value name or number
+func checkEnum(ed protoreflect.EnumDescriptor, value interface{}) (string, string) {
+	switch t := value.(type) {
+	case string:
+		if ed.Values().ByName(protoreflect.Name(t)) != nil {
+			return "", ""
+		}
+		return FieldErrorInvalidEnum, fmt.Sprintf("%q is not a value of %s", t, ed.FullName())
+	}
+	if text, ok := numberText(value); ok {
+		n, err := strconv.ParseInt(text, 10, 32)
+		if err == nil && ed.Values().ByNumber(protoreflect.EnumNumber(n)) != nil {
+			return "", ""
+		}
+		return FieldErrorInvalidEnum, fmt.Sprintf("%s is not a value of %s", text, ed.FullName())
+	}
+	return FieldErrorTypeMismatch, fmt.Sprintf("expected an enum name or number, got %s", describeValue(value))
+}
+
+// numberText renders a numeric value in decimal without exponent, reporting false for
+// anything that is not a number
+func numberText(value interface{}) (string, bool) {
+	switch t := value.(type) {
+	case json.Number:
+		return t.String(), true
+	case float64:
+		if math.IsInf(t, 0) || math.IsNaN(t) {
+			return "", false
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32), true
+	case int, int32, int64, uint, uint32, uint64:
+		return fmt.Sprint(t), true
+	}
+	return "", false
+}
+
+// checkMapKey reports why key cannot be parsed as the map's key kind, or "" when it can
+func checkMapKey(fd protoreflect.FieldDescriptor, key string) string {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return ""
+	case protoreflect.BoolKind:
+		if key != "true" && key != "false" {
+			return "expected true or false"
+		}
+		return ""
+	}
+	if code, msg := checkInteger(fd.Kind(), json.Number(key)); code != "" {
+		return msg
+	}
+	return ""
+}
+
+// lookupField finds a field by JSON name or proto name, both of which protojson accepts
+func lookupField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByJSONName(name); fd != nil {
+		return fd
+	}
+	return md.Fields().ByName(protoreflect.Name(name))
+}
+
+// isDeprecatedField reports whether the field is marked [deprecated = true]
+func isDeprecatedField(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDeprecated()
+}
+
+// anyTypePrefix returns the path of the Any message whose "@type" path sets
+func anyTypePrefix(path string) (string, bool) {
+	prefix, key, ok := splitLastKey(path)
+	if !ok || key != anyTypeKey {
+		return "", false
+	}
+	return prefix, true
+}
+
+// splitLastKey splits a path ending on a key into the canonical path before it and the key
+func splitLastKey(path string) (string, string, bool) {
+	segs, err := dotpath.ParsePath(path)
+	if err != nil || segs[len(segs)-1].Kind != dotpath.KeySegment {
+		return "", "", false
+	}
+	return dotpath.FormatPath(segs[:len(segs)-1]), segs[len(segs)-1].Key, true
+}
+
+// anyTypeURL expands a bare message name into a type URL
+func anyTypeURL(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return defaultTypeURLPrefix + name
+}
+
+// describeValue names the JSON type of a value for error messages
+func describeValue(value interface{}) string {
+	switch t := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", t)
+	case bool:
+		return fmt.Sprintf("bool %v", t)
+	case float32, float64, json.Number, int, int32, int64, uint, uint32, uint64:
+		return fmt.Sprintf("number %v", t)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,275 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const accountProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/struct.proto";
+
+enum Tier {
+  TIER_UNSPECIFIED = 0;
+  TIER_GOLD = 1;
+}
+
+message Address {
+  string city = 1;
+  int32 zip = 2;
+}
+
+message Account {
+  string name = 1;
+  int32 age = 2;
+  uint64 balance = 3;
+  Tier tier = 4;
+  bool active = 5;
+  repeated Address addresses = 6;
+  map<string, int32> limits = 7;
+  map<int64, Address> offices = 8;
+  string legacy_code = 9 [deprecated = true];
+  oneof contact {
+    string email = 10;
+    string phone = 11;
+  }
+  google.protobuf.Any extension = 12;
+  google.protobuf.Struct labels = 13;
+  double score = 14;
+}
+`
+
+func TestValidateBody_Valid(t *testing.T) {
+	svc := NewService(setupRegistry(t, "account.proto"))
+
+	fieldErrors, err := svc.ValidateBody("acme.v1.Account", []types.BodyField{
+		{Path: "name", Value: "Ada"},
+		{Path: "age", Value: "36"},
+		{Path: "balance", Value: "18446744073709551615"},
+		{Path: "tier", Value: "TIER_GOLD"},
+		{Path: "active", Value: "true"},
+		{Path: "addresses[0].city", Value: "Paris"},
+		{Path: "addresses[1]", Value: `{"city": "Lyon", "zip": 69001}`},
+		{Path: "limits.daily", Value: 10},
+		{Path: "offices.42.city", Value: "Oslo"},
+		{Path: "email", Value: "ada@example.com"},
+		{Path: "extension.sku", Value: "A-1"},
+		{Path: "extension.@type", Value: "acme.v1.Address"},
+		{Path: "labels.anything.goes", Value: "here"},
+		{Path: "score", Value: "NaN"},
+		{Path: "age", Value: "{{age}}"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ValidateBody failed: %v", err)
+	}
+	// extension.sku is not a field of the packed Address
+	if len(fieldErrors) != 1 || fieldErrors[0].Path != "extension.sku" || fieldErrors[0].Code != FieldErrorUnknownField {
+		t.Fatalf("expected only the unknown packed field to be reported, got %+v", fieldErrors)
+	}
+}
+
+func TestValidateBody_Errors(t *testing.T) {
+	svc := NewService(setupRegistry(t, "account.proto"))
+
+	fieldErrors, err := svc.ValidateBody("acme.v1.Account", []types.BodyField{
+		{Path: "nickname", Value: "x"},
+		{Path: "age", Value: "old"},
+		{Path: "age", Value: 3000000000},
+		{Path: "balance", Value: -1},
+		{Path: "tier", Value: "TIER_PLATINUM"},
+		{Path: "tier", Value: 7},
+		{Path: "addresses.city", Value: "Paris"},
+		{Path: "addresses[0].zip", Value: 1.5},
+		{Path: "name[0]", Value: "x"},
+		{Path: "offices.hq.city", Value: "Oslo"},
+		{Path: "legacyCode", Value: "L1"},
+		{Path: "email", Value: "a@b.c"},
+		{Path: "phone", Value: "555"},
+		{Path: "addresses[1]", Value: `{"city": 5, "country": "FR"}`},
+		{Path: "extension.sku", Value: "A-1"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ValidateBody failed: %v", err)
+	}
+
+	want := []FieldError{
+		{Path: "nickname", Code: FieldErrorUnknownField},
+		{Path: "age", Code: FieldErrorTypeMismatch},
+		{Path: "age", Code: FieldErrorOutOfRange},
+		{Path: "balance", Code: FieldErrorOutOfRange},
+		{Path: "tier", Code: FieldErrorInvalidEnum},
+		{Path: "tier", Code: FieldErrorInvalidEnum},
+		{Path: "addresses.city", Code: FieldErrorInvalidPath},
+		{Path: "addresses[0].zip", Code: FieldErrorTypeMismatch},
+		{Path: "name[0]", Code: FieldErrorInvalidPath},
+		{Path: "offices.hq.city", Code: FieldErrorTypeMismatch},
+		{Path: "phone", Code: FieldErrorOneofConflict},
+		{Path: "addresses[1].country", Code: FieldErrorUnknownField},
+		{Path: "extension.sku", Code: FieldErrorUnresolvedType},
+	}
+	if len(fieldErrors) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %+v", len(want), len(fieldErrors), fieldErrors)
+	}
+	for i, w := range want {
+		if fieldErrors[i].Path != w.Path || fieldErrors[i].Code != w.Code {
+			t.Errorf("error %d: expected %s at %q, got %s at %q (%s)", i, w.Code, w.Path, fieldErrors[i].Code, fieldErrors[i].Path, fieldErrors[i].Message)
+		}
+	}
+}
+
+func TestBuildBody_RejectsInvalidFields(t *testing.T) {
+	svc := NewService(setupRegistry(t, "account.proto"))
+
+	_, _, err := buildTestBody(svc, "acme.v1.Account", []types.BodyField{
+		{Path: "email", Value: "a@b.c"},
+		{Path: "phone", Value: "555"},
+	}, nil)
+	var validationErr *BodyValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a BodyValidationError, got %v", err)
+	}
+	if len(validationErr.Errors) != 1 || validationErr.Errors[0].Code != FieldErrorOneofConflict {
+		t.Fatalf("expected a oneof conflict, got %+v", validationErr.Errors)
+	}
+}
+
+func TestDeprecatedFields_Warn(t *testing.T) {
+	svc := NewService(setupRegistry(t, "account.proto"))
+	req := &RunReq{
+		Method:       "POST",
+		URL:          "http://localhost/accounts",
+		ProtoMessage: "acme.v1.Account",
+		Body:         []types.BodyField{{Path: "name", Value: "Ada"}, {Path: "legacyCode", Value: "L1"}},
+	}
+
+	fieldErrors, warnings, err := svc.ValidateRun(req)
+	if err != nil {
+		t.Fatalf("ValidateRun failed: %v", err)
+	}
+	if len(fieldErrors) != 0 {
+		t.Errorf("expected no errors, got %+v", fieldErrors)
+	}
+	if len(warnings) != 1 || warnings[0].Path != "legacyCode" || warnings[0].Code != FieldErrorDeprecated {
+		t.Errorf("expected a deprecated warning for legacyCode, got %+v", warnings)
+	}
+
+	ctx, err := svc.buildRequestContext(req)
+	if err != nil {
+		t.Fatalf("buildRequestContext failed: %v", err)
+	}
+	if len(ctx.FieldWarnings) != 1 || ctx.FieldWarnings[0].Code != FieldErrorDeprecated {
+		t.Errorf("expected a deprecated warning on the run, got %+v", ctx.FieldWarnings)
+	}
+	if _, ok := ctx.Body.([]byte); !ok {
+		t.Errorf("expected an encoded body, got %T", ctx.Body)
+	}
+}
+
+func TestBuildBody_PathSyntax(t *testing.T) {
+	reg := setupRegistry(t, "account.proto")
+	svc := NewService(reg)
+
+	body, _, err := buildTestBody(svc, "acme.v1.Account", []types.BodyField{
+		{Path: `limits["team.daily"]`, Value: 5},
+		{Path: `limits.team\.weekly`, Value: 20},
+		{Path: "addresses[]", Value: `{"city": "Paris"}`},
+		{Path: "addresses[].city", Value: "Lyon"},
+		{Path: "addresses[-1].zip", Value: 69001},
+		{Path: `offices["42"].city`, Value: "Oslo"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+
+	md, _ := reg.GetMessageDescriptor("acme.v1.Account")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	encoded, _ := protojson.Marshal(msg)
+	got := strings.ReplaceAll(string(encoded), " ", "")
+	for _, want := range []string{`"team.daily":5`, `"team.weekly":20`, `{"city":"Paris"},{"city":"Lyon","zip":69001}`, `"42":{"city":"Oslo"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected body to contain %s, got %s", want, got)
+		}
+	}
+}
+
+func TestValidateBody_PathSyntaxErrors(t *testing.T) {
+	svc := NewService(setupRegistry(t, "account.proto"))
+
+	fieldErrors, err := svc.ValidateBody("acme.v1.Account", []types.BodyField{
+		{Path: "addresses[x].city", Value: "Paris"},
+		{Path: `limits["open`, Value: 1},
+		{Path: "name[]", Value: "x"},
+		{Path: "limits[0]", Value: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("ValidateBody failed: %v", err)
+	}
+
+	want := []struct{ path, segment string }{
+		{"addresses[x].city", `"[x]"`},
+		{`limits["open`, `"[\"open"`},
+		{"name[]", "not repeated"},
+		{"limits[0]", "does not take an index"},
+	}
+	if len(fieldErrors) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %+v", len(want), len(fieldErrors), fieldErrors)
+	}
+	for i, w := range want {
+		if fieldErrors[i].Path != w.path || fieldErrors[i].Code != FieldErrorInvalidPath || !strings.Contains(fieldErrors[i].Message, w.segment) {
+			t.Errorf("error %d: expected %s at %q mentioning %s, got %s at %q (%s)", i, FieldErrorInvalidPath, w.path, w.segment, fieldErrors[i].Code, fieldErrors[i].Path, fieldErrors[i].Message)
+		}
+	}
+}
+
+func TestBuildRequestContext_TypedPlaceholders(t *testing.T) {
+	reg := setupRegistry(t, "account.proto")
+	svc := NewService(reg)
+
+	ctx, err := svc.buildRequestContext(&RunReq{
+		Method:       "POST",
+		URL:          "http://localhost/accounts",
+		ProtoMessage: "acme.v1.Account",
+		Body: []types.BodyField{
+			{Path: "age", Value: "{{age}}"},
+			{Path: "active", Value: "{{active}}"},
+			{Path: "balance", Value: "{{balance}}"},
+			{Path: "addresses[0]", Value: "{{home}}"},
+			{Path: "name", Value: "{{name}}"},
+		},
+		Variables: map[string]string{
+			"age":     "36",
+			"active":  "true",
+			"balance": "18446744073709551615",
+			"home":    `{"city": "Oslo", "zip": 150}`,
+			"name":    `"1815"`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("buildRequestContext failed: %v", err)
+	}
+
+	md, _ := reg.GetMessageDescriptor("acme.v1.Account")
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(ctx.Body.([]byte), msg); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	encoded, _ := protojson.Marshal(msg)
+	got := strings.ReplaceAll(string(encoded), " ", "")
+	for _, want := range []string{`"name":"1815"`, `"age":36`, `"balance":"18446744073709551615"`, `"active":true`, `"addresses":[{"city":"Oslo","zip":150}]`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected body to contain %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datahopper/backend/internal/interpolate"
+)
+
+// Parts of a request a placeholder can appear in
+const (
+	LocationURL    = "url"
+	LocationHeader = "header"
+	LocationBody   = "body"
+)
+
+// UnresolvedVariable is a placeholder left without a value and where it appears
+type UnresolvedVariable struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`      // url, header or body
+	Key      string `json:"key,omitempty"` // Header name or body field path; empty for the URL and raw bodies
+}
+
+func (v UnresolvedVariable) String() string {
+	if v.Key == "" {
+		return v.Name + " (" + v.Location + ")"
+	}
+	return fmt.Sprintf("%s (%s %s)", v.Name, v.Location, v.Key)
+}
+
+// UnresolvedVariablesError rejects a strict run that has placeholders without a value
+type UnresolvedVariablesError struct {
+	Variables []UnresolvedVariable
+}
+
+func (e *UnresolvedVariablesError) Error() string {
+	names := make([]string, len(e.Variables))
+	for i, v := range e.Variables {
+		names[i] = v.String()
+	}
+	return "unresolved variables: " + strings.Join(names, ", ")
+}
+
+// unresolvedVariables lists the placeholders of the URL, headers and body that vars does not
+// resolve, once per variable and location. Headers are checked in name order.
+func unresolvedVariables(req *RunReq, vars map[string]string) []UnresolvedVariable {
+	var out []UnresolvedVariable
+	check := func(location, key string, value interface{}) {
+		seen := make(map[string]bool)
+		eachString(value, func(s string) {
+			for _, name := range interpolate.ValidateVariables(s, vars) {
+				if !seen[name] {
+					seen[name] = true
+					out = append(out, UnresolvedVariable{Name: name, Location: location, Key: key})
+				}
+			}
+		})
+	}
+
+	check(LocationURL, "", req.URL)
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check(LocationHeader, name, req.Headers[name])
+	}
+	if isRawBodyMode(req.BodyMode) {
+		check(LocationBody, "", req.RawBody)
+	} else {
+		for _, field := range req.Body {
+			check(LocationBody, field.Path, field.Value)
+		}
+	}
+	return out
+}
+
+// variableCycle returns the *interpolate.CycleError of the first placeholder among values
+// whose variables refer back to themselves. ValidateVariables counts such variables as
+// defined, so a run has to resolve them to find out.
+func variableCycle(vars map[string]string, values ...interface{}) error {
+	var cycle error
+	for _, value := range values {
+		eachString(value, func(s string) {
+			if cycle != nil {
+				return
+			}
+			var cycleErr *interpolate.CycleError
+			if _, err := interpolate.Resolve(s, vars); errors.As(err, &cycleErr) {
+				cycle = cycleErr
+			}
+		})
+	}
+	return cycle
+}
+
+// eachString calls fn for every string in a body field value or header map
+func eachString(v interface{}, fn func(string)) {
+	switch val := v.(type) {
+	case string:
+		fn(val)
+	case map[string]string:
+		for _, item := range val {
+			fn(item)
+		}
+	case []interface{}:
+		for _, item := range val {
+			eachString(item, fn)
+		}
+	case map[string]interface{}:
+		for _, item := range val {
+			eachString(item, fn)
+		}
+	}
+}
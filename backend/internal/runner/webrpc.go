@@ -0,0 +1,387 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Envelope flags used by Connect streaming and gRPC-Web framing
+const (
+	envelopeFlagCompressed = 0x01 // Payload is compressed
+	envelopeFlagEndStream  = 0x02 // Connect end-of-stream message (JSON)
+	envelopeFlagTrailers   = 0x80 // gRPC-Web trailers frame
+)
+
+// envelopeHeaderSize is the flag byte plus the big-endian 4-byte length prefix
+const envelopeHeaderSize = 5
+
+// connectTrailerPrefix marks trailers sent as headers on Connect unary responses
+const connectTrailerPrefix = "trailer-"
+
+// connectCodes maps Connect error codes to their gRPC equivalents
+var connectCodes = map[string]codes.Code{
+	"canceled":            codes.Canceled,
+	"unknown":             codes.Unknown,
+	"invalid_argument":    codes.InvalidArgument,
+	"deadline_exceeded":   codes.DeadlineExceeded,
+	"not_found":           codes.NotFound,
+	"already_exists":      codes.AlreadyExists,
+	"permission_denied":   codes.PermissionDenied,
+	"resource_exhausted":  codes.ResourceExhausted,
+	"failed_precondition": codes.FailedPrecondition,
+	"aborted":             codes.Aborted,
+	"out_of_range":        codes.OutOfRange,
+	"unimplemented":       codes.Unimplemented,
+	"internal":            codes.Internal,
+	"unavailable":         codes.Unavailable,
+	"data_loss":           codes.DataLoss,
+	"unauthenticated":     codes.Unauthenticated,
+}
+
+// envelope is a single length-prefixed frame
+type envelope struct {
+	flags byte
+	data  []byte
+}
+
+// connectError is the Connect JSON error body
+type connectError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// connectEndStream is the payload of a Connect end-of-stream message
+type connectEndStream struct {
+	Error    *connectError       `json:"error,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+// isWebRPCProtocol reports whether the protocol is an HTTP-based RPC protocol sent through the HTTP client
+func isWebRPCProtocol(protocol string) bool {
+	switch protocol {
+	case ProtocolConnect, ProtocolConnectStream, ProtocolGRPCWeb, ProtocolGRPCWebText, ProtocolTwirp, ProtocolTwirpJSON:
+		return true
+	}
+	return false
+}
+
+// resolveWebRPC prepares a Connect, gRPC-Web or Twirp request. When req.ServiceMethod is set
+// the method path is appended to the base URL and message types come from the method descriptor.
+func (s *Service) resolveWebRPC(req *RunReq) (*RunReq, error) {
+	resolved := *req
+	if strings.TrimSpace(req.ServiceMethod) != "" {
+		r, method, err := s.resolveRPCMethod(req)
+		if err != nil {
+			return nil, err
+		}
+		resolved = *r
+		path := grpcMethodPath(method)
+		if req.Protocol == ProtocolTwirp || req.Protocol == ProtocolTwirpJSON {
+			path = twirpPathPrefix + path
+		}
+		resolved.URL = strings.TrimRight(req.URL, "/") + path
+	}
+	resolved.Method = http.MethodPost
+	return &resolved, nil
+}
+
+// webRPCHeaders returns the protocol headers for a Connect, gRPC-Web or Twirp request
+func webRPCHeaders(protocol string, timeoutSeconds int) map[string]string {
+	switch protocol {
+	case ProtocolTwirp:
+		return map[string]string{
+			"Content-Type": twirpContentType,
+			"Accept":       twirpContentType,
+		}
+	case ProtocolTwirpJSON:
+		return map[string]string{
+			"Content-Type": twirpJSONContentType,
+			"Accept":       twirpJSONContentType,
+		}
+	case ProtocolConnect, ProtocolConnectStream:
+		contentType := "application/proto"
+		if protocol == ProtocolConnectStream {
+			contentType = "application/connect+proto"
+		}
+		return map[string]string{
+			"Content-Type":             contentType,
+			"Connect-Protocol-Version": "1",
+			"Connect-Timeout-Ms":       strconv.Itoa(timeoutSeconds * 1000),
+		}
+	default:
+		contentType := "application/grpc-web+proto"
+		if protocol == ProtocolGRPCWebText {
+			contentType = "application/grpc-web-text+proto"
+		}
+		return map[string]string{
+			"Content-Type": contentType,
+			"Accept":       contentType,
+			"X-Grpc-Web":   "1",
+			"Grpc-Timeout": strconv.Itoa(timeoutSeconds) + "S",
+		}
+	}
+}
+
+// frameWebRPCBody wraps an encoded request message for the wire. Connect unary and Twirp send
+// the message as-is (Twirp JSON bodies are converted by twirpJSONBody first); streaming and
+// gRPC-Web protocols use an envelope, base64-encoded for gRPC-Web text.
+func frameWebRPCBody(protocol string, body interface{}) ([]byte, error) {
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+		payload = []byte{}
+	case []byte:
+		payload = b
+	default:
+		return nil, fmt.Errorf("%s requests require a protobuf message type", protocol)
+	}
+
+	switch protocol {
+	case ProtocolConnect, ProtocolTwirp, ProtocolTwirpJSON:
+		return payload, nil
+	case ProtocolGRPCWebText:
+		framed := encodeEnvelope(0, payload)
+		return []byte(base64.StdEncoding.EncodeToString(framed)), nil
+	default:
+		return encodeEnvelope(0, payload), nil
+	}
+}
+
+// unwrapWebRPCResponse strips protocol framing from a Connect, gRPC-Web or Twirp response,
+// collecting response messages, trailers and the RPC status or error into resp
+func unwrapWebRPCResponse(protocol string, resp *ResponseContext) error {
+	switch protocol {
+	case ProtocolConnect:
+		unwrapConnectUnary(resp)
+		return nil
+	case ProtocolConnectStream:
+		return unwrapConnectStream(resp)
+	case ProtocolGRPCWeb, ProtocolGRPCWebText:
+		return unwrapGRPCWeb(resp, protocol == ProtocolGRPCWebText)
+	case ProtocolTwirp, ProtocolTwirpJSON:
+		unwrapTwirp(resp)
+	}
+	return nil
+}
+
+// unwrapConnectUnary moves trailer- headers into trailers and parses the JSON error body
+func unwrapConnectUnary(resp *ResponseContext) {
+	trailers := make(map[string]string)
+	for key, value := range resp.Headers {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, connectTrailerPrefix) {
+			trailers[strings.TrimPrefix(lower, connectTrailerPrefix)] = value
+			delete(resp.Headers, key)
+		}
+	}
+	if len(trailers) > 0 {
+		resp.Trailers = trailers
+	}
+
+	if resp.Status == http.StatusOK {
+		resp.GRPCStatus = &GRPCStatus{Code: int(codes.OK), Name: codes.OK.String()}
+		return
+	}
+	if strings.Contains(resp.ContentType, "application/json") {
+		var connectErr connectError
+		if err := json.Unmarshal(resp.Body, &connectErr); err == nil && connectErr.Code != "" {
+			resp.GRPCStatus = connectErr.status()
+			resp.ErrorDetails = connectDetailsToAny(connectErr.Details)
+		}
+	}
+}
+
+// unwrapConnectStream splits an enveloped Connect streaming response into messages and
+// reads trailers and the final status from the end-of-stream message
+func unwrapConnectStream(resp *ResponseContext) error {
+	if resp.Status != http.StatusOK {
+		// Errors before the stream starts are reported like unary errors
+		unwrapConnectUnary(resp)
+		return nil
+	}
+
+	frames, err := decodeEnvelopes(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	resp.Messages = [][]byte{}
+	for _, frame := range frames {
+		if frame.flags&envelopeFlagEndStream == 0 {
+			resp.Messages = append(resp.Messages, frame.data)
+			continue
+		}
+
+		var end connectEndStream
+		if err := json.Unmarshal(frame.data, &end); err != nil {
+			return fmt.Errorf("invalid end-of-stream message: %w", err)
+		}
+		if len(end.Metadata) > 0 {
+			resp.Trailers = make(map[string]string, len(end.Metadata))
+			for key, values := range end.Metadata {
+				resp.Trailers[strings.ToLower(key)] = strings.Join(values, ", ")
+			}
+		}
+		if end.Error != nil {
+			resp.GRPCStatus = end.Error.status()
+			resp.ErrorDetails = connectDetailsToAny(end.Error.Details)
+		}
+	}
+
+	finishWebRPCStatus(resp)
+	return nil
+}
+
+// unwrapGRPCWeb splits a gRPC-Web response into messages and the trailers frame. The status
+// comes from the trailers frame, or from headers for trailers-only responses.
+func unwrapGRPCWeb(resp *ResponseContext, text bool) error {
+	data := resp.Body
+	if text {
+		decoded, err := decodeGRPCWebText(data)
+		if err != nil {
+			return err
+		}
+		data = decoded
+	}
+
+	frames, err := decodeEnvelopes(data)
+	if err != nil {
+		return err
+	}
+
+	trailers := make(map[string]string)
+	resp.Messages = [][]byte{}
+	for _, frame := range frames {
+		if frame.flags&envelopeFlagTrailers == 0 {
+			resp.Messages = append(resp.Messages, frame.data)
+			continue
+		}
+		for _, line := range strings.Split(string(frame.data), "\r\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			trailers[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	if len(trailers) > 0 {
+		resp.Trailers = trailers
+	}
+
+	statusValue, hasStatus := trailers["grpc-status"]
+	message := trailers["grpc-message"]
+	if !hasStatus {
+		statusValue, hasStatus = lookupHeader(resp.Headers, "grpc-status")
+		message, _ = lookupHeader(resp.Headers, "grpc-message")
+	}
+	if hasStatus {
+		code, err := strconv.Atoi(statusValue)
+		if err != nil {
+			return fmt.Errorf("invalid grpc-status %q", statusValue)
+		}
+		if unescaped, err := url.PathUnescape(message); err == nil {
+			message = unescaped
+		}
+		resp.GRPCStatus = &GRPCStatus{Code: code, Name: codes.Code(code).String(), Message: message}
+		if code != int(codes.OK) {
+			resp.ErrorDetails = statusDetailsFromTrailers(trailers)
+		}
+	}
+
+	finishWebRPCStatus(resp)
+	return nil
+}
+
+// finishWebRPCStatus defaults a missing status to OK and maps failures onto the HTTP status,
+// since streaming and gRPC-Web responses report errors with HTTP 200
+func finishWebRPCStatus(resp *ResponseContext) {
+	if resp.GRPCStatus == nil {
+		resp.GRPCStatus = &GRPCStatus{Code: int(codes.OK), Name: codes.OK.String()}
+	}
+	if resp.GRPCStatus.Code != int(codes.OK) && resp.Status == http.StatusOK {
+		resp.Status = httpStatusFromGRPCCode(codes.Code(resp.GRPCStatus.Code))
+	}
+}
+
+// status converts a Connect error into its API representation
+func (e *connectError) status() *GRPCStatus {
+	code, ok := connectCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	return &GRPCStatus{Code: int(code), Name: code.String(), Message: e.Message}
+}
+
+// encodeEnvelope prefixes a payload with its flags and length
+func encodeEnvelope(flags byte, payload []byte) []byte {
+	framed := make([]byte, envelopeHeaderSize+len(payload))
+	framed[0] = flags
+	binary.BigEndian.PutUint32(framed[1:envelopeHeaderSize], uint32(len(payload)))
+	copy(framed[envelopeHeaderSize:], payload)
+	return framed
+}
+
+// decodeEnvelopes splits a body into length-prefixed frames
+func decodeEnvelopes(data []byte) ([]envelope, error) {
+	var frames []envelope
+	for len(data) > 0 {
+		if len(data) < envelopeHeaderSize {
+			return nil, fmt.Errorf("truncated envelope header: %d bytes", len(data))
+		}
+		flags := data[0]
+		size := binary.BigEndian.Uint32(data[1:envelopeHeaderSize])
+		data = data[envelopeHeaderSize:]
+		if uint32(len(data)) < size {
+			return nil, fmt.Errorf("truncated envelope: expected %d bytes, got %d", size, len(data))
+		}
+		if flags&envelopeFlagCompressed != 0 {
+			return nil, fmt.Errorf("compressed messages are not supported")
+		}
+		frames = append(frames, envelope{flags: flags, data: data[:size]})
+		data = data[size:]
+	}
+	return frames, nil
+}
+
+// decodeGRPCWebText decodes a gRPC-Web text body. Servers may concatenate separately
+// padded base64 chunks, so decoding restarts after each padded chunk.
+func decodeGRPCWebText(data []byte) ([]byte, error) {
+	data = bytes.Join(bytes.Fields(data), nil)
+	var out []byte
+	for len(data) > 0 {
+		end := len(data)
+		if idx := bytes.IndexByte(data, '='); idx >= 0 {
+			end = idx
+			for end < len(data) && data[end] == '=' {
+				end++
+			}
+		}
+		chunk, err := base64.StdEncoding.DecodeString(string(data[:end]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid grpc-web-text body: %w", err)
+		}
+		out = append(out, chunk...)
+		data = data[end:]
+	}
+	return out, nil
+}
+
+// lookupHeader finds a header value regardless of key casing
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
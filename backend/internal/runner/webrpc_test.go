@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// startWebRPCServer serves Greeter/SayHello over Connect and gRPC-Web using the request
+// Content-Type to pick the framing. Requests with name "missing" fail with NOT_FOUND.
+func startWebRPCServer(t *testing.T, svc *Service) *httptest.Server {
+	t.Helper()
+	method, err := svc.registry.GetMethodDescriptor("greet.v1.Greeter/SayHello")
+	if err != nil {
+		t.Fatalf("method lookup failed: %v", err)
+	}
+
+	reply := func(name string) []byte {
+		out := dynamicpb.NewMessage(method.Output())
+		out.Set(method.Output().Fields().ByName("message"), protoreflect.ValueOfString("Hello "+name))
+		b, _ := proto.Marshal(out)
+		return b
+	}
+	readName := func(payload []byte) string {
+		in := dynamicpb.NewMessage(method.Input())
+		if err := proto.Unmarshal(payload, in); err != nil {
+			t.Errorf("server failed to unmarshal request: %v", err)
+		}
+		return in.Get(method.Input().Fields().ByName("name")).String()
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/greet.v1.Greeter/SayHello" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		contentType := r.Header.Get("Content-Type")
+
+		switch contentType {
+		case "application/proto":
+			name := readName(body)
+			if name == "missing" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"code":"not_found","message":"no such person"}`))
+				return
+			}
+			w.Header().Set("Content-Type", "application/proto")
+			w.Header().Set("Trailer-X-Served-By", "greeter")
+			_, _ = w.Write(reply(name))
+
+		case "application/connect+proto":
+			frames, err := decodeEnvelopes(body)
+			if err != nil || len(frames) != 1 {
+				t.Errorf("bad connect stream request: %v", err)
+			}
+			name := readName(frames[0].data)
+			w.Header().Set("Content-Type", "application/connect+proto")
+			_, _ = w.Write(encodeEnvelope(0, reply(name)))
+			_, _ = w.Write(encodeEnvelope(0, reply(name+" again")))
+			_, _ = w.Write(encodeEnvelope(envelopeFlagEndStream, []byte(`{"metadata":{"x-served-by":["greeter"]}}`)))
+
+		case "application/grpc-web+proto", "application/grpc-web-text+proto":
+			text := contentType == "application/grpc-web-text+proto"
+			if text {
+				body, _ = base64.StdEncoding.DecodeString(string(body))
+			}
+			frames, err := decodeEnvelopes(body)
+			if err != nil || len(frames) != 1 {
+				t.Errorf("bad grpc-web request: %v", err)
+			}
+			name := readName(frames[0].data)
+
+			var out []byte
+			trailer := "grpc-status: 0\r\nx-served-by: greeter\r\n"
+			if name == "missing" {
+				trailer = "grpc-status: 5\r\ngrpc-message: no%20such%20person\r\n"
+			} else {
+				out = encodeEnvelope(0, reply(name))
+			}
+			trailerFrame := encodeEnvelope(envelopeFlagTrailers, []byte(trailer))
+
+			w.Header().Set("Content-Type", contentType)
+			if text {
+				// Encode frames separately to exercise chunked base64 decoding
+				_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(out)))
+				_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(trailerFrame)))
+				return
+			}
+			_, _ = w.Write(append(out, trailerFrame...))
+
+		default:
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func runWebRPC(t *testing.T, svc *Service, baseURL, protocol, name string) *RunRes {
+	t.Helper()
+	res, err := svc.Run(&RunReq{
+		Method:         "POST",
+		URL:            baseURL,
+		Protocol:       protocol,
+		ServiceMethod:  "greet.v1.Greeter/SayHello",
+		Body:           []types.BodyField{{Path: "name", Value: name}},
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatalf("Run(%s) failed: %v", protocol, err)
+	}
+	return res
+}
+
+func TestRunConnect_Unary(t *testing.T) {
+	svc := NewService(setupRegistry(t, "greet.proto"))
+	srv := startWebRPCServer(t, svc)
+
+	res := runWebRPC(t, svc, srv.URL+"/", ProtocolConnect, "Ada")
+	if res.Status != 200 || res.GRPCStatus == nil || res.GRPCStatus.Code != 0 {
+		t.Fatalf("expected OK, got status %d / %+v", res.Status, res.GRPCStatus)
+	}
+	if !strings.Contains(res.Decoded, "Hello Ada") {
+		t.Fatalf("expected decoded reply, got %q (decodeError=%q)", res.Decoded, res.DecodeError)
+	}
+	if res.Trailers["x-served-by"] != "greeter" {
+		t.Fatalf("expected trailer from trailer- header, got %v", res.Trailers)
+	}
+	if _, ok := res.Headers["Trailer-X-Served-By"]; ok {
+		t.Fatal("expected trailer- header to be moved out of headers")
+	}
+}
+
+func TestRunConnect_ErrorJSON(t *testing.T) {
+	svc := NewService(setupRegistry(t, "greet.proto"))
+	srv := startWebRPCServer(t, svc)
+
+	res := runWebRPC(t, svc, srv.URL, ProtocolConnect, "missing")
+	if res.Status != 404 {
+		t.Fatalf("expected status 404, got %d", res.Status)
+	}
+	if res.GRPCStatus == nil || res.GRPCStatus.Name != "NotFound" || res.GRPCStatus.Message != "no such person" {
+		t.Fatalf("unexpected status: %+v", res.GRPCStatus)
+	}
+	if res.Decoded != "" || res.DecodeError != "" {
+		t.Fatalf("expected no decode attempt on JSON error, got %q / %q", res.Decoded, res.DecodeError)
+	}
+}
+
+func TestRunConnect_Streaming(t *testing.T) {
+	svc := NewService(setupRegistry(t, "greet.proto"))
+	srv := startWebRPCServer(t, svc)
+
+	res := runWebRPC(t, svc, srv.URL, ProtocolConnectStream, "Ada")
+	if res.GRPCStatus == nil || res.GRPCStatus.Code != 0 {
+		t.Fatalf("expected OK status, got %+v", res.GRPCStatus)
+	}
+	if !strings.HasPrefix(res.Decoded, "[") || !strings.Contains(res.Decoded, "Hello Ada again") {
+		t.Fatalf("expected decoded message array, got %q (decodeError=%q)", res.Decoded, res.DecodeError)
+	}
+	if res.Trailers["x-served-by"] != "greeter" {
+		t.Fatalf("expected end-stream metadata as trailers, got %v", res.Trailers)
+	}
+}
+
+func TestRunGRPCWeb(t *testing.T) {
+	svc := NewService(setupRegistry(t, "greet.proto"))
+	srv := startWebRPCServer(t, svc)
+
+	for _, protocol := range []string{ProtocolGRPCWeb, ProtocolGRPCWebText} {
+		res := runWebRPC(t, svc, srv.URL, protocol, "Ada")
+		if res.Status != 200 || res.GRPCStatus == nil || res.GRPCStatus.Code != 0 {
+			t.Fatalf("%s: expected OK, got status %d / %+v", protocol, res.Status, res.GRPCStatus)
+		}
+		if !strings.Contains(res.Decoded, "Hello Ada") {
+			t.Fatalf("%s: expected decoded reply, got %q (decodeError=%q)", protocol, res.Decoded, res.DecodeError)
+		}
+		if res.Trailers["x-served-by"] != "greeter" {
+			t.Fatalf("%s: expected trailers frame, got %v", protocol, res.Trailers)
+		}
+
+		res = runWebRPC(t, svc, srv.URL, protocol, "missing")
+		if res.Status != 404 || res.GRPCStatus.Name != "NotFound" || res.GRPCStatus.Message != "no such person" {
+			t.Fatalf("%s: unexpected error result: status %d / %+v", protocol, res.Status, res.GRPCStatus)
+		}
+		if res.Decoded != "" {
+			t.Fatalf("%s: expected no decoded body on error, got %q", protocol, res.Decoded)
+		}
+	}
+}
+
+func TestDecodeEnvelopes_Truncated(t *testing.T) {
+	framed := encodeEnvelope(0, []byte("hello"))
+	if _, err := decodeEnvelopes(framed[:len(framed)-1]); err == nil {
+		t.Fatal("expected error for truncated envelope")
+	}
+	if _, err := decodeEnvelopes(framed[:3]); err == nil {
+		t.Fatal("expected error for truncated header")
+	}
+}
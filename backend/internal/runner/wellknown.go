@@ -0,0 +1,230 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Well-known types with a dedicated JSON mapping in protojson
+const (
+	timestampTypeName   = "google.protobuf.Timestamp"
+	durationTypeName    = "google.protobuf.Duration"
+	fieldMaskTypeName   = "google.protobuf.FieldMask"
+	structTypeName      = "google.protobuf.Struct"
+	valueTypeName       = "google.protobuf.Value"
+	listValueTypeName   = "google.protobuf.ListValue"
+	emptyTypeName       = "google.protobuf.Empty"
+	int32ValueTypeName  = "google.protobuf.Int32Value"
+	int64ValueTypeName  = "google.protobuf.Int64Value"
+	uint32ValueTypeName = "google.protobuf.UInt32Value"
+	uint64ValueTypeName = "google.protobuf.UInt64Value"
+	floatValueTypeName  = "google.protobuf.FloatValue"
+	doubleValueTypeName = "google.protobuf.DoubleValue"
+	boolValueTypeName   = "google.protobuf.BoolValue"
+	stringValueTypeName = "google.protobuf.StringValue"
+	bytesValueTypeName  = "google.protobuf.BytesValue"
+)
+
+// isWellKnownJSONType reports whether values of the message are rewritten by
+// normalizeWellKnownValue rather than walked field by field
+func isWellKnownJSONType(fullName string) bool {
+	_, ok := normalizeWellKnownValue(fullName, nil)
+	return ok
+}
+
+// normalizeWellKnownValue converts a UI-friendly value for a well-known type into the JSON
+// shape protojson expects. ok is false when fullName is not a well-known type handled here,
+// in which case the caller should treat the value as a regular message.
+func normalizeWellKnownValue(fullName string, v interface{}) (interface{}, bool) {
+	switch fullName {
+	case timestampTypeName:
+		return normalizeTimestamp(v), true
+	case durationTypeName:
+		return normalizeDuration(v), true
+	case fieldMaskTypeName:
+		return normalizeFieldMask(v), true
+	case structTypeName:
+		return parseJSONString(v, '{'), true
+	case listValueTypeName:
+		return parseJSONString(v, '['), true
+	case valueTypeName:
+		// Any JSON value is valid; strings stay strings
+		return v, true
+	case emptyTypeName:
+		return map[string]interface{}{}, true
+	case int32ValueTypeName, int64ValueTypeName, uint32ValueTypeName, uint64ValueTypeName,
+		floatValueTypeName, doubleValueTypeName:
+		return unwrapWrapper(v), true
+	case boolValueTypeName:
+		v = unwrapWrapper(v)
+		if s, isString := v.(string); isString {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+		return v, true
+	case stringValueTypeName:
+		v = unwrapWrapper(v)
+		switch sv := v.(type) {
+		case int, int32, int64, uint, uint32, uint64, float32, float64, bool, json.Number:
+			return formatPrimitiveAsString(sv), true
+		}
+		return v, true
+	case bytesValueTypeName:
+		return unwrapWrapper(v), true
+	}
+	return v, false
+}
+
+// normalizeTimestamp accepts {seconds, nanos} objects and Unix seconds as numbers or
+// numeric strings; other strings are passed through as RFC3339
+func normalizeTimestamp(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		seconds := extractInt64(t["seconds"]) // default 0 if missing
+		nanos := extractInt64(t["nanos"])     // default 0 if missing
+		if nanos < 0 {
+			nanos = 0
+		}
+		if nanos > 999999999 {
+			nanos = 999999999
+		}
+		return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
+	case int, int32, int64, float32, float64, json.Number:
+		return time.Unix(extractInt64(t), 0).UTC().Format(time.RFC3339Nano)
+	case string:
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return v
+}
+
+// normalizeDuration accepts "1.5s", Go durations like "1m30s" or "500ms", bare seconds as
+// numbers or strings, and {seconds, nanos} objects
+func normalizeDuration(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return formatDurationJSON(extractInt64(t["seconds"]), extractInt64(t["nanos"]))
+	case int, int32, int64:
+		return formatDurationJSON(extractInt64(t), 0)
+	case float32, float64, json.Number:
+		return formatPrimitiveAsString(t) + "s"
+	case string:
+		s := strings.TrimSpace(t)
+		if s == "" {
+			return v
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return s + "s"
+		}
+		if strings.HasSuffix(s, "s") {
+			if _, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64); err == nil {
+				return s
+			}
+		}
+		if d, err := time.ParseDuration(s); err == nil {
+			return formatDurationJSON(int64(d/time.Second), int64(d%time.Second))
+		}
+	}
+	return v
+}
+
+// formatDurationJSON renders seconds and nanos in the protojson form, e.g. "-1.5s"
+func formatDurationJSON(seconds, nanos int64) string {
+	seconds += nanos / 1e9
+	nanos %= 1e9
+	sign := ""
+	if seconds < 0 || nanos < 0 {
+		sign = "-"
+		if seconds < 0 {
+			seconds = -seconds
+		}
+		if nanos < 0 {
+			nanos = -nanos
+		}
+	}
+	if nanos == 0 {
+		return fmt.Sprintf("%s%ds", sign, seconds)
+	}
+	return fmt.Sprintf("%s%d.%ss", sign, seconds, strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+}
+
+// normalizeFieldMask accepts a comma-separated string, a list of paths or a {paths: [...]}
+// object. Paths may be snake_case; protojson requires lowerCamelCase.
+func normalizeFieldMask(v interface{}) interface{} {
+	var paths []string
+	switch t := v.(type) {
+	case string:
+		paths = strings.Split(t, ",")
+	case []interface{}:
+		for _, item := range t {
+			paths = append(paths, fmt.Sprint(item))
+		}
+	case map[string]interface{}:
+		return normalizeFieldMask(t["paths"])
+	default:
+		return v
+	}
+
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, fieldMaskPathToJSON(p))
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// fieldMaskPathToJSON converts each segment of a snake_case path to lowerCamelCase
+func fieldMaskPathToJSON(path string) string {
+	segments := strings.Split(path, ".")
+	for i, seg := range segments {
+		var b strings.Builder
+		upper := false
+		for _, r := range seg {
+			if r == '_' {
+				upper = true
+				continue
+			}
+			if upper && r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			upper = false
+			b.WriteRune(r)
+		}
+		segments[i] = b.String()
+	}
+	return strings.Join(segments, ".")
+}
+
+// parseJSONString decodes a string holding a JSON object or array (per open) so free-form
+// Struct and ListValue input can be typed into a single field
+func parseJSONString(v interface{}, open byte) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" || trimmed[0] != open {
+		return v
+	}
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil {
+		return v
+	}
+	return parsed
+}
+
+// unwrapWrapper accepts the object form {value: x} of a wrapper type alongside the bare scalar
+func unwrapWrapper(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok && len(m) == 1 {
+		if inner, exists := m["value"]; exists {
+			return inner
+		}
+	}
+	return v
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datahopper/backend/internal/types"
+)
+
+const wellKnownProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/duration.proto";
+import "google/protobuf/empty.proto";
+import "google/protobuf/field_mask.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+message Settings {
+  google.protobuf.Duration timeout = 1;
+  repeated google.protobuf.Duration backoff = 2;
+  google.protobuf.FieldMask update_mask = 3;
+  google.protobuf.Struct metadata = 4;
+  google.protobuf.Value extra = 5;
+  google.protobuf.ListValue tags = 6;
+  google.protobuf.Int64Value limit = 7;
+  google.protobuf.StringValue label = 8;
+  google.protobuf.BoolValue enabled = 9;
+  google.protobuf.Empty ping = 10;
+  google.protobuf.Timestamp since = 11;
+  map<string, google.protobuf.Duration> windows = 12;
+}
+`
+
+func TestEncodeProtobufBody_WellKnownTypes(t *testing.T) {
+	svc := NewService(setupRegistry(t, "settings.proto"))
+
+	body, _, err := buildTestBody(svc, "acme.v1.Settings", []types.BodyField{
+		{Path: "timeout", Value: "1.5s"},
+		{Path: "backoff[0]", Value: "500ms"},
+		{Path: "backoff[1]", Value: 2},
+		{Path: "updateMask", Value: "display_name, address.zip_code"},
+		{Path: "metadata.team", Value: "core"},
+		{Path: "metadata.fields.count", Value: 3},
+		{Path: "extra", Value: "free text"},
+		{Path: "tags", Value: `["a", 1, true]`},
+		{Path: "limit", Value: "250"},
+		{Path: "label", Value: 42},
+		{Path: "enabled", Value: "true"},
+		{Path: "ping", Value: ""},
+		{Path: "since", Value: "60"},
+		{Path: "windows.peak", Value: "1m30s"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildBody failed: %v", err)
+	}
+
+	decoded, err := svc.decodeProtobufResponse("acme.v1.Settings", body.([]byte))
+	if err != nil {
+		t.Fatalf("decodeProtobufResponse failed: %v", err)
+	}
+	for _, want := range []string{
+		`"timeout": "1.500s"`,
+		`"0.500s"`,
+		`"2s"`,
+		`"updateMask": "displayName,address.zipCode"`,
+		`"team": "core"`,
+		`"count": 3`,
+		`"extra": "free text"`,
+		`"limit": "250"`,
+		`"label": "42"`,
+		`"enabled": true`,
+		`"ping": {}`,
+		`"since": "1970-01-01T00:01:00Z"`,
+		`"peak": "90s"`,
+	} {
+		if !strings.Contains(collapseSpaces(decoded), want) {
+			t.Errorf("expected decoded output to contain %s, got:\n%s", want, decoded)
+		}
+	}
+}
+
+func TestNormalizeWellKnownValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		fullName string
+		in       interface{}
+		want     interface{}
+	}{
+		{"duration seconds", durationTypeName, "1.5", "1.5s"},
+		{"duration suffix", durationTypeName, "1.5s", "1.5s"},
+		{"duration object", durationTypeName, map[string]interface{}{"seconds": 1, "nanos": 250000000}, "1.25s"},
+		{"duration negative", durationTypeName, "-1.5s", "-1.5s"},
+		{"duration go", durationTypeName, "-2m", "-120s"},
+		{"field mask list", fieldMaskTypeName, []interface{}{"a_b", "c"}, "aB,c"},
+		{"field mask object", fieldMaskTypeName, map[string]interface{}{"paths": "x.y_z"}, "x.yZ"},
+		{"wrapper object", int32ValueTypeName, map[string]interface{}{"value": 7}, 7},
+		{"bool string", boolValueTypeName, "false", false},
+		{"struct string", structTypeName, `{"k": "v"}`, map[string]interface{}{"k": "v"}},
+		{"timestamp passthrough", timestampTypeName, "2024-01-02T15:04:05Z", "2024-01-02T15:04:05Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := normalizeWellKnownValue(tt.fullName, tt.in)
+			if !ok {
+				t.Fatalf("expected %s to be handled", tt.fullName)
+			}
+			if gotMap, isMap := got.(map[string]interface{}); isMap {
+				if wantMap, _ := tt.want.(map[string]interface{}); len(gotMap) != len(wantMap) || gotMap["k"] != wantMap["k"] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := normalizeWellKnownValue("acme.v1.Settings", map[string]interface{}{}); ok {
+		t.Fatal("expected regular messages to be left alone")
+	}
+}
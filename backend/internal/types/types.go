@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
 
 // Variable represents a key-value variable
 type Variable struct {
@@ -16,21 +20,100 @@ type HeaderKV struct {
 
 // BodyField represents a body field with dot-path and value
 type BodyField struct {
-	Path  string      `json:"path"`
-	Value interface{} `json:"value"`
+	Path     string      `json:"path"`
+	Value    interface{} `json:"value"`
+	Presence string      `json:"presence,omitempty"` // PresenceSet, PresenceUnset or PresenceDefault; empty sends Value as is
+	Encoding string      `json:"encoding,omitempty"` // For bytes fields: BytesBase64 (default), BytesHex, BytesUTF8 or BytesFile
 }
 
+// Presence modes for BodyField. Set and default only apply to fields with explicit presence
+// (proto3 optional, proto2 scalars, messages and oneof members); any singular field can be unset.
+const (
+	PresenceSet     = "set"     // Mark the field present; a null or empty value sends its default
+	PresenceUnset   = "unset"   // Leave the field out whatever Value holds
+	PresenceDefault = "default" // Mark the field present with its default value, ignoring Value
+)
+
+// Body modes of a request: how its body is written before it is encoded as ProtoMessage
+const (
+	BodyModeFields = "fields" // Dot-path BodyFields (default)
+	BodyModeJSON   = "json"   // A protojson document in RawBody
+	BodyModeText   = "text"   // A protobuf text-format document in RawBody
+)
+
+// Encodings of BodyField values for bytes fields, also used as BytesDisplay modes for
+// decoded responses (except BytesFile)
+const (
+	BytesBase64 = "base64" // Standard or URL-safe base64, padding optional
+	BytesHex    = "hex"    // Hex digits, optionally prefixed with 0x and separated by spaces or colons
+	BytesUTF8   = "utf8"   // The UTF-8 bytes of the string itself
+	BytesFile   = "file"   // The ID of a RequestFile uploaded with the request
+)
+
+// BytesDisplay picks how bytes fields of decoded responses are shown. Keys are field
+// dot-paths without list indexes or map keys ("attachment.data", "chunks"), or "*" for
+// every bytes field; values are BytesBase64, BytesHex or BytesUTF8.
+type BytesDisplay map[string]string
+
+// RequestFile is a file uploaded with a request, referenced by bytes body fields
+type RequestFile struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType,omitempty"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Data        []byte    `json:"-"`
+}
+
+// UnmarshalJSON decodes numeric values as json.Number so 64-bit integers keep full precision
+func (f *BodyField) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Path     string      `json:"path"`
+		Value    interface{} `json:"value"`
+		Presence string      `json:"presence"`
+		Encoding string      `json:"encoding"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	f.Path, f.Value, f.Presence, f.Encoding = raw.Path, raw.Value, raw.Presence, raw.Encoding
+	return nil
+}
+
+// ResponseTypeMatcher maps response status codes to the message type used to decode them.
+// Status is an exact code ("404"), a class ("5xx"), a range ("400-499") or "default".
+type ResponseTypeMatcher struct {
+	Status      string `json:"status"`
+	MessageType string `json:"messageType"`
+}
+
+// OneofSelections records which member of each oneof group a body sets. Keys are the
+// dot-path of the message holding the group followed by the oneof name ("payment.method",
+// "items[0].kind", or just "method" at the root); values are member field names as listed
+// in the schema's OneofGroup.
+type OneofSelections map[string]string
+
 // Request represents an HTTP request configuration
 type Request struct {
 	ID              string       `json:"id"`
 	Name            string       `json:"name"`
 	Method          string       `json:"method"`
 	URL             string       `json:"url"`
+	Protocol        string       `json:"protocol,omitempty"`        // http (default), grpc, connect, ...
+	ServiceMethod   string       `json:"serviceMethod,omitempty"`   // RPC method for RPC protocols, e.g. pkg.Service/Method
 	ProtoMessage    string       `json:"protoMessage,omitempty"`    // FQN of request message type
     ResponseType    string       `json:"responseType,omitempty"`    // FQN of success response message type
     ErrorResponseType string     `json:"errorResponseType,omitempty"` // FQN of error response message type
+	ResponseTypes   []ResponseTypeMatcher `json:"responseTypes,omitempty"` // Ordered status matchers; first match wins
+	OneofSelections OneofSelections `json:"oneofSelections,omitempty"` // Selected member per oneof group
+	BytesDisplay    BytesDisplay `json:"bytesDisplay,omitempty"` // How decoded bytes fields are shown
+	Files           []*RequestFile `json:"files,omitempty"` // Uploaded files referenced by bytes fields
 	Headers         []HeaderKV   `json:"headers"`
+	BodyMode        string       `json:"bodyMode,omitempty"` // BodyModeFields (default), BodyModeJSON or BodyModeText
 	Body            []BodyField  `json:"body"`
+	RawBody         string       `json:"rawBody,omitempty"` // Body document for the json and text modes
 	TimeoutSeconds  int          `json:"timeoutSeconds"`
 	CreatedAt       time.Time    `json:"createdAt"`
 	UpdatedAt       time.Time    `json:"updatedAt"`
@@ -45,6 +128,7 @@ type Collection struct {
 	Description string            `json:"description"`
 	ProtoRoots  []string          `json:"protoRoots"`  // Paths to .proto files
 	Variables   map[string]string `json:"variables"`   // Collection-scoped variables
+	StrictVariables bool          `json:"strictVariables,omitempty"` // Runs fail on unresolved placeholders instead of warning
 	Requests    []*Request        `json:"requests"`
 	CreatedAt   time.Time         `json:"createdAt"`
 	UpdatedAt   time.Time         `json:"updatedAt"`
@@ -56,12 +140,17 @@ type Environment struct {
 	Variables map[string]string `json:"variables"`
 }
 
+// GlobalEnvironment names the environment whose variables apply to every run, beneath the
+// collection, the selected environment and per-run overrides
+const GlobalEnvironment = "globals"
+
 // CreateCollectionRequest represents the request to create a collection
 type CreateCollectionRequest struct {
 	Name        string            `json:"name" binding:"required"`
 	Description string            `json:"description"`
 	ProtoRoots  []string          `json:"protoRoots"`
 	Variables   map[string]string `json:"variables"`
+	StrictVariables bool          `json:"strictVariables"`
 }
 
 // CreateRequestRequest represents the request to create a request
@@ -69,11 +158,18 @@ type CreateRequestRequest struct {
 	Name           string       `json:"name" binding:"required"`
 	Method         string       `json:"method" binding:"required"`
 	URL            string       `json:"url" binding:"required"`
+	Protocol       string       `json:"protocol"`
+	ServiceMethod  string       `json:"serviceMethod"`
 	ProtoMessage   string       `json:"protoMessage"`
     ResponseType   string       `json:"responseType"`
     ErrorResponseType string    `json:"errorResponseType"`
+	ResponseTypes  []ResponseTypeMatcher `json:"responseTypes"`
+	OneofSelections OneofSelections `json:"oneofSelections"`
+	BytesDisplay   BytesDisplay `json:"bytesDisplay"`
 	Headers        []HeaderKV   `json:"headers"`
+	BodyMode       string       `json:"bodyMode"`
 	Body           []BodyField  `json:"body"`
+	RawBody        string       `json:"rawBody"`
 	TimeoutSeconds int          `json:"timeoutSeconds"`
 }
 
@@ -82,11 +178,18 @@ type UpdateRequestRequest struct {
 	Name           string       `json:"name"`
 	Method         string       `json:"method"`
 	URL            string       `json:"url"`
+	Protocol       string       `json:"protocol"`
+	ServiceMethod  string       `json:"serviceMethod"`
 	ProtoMessage   string       `json:"protoMessage"`
     ResponseType   string       `json:"responseType"`
     ErrorResponseType string    `json:"errorResponseType"`
+	ResponseTypes  []ResponseTypeMatcher `json:"responseTypes"`
+	OneofSelections OneofSelections `json:"oneofSelections"`
+	BytesDisplay   BytesDisplay `json:"bytesDisplay"`
 	Headers        []HeaderKV   `json:"headers"`
+	BodyMode       string       `json:"bodyMode"`
 	Body           []BodyField  `json:"body"`
+	RawBody        string       `json:"rawBody"`
 	TimeoutSeconds int          `json:"timeoutSeconds"`
 }
 
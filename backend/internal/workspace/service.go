@@ -1,10 +1,12 @@
 package workspace
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/datahopper/backend/internal/store"
 	"github.com/datahopper/backend/internal/types"
+	"github.com/google/uuid"
 )
 
 // Service provides workspace management functionality
@@ -22,13 +24,14 @@ func NewService(store store.Store) *Service {
 // CreateCollection creates a new collection
 func (s *Service) CreateCollection(req *types.CreateCollectionRequest) (*types.Collection, error) {
 	collection := &types.Collection{
-		Name:        req.Name,
-		Description: req.Description,
-		ProtoRoots:  req.ProtoRoots,
-		Variables:   req.Variables,
-		Requests:    []*types.Request{},
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Name:            req.Name,
+		Description:     req.Description,
+		ProtoRoots:      req.ProtoRoots,
+		Variables:       req.Variables,
+		StrictVariables: req.StrictVariables,
+		Requests:        []*types.Request{},
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	if err := s.store.CreateCollection(collection); err != nil {
@@ -65,11 +68,18 @@ func (s *Service) CreateRequest(collectionID string, req *types.CreateRequestReq
 		Name:           req.Name,
 		Method:         req.Method,
 		URL:            req.URL,
+		Protocol:       req.Protocol,
+		ServiceMethod:  req.ServiceMethod,
 		ProtoMessage:   req.ProtoMessage,
         ResponseType:   req.ResponseType,
         ErrorResponseType: req.ErrorResponseType,
+		ResponseTypes:  req.ResponseTypes,
+		OneofSelections: req.OneofSelections,
+		BytesDisplay:   req.BytesDisplay,
 		Headers:        req.Headers,
+		BodyMode:       req.BodyMode,
 		Body:           req.Body,
+		RawBody:        req.RawBody,
 		TimeoutSeconds: req.TimeoutSeconds,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
@@ -105,6 +115,12 @@ func (s *Service) UpdateRequest(collectionID string, req *types.UpdateRequestReq
 	if req.URL != "" {
 		existing.URL = req.URL
 	}
+	if req.Protocol != "" {
+		existing.Protocol = req.Protocol
+	}
+	if req.ServiceMethod != "" {
+		existing.ServiceMethod = req.ServiceMethod
+	}
 	if req.ProtoMessage != "" {
 		existing.ProtoMessage = req.ProtoMessage
 	}
@@ -114,12 +130,27 @@ func (s *Service) UpdateRequest(collectionID string, req *types.UpdateRequestReq
     if req.ErrorResponseType != "" {
         existing.ErrorResponseType = req.ErrorResponseType
     }
+	if req.ResponseTypes != nil {
+		existing.ResponseTypes = req.ResponseTypes
+	}
+	if req.OneofSelections != nil {
+		existing.OneofSelections = req.OneofSelections
+	}
+	if req.BytesDisplay != nil {
+		existing.BytesDisplay = req.BytesDisplay
+	}
 	if req.Headers != nil {
 		existing.Headers = req.Headers
 	}
+	if req.BodyMode != "" {
+		existing.BodyMode = req.BodyMode
+	}
 	if req.Body != nil {
 		existing.Body = req.Body
 	}
+	if req.RawBody != "" {
+		existing.RawBody = req.RawBody
+	}
 	if req.TimeoutSeconds > 0 {
 		existing.TimeoutSeconds = req.TimeoutSeconds
 	}
@@ -138,6 +169,56 @@ func (s *Service) DeleteRequest(collectionID, requestID string) error {
 	return s.store.DeleteRequest(collectionID, requestID)
 }
 
+// AddRequestFile stores an uploaded file with a request so bytes fields can reference it
+func (s *Service) AddRequestFile(collectionID, requestID, name, contentType string, data []byte) (*types.RequestFile, error) {
+	request, err := s.store.GetRequest(collectionID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	file := &types.RequestFile{
+		ID:          uuid.NewString(),
+		Name:        name,
+		ContentType: contentType,
+		Size:        len(data),
+		CreatedAt:   time.Now(),
+		Data:        data,
+	}
+	request.Files = append(request.Files, file)
+	if err := s.store.UpdateRequest(collectionID, request); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// FindRequestFile looks up a file uploaded with a request by ID
+func (s *Service) FindRequestFile(collectionID, requestID, fileID string) (*types.RequestFile, error) {
+	request, err := s.store.GetRequest(collectionID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range request.Files {
+		if file.ID == fileID {
+			return file, nil
+		}
+	}
+	return nil, fmt.Errorf("file not found: %s", fileID)
+}
+
+// DeleteRequestFile removes an uploaded file from a request
+func (s *Service) DeleteRequestFile(collectionID, requestID, fileID string) error {
+	request, err := s.store.GetRequest(collectionID, requestID)
+	if err != nil {
+		return err
+	}
+	for i, file := range request.Files {
+		if file.ID == fileID {
+			request.Files = append(request.Files[:i], request.Files[i+1:]...)
+			return s.store.UpdateRequest(collectionID, request)
+		}
+	}
+	return fmt.Errorf("file not found: %s", fileID)
+}
+
 // CreateEnvironment creates a new environment
 func (s *Service) CreateEnvironment(env *types.Environment) error {
 	return s.store.CreateEnvironment(env)
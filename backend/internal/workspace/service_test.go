@@ -199,6 +199,50 @@ func TestWorkspaceService(t *testing.T) {
 		}
 	})
 
+	t.Run("RequestFiles", func(t *testing.T) {
+		collection, _ := service.CreateCollection(&types.CreateCollectionRequest{
+			Name: "Test Collection for Files",
+		})
+
+		request, _ := service.CreateRequest(collection.ID, &types.CreateRequestRequest{
+			Name:         "Test Request for Files",
+			Method:       "POST",
+			URL:          "/api/upload",
+		})
+
+		file, err := service.AddRequestFile(collection.ID, request.ID, "avatar.png", "image/png", []byte{0x89, 'P', 'N', 'G'})
+		if err != nil {
+			t.Fatalf("Failed to add request file: %v", err)
+		}
+		if file.Size != 4 {
+			t.Errorf("Expected file size 4, got %d", file.Size)
+		}
+
+		found, err := service.FindRequestFile(collection.ID, request.ID, file.ID)
+		if err != nil {
+			t.Fatalf("Failed to find request file: %v", err)
+		}
+		if string(found.Data) != "\x89PNG" {
+			t.Errorf("Expected stored file data, got %q", found.Data)
+		}
+
+		other, _ := service.CreateRequest(collection.ID, &types.CreateRequestRequest{
+			Name:   "Other Request",
+			Method: "POST",
+			URL:    "/api/upload",
+		})
+		if _, err := service.FindRequestFile(collection.ID, other.ID, file.ID); err == nil {
+			t.Error("Expected error when finding a file of another request")
+		}
+
+		if err := service.DeleteRequestFile(collection.ID, request.ID, file.ID); err != nil {
+			t.Errorf("Failed to delete request file: %v", err)
+		}
+		if _, err := service.FindRequestFile(collection.ID, request.ID, file.ID); err == nil {
+			t.Error("Expected error when finding deleted file")
+		}
+	})
+
 	t.Run("ListCollections", func(t *testing.T) {
 		// Create a few collections
 		service.CreateCollection(&types.CreateCollectionRequest{Name: "Collection 1"})
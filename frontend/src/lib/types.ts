@@ -157,6 +157,7 @@ export interface MapMeta {
 export interface WellKnownTypeMeta {
   type: string;
   format?: string;
+  accepts?: string[];
 }
 
 export interface FieldSchemaMeta {
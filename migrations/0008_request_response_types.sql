@@ -0,0 +1,4 @@
+-- Ordered status-code matchers mapping responses to message types
+ALTER TABLE IF EXISTS requests
+  ADD COLUMN IF NOT EXISTS response_type_matchers JSONB NOT NULL DEFAULT '[]'::jsonb;
+
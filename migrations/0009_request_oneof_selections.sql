@@ -0,0 +1,3 @@
+-- Selected member per oneof group, keyed by the group's dot-path
+ALTER TABLE IF EXISTS requests
+  ADD COLUMN IF NOT EXISTS oneof_selections JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,3 @@
+-- Presence mode per body_model path (set, unset or default)
+ALTER TABLE IF EXISTS requests
+  ADD COLUMN IF NOT EXISTS body_presence JSONB NOT NULL DEFAULT '{}'::jsonb;
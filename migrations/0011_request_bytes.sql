@@ -0,0 +1,16 @@
+-- Encoding per body_model path of bytes fields, and how decoded bytes are shown
+ALTER TABLE IF EXISTS requests
+  ADD COLUMN IF NOT EXISTS body_encodings JSONB NOT NULL DEFAULT '{}'::jsonb,
+  ADD COLUMN IF NOT EXISTS bytes_display JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Files uploaded with a request for bytes fields
+CREATE TABLE IF NOT EXISTS request_files (
+  id UUID PRIMARY KEY,
+  request_id UUID NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  content_type TEXT NOT NULL DEFAULT '',
+  data BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_files_request ON request_files(request_id);
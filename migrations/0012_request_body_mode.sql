@@ -0,0 +1,4 @@
+-- Body mode (fields, json or text) and the raw document for the json and text modes
+ALTER TABLE IF EXISTS requests
+  ADD COLUMN IF NOT EXISTS body_mode TEXT NOT NULL DEFAULT 'fields',
+  ADD COLUMN IF NOT EXISTS raw_body TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Strict variables: runs of the collection's requests fail on unresolved placeholders
+ALTER TABLE IF EXISTS collections
+  ADD COLUMN IF NOT EXISTS strict_variables BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Collection-scoped variables, and the protocol and RPC method a saved request runs with
+ALTER TABLE IF EXISTS collections
+  ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE IF EXISTS requests
+  ADD COLUMN IF NOT EXISTS protocol TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS service_method TEXT NOT NULL DEFAULT '';